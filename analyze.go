@@ -0,0 +1,19 @@
+package math_calculation
+
+import (
+	"github.com/ZHOUXING1997/math_calculation/internal/croe"
+	"github.com/ZHOUXING1997/math_calculation/internal/validator"
+)
+
+// Analyze 解析表达式并返回引用的变量、调用的函数、运算符、节点数和深度，不会计算表达式
+// 可用于在保存用户公式前确定需要哪些输入变量
+func Analyze(expression string) (*Analysis, error) {
+	return croe.Analyze(expression, nil)
+}
+
+// Diagnose 一次性返回表达式中的所有错误，按位置排序，没有问题时返回 nil
+// 包括所有语法错误、未知函数、参数数量错误、未声明的变量（options.Variables 不为 nil 时）和验证错误，
+// 适用于公式编辑器的实时检查。每个错误都可以通过 Diagnostic 获取行号、列号和标记
+func Diagnose(expression string, options DiagnoseOptions) []error {
+	return validator.Diagnose(expression, options)
+}
@@ -0,0 +1,59 @@
+package math_calculation
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/ZHOUXING1997/math_calculation/internal/validator"
+)
+
+// TestAnalyze 测试表达式分析
+func TestAnalyze(t *testing.T) {
+	analysis, err := Analyze("qty * unit_price * (1 - discount)")
+	if err != nil {
+		t.Fatalf("Analyze() error = %v", err)
+	}
+	if want := []string{"qty", "unit_price", "discount"}; !reflect.DeepEqual(analysis.Variables, want) {
+		t.Errorf("Analyze().Variables = %v, want %v", analysis.Variables, want)
+	}
+
+	// 计算器会先按验证选项验证表达式
+	options := validator.DefaultValidationOptions
+	options.DisallowedFunctions = []string{"pow"}
+	if _, err := NewCalculator(nil).WithValidationOptions(options).Analyze("pow(x, 2)"); err == nil {
+		t.Errorf("Calculator.Analyze() expected validation error")
+	}
+}
+
+// TestDiagnose 测试一次性返回所有错误
+func TestDiagnose(t *testing.T) {
+	errs := Diagnose("foo(qty) * (price +, 2", DiagnoseOptions{
+		ValidationOptions: NewDefaultValidationOptions(),
+		Variables:         []string{"qty"},
+	})
+	wantCodes := []ErrorCode{CodeUnsupportedOperator, CodeInvalidExpression, CodeUndefinedVariable, CodeInvalidExpression}
+	if len(errs) != len(wantCodes) {
+		t.Fatalf("Diagnose() = %v, want %d errors", errs, len(wantCodes))
+	}
+	for i, err := range errs {
+		if code := ErrorCodeOf(err); code != wantCodes[i] {
+			t.Errorf("Diagnose()[%d] = %v, code %q, want %q", i, err, code, wantCodes[i])
+		}
+	}
+
+	if errs := Diagnose("max(qty, 1)", DiagnoseOptions{ValidationOptions: NewDefaultValidationOptions()}); errs != nil {
+		t.Errorf("Diagnose() = %v, want nil", errs)
+	}
+
+	// 计算器使用自己的验证选项和语言
+	options := NewDefaultValidationOptions()
+	options.DisallowedFunctions = []string{"pow"}
+	errs = NewCalculator(nil).WithValidationOptions(options).WithLocale(LocaleEn).Diagnose("pow(x, 2) + y", []string{"x"})
+	if len(errs) != 2 || !errors.Is(errs[0], ErrValidationFailed) || !errors.Is(errs[1], ErrUndefinedVariable) {
+		t.Fatalf("Calculator.Diagnose() = %v", errs)
+	}
+	if want := "position 12: undefined variable: y: undefined variable"; errs[1].Error() != want {
+		t.Errorf("Calculator.Diagnose()[1] = %q, want %q", errs[1].Error(), want)
+	}
+}
@@ -1,206 +1,506 @@
 package math_calculation
 
 import (
+	"context"
+	"sort"
+	"sync"
 	"time"
 
 	"github.com/shopspring/decimal"
 
 	"github.com/ZHOUXING1997/math_calculation/math_config"
 
+	"github.com/ZHOUXING1997/math_calculation/internal"
+	"github.com/ZHOUXING1997/math_calculation/internal/binder"
 	"github.com/ZHOUXING1997/math_calculation/internal/croe"
 	"github.com/ZHOUXING1997/math_calculation/internal/debug"
+	"github.com/ZHOUXING1997/math_calculation/internal/math_utils"
 	"github.com/ZHOUXING1997/math_calculation/internal/validator"
 )
 
 // Calculator 计算器结构体，支持链式API
+// 计算器可以被多个 goroutine 同时使用：With 系列方法不修改原计算器，而是返回使用配置和变量副本的新计算器，
+// 因此链式调用的结果需要赋值，如 calc = calc.WithVariable("x", x)
 type Calculator struct {
-	config            *math_config.CalcConfig
-	vars              map[string]decimal.Decimal
-	validationOptions validator.ValidationOptions
-	compiled          *croe.CompiledExpression
-	lastDebugInfo     *debug.DebugInfo
+	config            *math_config.CalcConfig    // 创建后不再修改
+	vars              map[string]decimal.Decimal // 创建后不再修改
+	provider          VariableProvider
+	validationOptions ValidationOptions
+	parallelOptions   ParallelOptions
+
+	mutex         sync.RWMutex                   // 保护 formulas 和 lastDebugInfo
+	formulas      map[string]*CompiledExpression // 按名称注册的预编译公式
+	lastDebugInfo *DebugInfo
 }
 
-// NewCalculator 创建新的计算器实例
+// NewCalculator 创建新的计算器实例，使用 cfg 的副本，之后修改 cfg 不会影响计算器
 func NewCalculator(cfg *math_config.CalcConfig) *Calculator {
-	if cfg == nil {
-		cfg = math_config.NewDefaultCalcConfig()
-	}
-
 	return &Calculator{
-		config:            cfg,
+		config:            cfg.Clone(),
 		vars:              make(map[string]decimal.Decimal),
 		validationOptions: validator.DefaultValidationOptions,
 	}
 }
 
+// Clone 返回计算器的副本，副本与原计算器的配置和变量互不影响
+func (c *Calculator) Clone() *Calculator {
+	vars := make(map[string]decimal.Decimal, len(c.vars))
+	for k, v := range c.vars {
+		vars[k] = v
+	}
+
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	formulas := make(map[string]*CompiledExpression, len(c.formulas))
+	for name, compiled := range c.formulas {
+		formulas[name] = compiled
+	}
+	return &Calculator{
+		config:            c.config.Clone(),
+		vars:              vars,
+		provider:          c.provider,
+		validationOptions: c.validationOptions,
+		parallelOptions:   c.parallelOptions,
+		formulas:          formulas,
+		lastDebugInfo:     c.lastDebugInfo,
+	}
+}
+
+// with 返回按 update 修改后的副本，已注册的公式改用副本的配置
+func (c *Calculator) with(update func(c *Calculator)) *Calculator {
+	clone := c.Clone()
+	update(clone)
+	for name, compiled := range clone.formulas {
+		clone.formulas[name] = compiled.WithConfig(clone.config)
+	}
+	return clone
+}
+
 // WithPrecision 设置精度
 func (c *Calculator) WithPrecision(precision int32) *Calculator {
-	c.config.Precision = precision
-	return c
+	return c.with(func(c *Calculator) {
+		c.config.Precision = precision
+	})
 }
 
 // WithPrecisionMode 设置精度模式
 func (c *Calculator) WithPrecisionMode(mode math_config.PrecisionMode) *Calculator {
-	c.config.PrecisionMode = mode
-	return c
+	return c.with(func(c *Calculator) {
+		c.config.PrecisionMode = mode
+	})
 }
 
 // WithRoundPrecision 设置精度模式为四舍五入
 func (c *Calculator) WithRoundPrecision() *Calculator {
-	c.config.PrecisionMode = math_config.RoundPrecision
-	return c
+	return c.with(func(c *Calculator) {
+		c.config.PrecisionMode = math_config.RoundPrecision
+	})
 }
 
 // WithCeilPrecision 设置精度模式为向上取整
 func (c *Calculator) WithCeilPrecision() *Calculator {
-	c.config.PrecisionMode = math_config.CeilPrecision
-	return c
+	return c.with(func(c *Calculator) {
+		c.config.PrecisionMode = math_config.CeilPrecision
+	})
 }
 
 // WithFloorPrecision 设置精度模式为向下取整
 func (c *Calculator) WithFloorPrecision() *Calculator {
-	c.config.PrecisionMode = math_config.FloorPrecision
-	return c
+	return c.with(func(c *Calculator) {
+		c.config.PrecisionMode = math_config.FloorPrecision
+	})
 }
 
 // WithTruncatePrecision 设置精度模式为截断（直接截断，不进行舍入）
 func (c *Calculator) WithTruncatePrecision() *Calculator {
-	c.config.PrecisionMode = math_config.TruncatePrecision
-	return c
+	return c.with(func(c *Calculator) {
+		c.config.PrecisionMode = math_config.TruncatePrecision
+	})
 }
 
 // WithTimeout 设置超时时间
 func (c *Calculator) WithTimeout(timeout time.Duration) *Calculator {
-	c.config.Timeout = timeout
-	return c
+	return c.with(func(c *Calculator) {
+		c.config.Timeout = timeout
+	})
 }
 
 // WithMaxRecursionDepth 设置最大递归深度
 func (c *Calculator) WithMaxRecursionDepth(depth int) *Calculator {
-	c.config.MaxRecursionDepth = depth
-	return c
+	return c.with(func(c *Calculator) {
+		c.config.MaxRecursionDepth = depth
+	})
 }
 
 // WithoutCache 禁用缓存
 func (c *Calculator) WithoutCache() *Calculator {
-	c.config.UseExprCache = false
-	c.config.UseLexerCache = false
-	return c
+	return c.with(func(c *Calculator) {
+		c.config.UseExprCache = false
+		c.config.UseLexerCache = false
+	})
 }
 
 // WithCache 启用缓存
 func (c *Calculator) WithCache() *Calculator {
-	c.config.UseExprCache = true
-	c.config.UseLexerCache = true
-	return c
+	return c.with(func(c *Calculator) {
+		c.config.UseExprCache = true
+		c.config.UseLexerCache = true
+	})
 }
 
 // WithPrecisionEachStep 在每一步应用精度控制
 func (c *Calculator) WithPrecisionEachStep() *Calculator {
-	c.config.ApplyPrecisionEachStep = true
-	return c
+	return c.with(func(c *Calculator) {
+		c.config.ApplyPrecisionEachStep = true
+	})
 }
 
 // WithPrecisionFinalResult 只在最终结果应用精度控制
 func (c *Calculator) WithPrecisionFinalResult() *Calculator {
-	c.config.ApplyPrecisionEachStep = false
-	return c
+	return c.with(func(c *Calculator) {
+		c.config.ApplyPrecisionEachStep = false
+	})
 }
 
 // WithVariable 添加变量
 func (c *Calculator) WithVariable(name string, value decimal.Decimal) *Calculator {
-	c.vars[name] = value
-	return c
+	return c.with(func(c *Calculator) {
+		c.vars[name] = value
+	})
 }
 
 // WithVariables 添加多个变量
 func (c *Calculator) WithVariables(vars map[string]decimal.Decimal) *Calculator {
-	for k, v := range vars {
-		c.vars[k] = v
+	return c.with(func(c *Calculator) {
+		for k, v := range vars {
+			c.vars[k] = v
+		}
+	})
+}
+
+// WithProvider 设置变量提供者，WithVariable 设置的变量中没有的变量在计算时从 provider 获取
+func (c *Calculator) WithProvider(provider VariableProvider) *Calculator {
+	return c.with(func(c *Calculator) {
+		c.provider = provider
+	})
+}
+
+// WithStruct 将结构体或结构体指针的字段绑定为变量，规则与 BindStruct 相同
+// v 不是结构体或结构体指针时，计算时获取变量会返回绑定错误
+func (c *Calculator) WithStruct(v interface{}) *Calculator {
+	provider, err := binder.Bind(v)
+	if err != nil {
+		return c.WithProvider(math_utils.ProviderFunc(func(context.Context, string) (decimal.Decimal, bool, error) {
+			return decimal.Zero, false, err
+		}))
 	}
-	return c
+	return c.WithProvider(provider)
+}
+
+// WithDefault 设置变量的默认值，变量未定义时使用
+func (c *Calculator) WithDefault(name string, value decimal.Decimal) *Calculator {
+	return c.with(func(c *Calculator) {
+		if c.config.VariableDefaults == nil {
+			c.config.VariableDefaults = make(map[string]decimal.Decimal)
+		}
+		c.config.VariableDefaults[name] = value
+	})
+}
+
+// WithMissingVariables 设置变量未定义且没有默认值时的处理策略
+func (c *Calculator) WithMissingVariables(policy math_config.MissingVariablePolicy) *Calculator {
+	return c.with(func(c *Calculator) {
+		c.config.MissingVariables = policy
+	})
 }
 
 // CalculateParallel 并行计算多个表达式
 func (c *Calculator) CalculateParallel(expressions []string) ([]decimal.Decimal, []error) {
-	return CalculateParallel(expressions, c.vars, c.config)
+	return c.CalculateParallelContext(context.Background(), expressions)
+}
+
+// CalculateParallelContext 使用调用方的上下文并行计算多个表达式
+func (c *Calculator) CalculateParallelContext(ctx context.Context, expressions []string) ([]decimal.Decimal, []error) {
+	jobs := make([]Job, len(expressions))
+	for i, expression := range expressions {
+		jobs[i] = Job{Expression: expression}
+	}
+	return c.CalculateJobs(ctx, jobs)
+}
+
+// WithParallelOptions 设置并行计算使用的工作池、并发数以及是否在有任务出错时取消其余任务
+func (c *Calculator) WithParallelOptions(options ParallelOptions) *Calculator {
+	return c.with(func(c *Calculator) {
+		c.parallelOptions = options
+	})
+}
+
+// CalculateJobs 并行计算多个任务，任务的变量覆盖计算器中的同名变量，按任务的顺序返回结果和错误
+func (c *Calculator) CalculateJobs(ctx context.Context, jobs []Job) ([]decimal.Decimal, []error) {
+	return CalculateJobs(math_utils.WithProvider(ctx, c.provider), c.jobs(jobs), c.config, c.parallelOptions)
+}
+
+// StreamJobs 并行计算多个任务，按完成顺序从返回的通道输出每个任务的结果
+func (c *Calculator) StreamJobs(ctx context.Context, jobs []Job) <-chan JobResult {
+	return StreamJobs(math_utils.WithProvider(ctx, c.provider), c.jobs(jobs), c.config, c.parallelOptions)
+}
+
+// jobs 返回合并了计算器变量的任务
+func (c *Calculator) jobs(jobs []Job) []Job {
+	merged := make([]Job, len(jobs))
+	for i, job := range jobs {
+		merged[i] = Job{Expression: job.Expression, Vars: c.merge(job.Vars)}
+	}
+	return merged
+}
+
+// merge 返回计算器变量与 vars 合并后的变量，vars 覆盖同名变量，vars 为空时直接返回计算器变量
+func (c *Calculator) merge(vars map[string]decimal.Decimal) map[string]decimal.Decimal {
+	if len(vars) == 0 {
+		return c.vars
+	}
+	merged := make(map[string]decimal.Decimal, len(c.vars)+len(vars))
+	for k, v := range c.vars {
+		merged[k] = v
+	}
+	for k, v := range vars {
+		merged[k] = v
+	}
+	return merged
 }
 
 // WithDebugMode 设置调试模式
 func (c *Calculator) WithDebugMode(mode math_config.DebugMode) *Calculator {
-	c.config.DebugMode = mode
-	return c
+	return c.with(func(c *Calculator) {
+		c.config.DebugMode = mode
+	})
+}
+
+// WithLocale 设置错误消息语言，如 "zh"、"en"
+func (c *Calculator) WithLocale(locale string) *Calculator {
+	return c.with(func(c *Calculator) {
+		c.config.Locale = locale
+	})
 }
 
 // WithValidationOptions 设置验证选项
-func (c *Calculator) WithValidationOptions(options validator.ValidationOptions) *Calculator {
-	c.validationOptions = options
-	return c
+func (c *Calculator) WithValidationOptions(options ValidationOptions) *Calculator {
+	return c.with(func(c *Calculator) {
+		c.validationOptions = options
+	})
 }
 
-// Compile 预编译表达式
-func (c *Calculator) Compile(expression string) (*croe.CompiledExpression, error) {
-	// 验证表达式
+// validate 按验证选项验证并清理表达式，验证错误按配置的语言输出
+func (c *Calculator) validate(expression string) (string, error) {
 	sanitized, err := validator.ValidateAndSanitizeExpression(expression, c.validationOptions)
+	if err != nil {
+		return "", internal.Localize(err, c.config.Locale)
+	}
+	return sanitized, nil
+}
+
+// locate 将按清理后的表达式定位的错误映射回调用方传入的表达式 expression，并按配置的语言输出
+// 清理会去掉首尾空白、合并连续的加减号，错误的位置和诊断信息仍然对应调用方看到的表达式
+func (c *Calculator) locate(err error, expression string) error {
+	if err == nil {
+		return nil
+	}
+	_, offsets := validator.SanitizeWithOffsets(expression)
+	return internal.Localize(internal.Relocate(err, expression, offsets), c.config.Locale)
+}
+
+// Compile 验证并预编译表达式，不会影响之后的 Calculate，需要按名称复用时使用 Register
+func (c *Calculator) Compile(expression string) (*CompiledExpression, error) {
+	// 验证表达式
+	sanitized, err := c.validate(expression)
 	if err != nil {
 		return nil, err
 	}
 
 	// 预编译表达式
 	compiled, err := croe.Compile(sanitized, c.config)
+	if err != nil {
+		return nil, c.locate(err, expression)
+	}
+	return compiled, nil
+}
+
+// Register 验证并预编译表达式，以 name 注册为公式，已有同名公式时替换，之后可以用 Eval 按名称计算
+// 注册的公式属于这个计算器，With 系列方法派生的计算器复制注册时已有的公式并使用新的配置
+func (c *Calculator) Register(name, expression string) (*CompiledExpression, error) {
+	if name == "" {
+		return nil, internal.Localize(internal.NewParseError(0, internal.ErrInvalidArgument, "", internal.MsgEmptyFormulaName), c.config.Locale)
+	}
+	compiled, err := c.Compile(expression)
 	if err != nil {
 		return nil, err
 	}
 
-	// 保存预编译表达式
-	c.compiled = compiled
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.formulas == nil {
+		c.formulas = make(map[string]*CompiledExpression)
+	}
+	c.formulas[name] = compiled
 	return compiled, nil
 }
 
+// Unregister 删除已注册的公式，公式不存在时返回 false
+func (c *Calculator) Unregister(name string) bool {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	_, ok := c.formulas[name]
+	delete(c.formulas, name)
+	return ok
+}
+
+// Formula 返回已注册的公式，公式不存在时返回 false
+func (c *Calculator) Formula(name string) (*CompiledExpression, bool) {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	compiled, ok := c.formulas[name]
+	return compiled, ok
+}
+
+// Formulas 返回所有已注册公式的名称，按名称排序
+func (c *Calculator) Formulas() []string {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	names := make([]string, 0, len(c.formulas))
+	for name := range c.formulas {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Eval 计算已注册的公式，vars 覆盖计算器中的同名变量
+func (c *Calculator) Eval(name string, vars map[string]decimal.Decimal) (decimal.Decimal, error) {
+	return c.EvalContext(context.Background(), name, vars)
+}
+
+// EvalContext 使用调用方的上下文计算已注册的公式，公式不存在时返回 ErrFormulaNotRegistered
+func (c *Calculator) EvalContext(ctx context.Context, name string, vars map[string]decimal.Decimal) (decimal.Decimal, error) {
+	compiled, ok := c.Formula(name)
+	if !ok {
+		return decimal.Zero, internal.Localize(internal.NewParseError(0, internal.ErrFormulaNotRegistered, name, internal.MsgFormulaNotRegistered, name), c.config.Locale)
+	}
+	return compiled.EvaluateContext(math_utils.WithProvider(ctx, c.provider), c.merge(vars))
+}
+
+// Analyze 验证并分析表达式，返回引用的变量、调用的函数等信息，不会计算表达式
+func (c *Calculator) Analyze(expression string) (*Analysis, error) {
+	// 验证表达式
+	sanitized, err := c.validate(expression)
+	if err != nil {
+		return nil, err
+	}
+
+	analysis, err := croe.Analyze(sanitized, c.config)
+	if err != nil {
+		return nil, c.locate(err, expression)
+	}
+	return analysis, nil
+}
+
+// Diagnose 按计算器的验证选项和语言一次性返回表达式中的所有错误，没有问题时返回 nil
+// variables 为已声明的变量，为 nil 时不检查未定义的变量
+func (c *Calculator) Diagnose(expression string, variables []string) []error {
+	return validator.Diagnose(expression, DiagnoseOptions{
+		ValidationOptions: c.validationOptions,
+		Variables:         variables,
+		Locale:            c.config.Locale,
+	})
+}
+
+// Derivative 对表达式关于变量 variable 求导，返回导数的预编译表达式
+func (c *Calculator) Derivative(expression, variable string) (*CompiledExpression, error) {
+	// 验证表达式
+	sanitized, err := c.validate(expression)
+	if err != nil {
+		return nil, err
+	}
+
+	derivative, err := Derivative(sanitized, variable, c.config)
+	if err != nil {
+		return nil, c.locate(err, expression)
+	}
+	return derivative, nil
+}
+
 // CalculateWithDebug 带调试信息的计算
-func (c *Calculator) CalculateWithDebug(expression string) (decimal.Decimal, *debug.DebugInfo, error) {
+func (c *Calculator) CalculateWithDebug(expression string) (decimal.Decimal, *DebugInfo, error) {
+	return c.CalculateWithDebugContext(context.Background(), expression)
+}
+
+// CalculateWithDebugContext 使用调用方的上下文进行带调试信息的计算
+func (c *Calculator) CalculateWithDebugContext(ctx context.Context, expression string) (decimal.Decimal, *DebugInfo, error) {
 	// 验证表达式
-	sanitized, err := validator.ValidateAndSanitizeExpression(expression, c.validationOptions)
+	sanitized, err := c.validate(expression)
 	if err != nil {
 		return decimal.Zero, nil, err
 	}
 
-	// 计算表达式
-	result, debugInfo, err := debug.DebugCalculate(sanitized, c.vars, c.config)
+	result, debugInfo, err := c.calculateWithDebug(ctx, sanitized)
+	if err != nil {
+		return decimal.Zero, debugInfo, c.locate(err, expression)
+	}
+	return result, debugInfo, nil
+}
+
+// calculateWithDebug 带调试信息地计算已清理的表达式，错误按清理后的表达式定位
+func (c *Calculator) calculateWithDebug(ctx context.Context, sanitized string) (decimal.Decimal, *DebugInfo, error) {
+	result, debugInfo, err := debug.DebugCalculateContext(math_utils.WithProvider(ctx, c.provider), sanitized, c.vars, c.config)
 	if err != nil {
 		return decimal.Zero, debugInfo, err
 	}
 
 	// 保存调试信息
+	c.mutex.Lock()
 	c.lastDebugInfo = debugInfo
+	c.mutex.Unlock()
 	return result, debugInfo, nil
 }
 
 // GetLastDebugInfo 获取最后一次调试信息
-func (c *Calculator) GetLastDebugInfo() *debug.DebugInfo {
+func (c *Calculator) GetLastDebugInfo() *DebugInfo {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
 	return c.lastDebugInfo
 }
 
 // Calculate 计算表达式
 func (c *Calculator) Calculate(expression string) (decimal.Decimal, error) {
+	return c.CalculateContext(context.Background(), expression)
+}
+
+// CalculateNullable 计算表达式，结果为空时返回无效的 NullDecimal 而不是 ErrNullResult
+func (c *Calculator) CalculateNullable(expression string) (NullDecimal, error) {
+	return math_utils.Nullable(c.Calculate(expression))
+}
+
+// CalculateContext 使用调用方的上下文计算表达式
+// ctx 取消或截止时间到达时返回 ErrCanceled，配置的超时时间到达时返回 ErrExecutionTimeout
+func (c *Calculator) CalculateContext(ctx context.Context, expression string) (decimal.Decimal, error) {
+	// 附加变量提供者
+	ctx = math_utils.WithProvider(ctx, c.provider)
+
 	// 验证表达式
-	sanitized, err := validator.ValidateAndSanitizeExpression(expression, c.validationOptions)
+	sanitized, err := c.validate(expression)
 	if err != nil {
 		return decimal.Zero, err
 	}
 
 	// 如果开启了调试模式，使用调试计算
+	var result decimal.Decimal
 	if c.config.DebugMode != math_config.DebugNone {
-		result, _, err := c.CalculateWithDebug(sanitized)
-		return result, err
+		result, _, err = c.calculateWithDebug(ctx, sanitized)
+	} else {
+		// 使用普通计算
+		result, err = CalculateContext(ctx, sanitized, c.vars, c.config)
 	}
-
-	// 如果有预编译表达式，使用预编译表达式计算
-	if c.compiled != nil {
-		return c.compiled.Evaluate(c.vars)
+	if err != nil {
+		return decimal.Zero, c.locate(err, expression)
 	}
-
-	// 使用普通计算
-	return Calculate(sanitized, c.vars, c.config)
+	return result, nil
 }
@@ -1,6 +1,9 @@
 package math_calculation
 
 import (
+	"errors"
+	"reflect"
+	"sync"
 	"testing"
 
 	"github.com/shopspring/decimal"
@@ -344,3 +347,285 @@ func TestExportFunctions(t *testing.T) {
 		}
 	})
 }
+
+// TestCalculatorVariableSchema 测试按变量声明验证表达式
+func TestCalculatorVariableSchema(t *testing.T) {
+	options := NewDefaultValidationOptions()
+	options.AllowedVariables = []string{"qty", "unit_price", "discount"}
+	options.RequiredVariables = []string{"qty", "unit_price"}
+	calc := NewCalculator(nil).WithValidationOptions(options)
+
+	if _, err := calc.Compile("qty * unit_price * (1 - discount)"); err != nil {
+		t.Errorf("Calculator.Compile() error = %v", err)
+	}
+
+	_, err := calc.Compile("qty * price")
+	validationErr, ok := err.(*ValidationError)
+	if !ok {
+		t.Fatalf("Calculator.Compile() error = %v, want *ValidationError", err)
+	}
+	if validationErr.Token != "price" || validationErr.Pos != 6 {
+		t.Errorf("Calculator.Compile() error token = %q at %d, want price at 6", validationErr.Token, validationErr.Pos)
+	}
+
+	if _, err := calc.Compile("qty * 2"); ErrorCodeOf(err) != CodeValidationFailed {
+		t.Errorf("Calculator.Compile() error = %v, want missing required variable", err)
+	}
+
+	// 无效的 UTF-8 字节在计算前被删除，不能借此绕过允许列表
+	options = NewDefaultValidationOptions()
+	options.AllowedVariables = []string{"qty"}
+	calc = NewCalculator(nil).WithValidationOptions(options).WithVariable("qty", decimal.NewFromInt(1)).WithVariable("secret", decimal.NewFromInt(42))
+	for _, expression := range []string{"sec\xffret+1", "qty+sec\xffret"} {
+		if got, err := calc.Calculate(expression); ErrorCodeOf(err) != CodeValidationFailed {
+			t.Errorf("Calculator.Calculate(%q) = %v, %v, want validation error", expression, got, err)
+		}
+	}
+}
+
+// TestCalculatorWithStruct 测试将结构体字段绑定为变量
+func TestCalculatorWithStruct(t *testing.T) {
+	type Customer struct {
+		Level int
+	}
+	type Order struct {
+		Qty       int
+		UnitPrice decimal.Decimal
+		Discount  string `calc:"discount_rate"`
+		Customer  Customer
+	}
+	order := &Order{Qty: 3, UnitPrice: decimal.RequireFromString("2.5"), Discount: "0.1", Customer: Customer{Level: 2}}
+
+	calc := NewCalculator(nil).WithStruct(order)
+	got, err := calc.Calculate("qty * unit_price * (1 - discount_rate) + customer.level")
+	if err != nil {
+		t.Fatalf("Calculator.Calculate() error = %v", err)
+	}
+	if want := decimal.RequireFromString("8.75"); !got.Equal(want) {
+		t.Errorf("Calculator.Calculate() = %v, want %v", got, want)
+	}
+
+	// 绑定指针时使用字段的当前值
+	order.Qty = 1
+	got, err = calc.Calculate("qty")
+	if err != nil || !got.Equal(decimal.NewFromInt(1)) {
+		t.Errorf("Calculator.Calculate() = %v, %v, want 1", got, err)
+	}
+
+	// 变量名可以作为 Diagnose 的已声明变量
+	provider, err := BindStruct(order)
+	if err != nil {
+		t.Fatalf("BindStruct() error = %v", err)
+	}
+	errs := calc.Diagnose("qty * unit_prce", provider.Names())
+	if len(errs) != 1 || !reflect.DeepEqual(SuggestionsOf(errs[0]), []string{"unit_price"}) {
+		t.Errorf("Calculator.Diagnose() = %v, want one error suggesting unit_price", errs)
+	}
+
+	// 不是结构体时返回绑定错误
+	if _, err := BindStruct(42); !errors.Is(err, ErrInvalidArgument) {
+		t.Errorf("BindStruct() error = %v, want %v", err, ErrInvalidArgument)
+	}
+	if _, err := NewCalculator(nil).WithStruct(42).Calculate("qty + 1"); !errors.Is(err, ErrInvalidArgument) {
+		t.Errorf("Calculator.Calculate() error = %v, want %v", err, ErrInvalidArgument)
+	}
+
+	// 绑定和转换错误按计算器的语言输出
+	for _, tt := range []struct {
+		value interface{}
+		want  string
+	}{
+		{42, "position 0: failed to look up variable qty: can only bind a struct or a pointer to a struct, got int: invalid argument"},
+		{&struct{ Qty string }{"abc"}, `position 0: failed to look up variable qty: "abc" is not a valid number: invalid argument`},
+	} {
+		_, err := NewCalculator(nil).WithLocale("en").WithStruct(tt.value).Calculate("qty + 1")
+		if err == nil || err.Error() != tt.want {
+			t.Errorf("Calculator.Calculate() error = %v, want %s", err, tt.want)
+		}
+	}
+}
+
+// TestCalculatorImmutable 测试 With 系列方法不修改原计算器
+func TestCalculatorImmutable(t *testing.T) {
+	cfg := math_config.NewDefaultCalcConfig()
+	base := NewCalculator(cfg).WithVariable("x", decimal.NewFromInt(10))
+
+	// 修改创建时传入的配置不影响计算器
+	cfg.Precision = 0
+
+	derived := base.WithPrecision(2).WithRoundPrecision().WithVariable("x", decimal.NewFromInt(20)).WithDefault("y", decimal.NewFromInt(1))
+	clone := base.Clone().WithVariable("x", decimal.NewFromInt(30))
+
+	tests := []struct {
+		name string
+		calc *Calculator
+		want string
+	}{
+		{name: "原计算器", calc: base, want: "3.3333333333"},
+		{name: "派生的计算器", calc: derived, want: "6.67"},
+		{name: "副本", calc: clone, want: "10"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.calc.Calculate("x / 3")
+			if err != nil || got.String() != tt.want {
+				t.Errorf("Calculate() = %v, %v, want %s", got, err, tt.want)
+			}
+		})
+	}
+
+	if _, err := base.Calculate("y"); !errors.Is(err, ErrUndefinedVariable) {
+		t.Errorf("Calculate(y) error = %v, want %v", err, ErrUndefinedVariable)
+	}
+
+	// 注册的公式使用派生计算器的配置
+	registered := NewCalculator(nil)
+	if _, err := registered.Register("third", "1 / 3"); err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+	if got, err := registered.WithPrecision(2).Eval("third", nil); err != nil || got.String() != "0.33" {
+		t.Errorf("Eval() = %v, %v, want 0.33", got, err)
+	}
+}
+
+// TestCalculatorConcurrentUse 测试多个 goroutine 同时使用和派生同一个计算器，使用 -race 检查数据竞争
+func TestCalculatorConcurrentUse(t *testing.T) {
+	calc := NewCalculator(nil).WithVariable("x", decimal.NewFromInt(9))
+
+	wg := sync.WaitGroup{}
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			derived := calc.WithPrecision(int32(i)).WithVariable("y", decimal.NewFromInt(int64(i))).WithLocale("en")
+			for j := 0; j < 20; j++ {
+				if got, err := calc.Calculate("x + 1"); err != nil || !got.Equal(decimal.NewFromInt(10)) {
+					t.Errorf("Calculate() = %v, %v, want 10", got, err)
+				}
+				if got, err := derived.Calculate("x + y"); err != nil || !got.Equal(decimal.NewFromInt(int64(9+i))) {
+					t.Errorf("Calculate() = %v, %v, want %d", got, err, 9+i)
+				}
+				if _, err := calc.WithDebugMode(math_config.DebugBasic).Calculate("x * 2"); err != nil {
+					t.Errorf("Calculate() error = %v", err)
+				}
+				_ = calc.Clone().GetLastDebugInfo()
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if _, err := calc.Calculate("y"); !errors.Is(err, ErrUndefinedVariable) {
+		t.Errorf("Calculate(y) error = %v, want %v", err, ErrUndefinedVariable)
+	}
+}
+
+// TestCalculatorRegistry 测试按名称注册和计算公式
+func TestCalculatorRegistry(t *testing.T) {
+	calc := NewCalculator(nil).WithVariable("rate", decimal.RequireFromString("0.1")).WithLocale("en")
+	if _, err := calc.Register("fee", "amount * rate"); err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+	if _, err := calc.Register("total", "amount + amount * rate"); err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+
+	// Calculate 总是计算传入的表达式
+	if got, err := calc.Calculate("rate * 2"); err != nil || !got.Equal(decimal.RequireFromString("0.2")) {
+		t.Errorf("Calculate() = %v, %v, want 0.2", got, err)
+	}
+
+	vars := map[string]decimal.Decimal{"amount": decimal.NewFromInt(200)}
+	tests := []struct {
+		name    string
+		formula string
+		vars    map[string]decimal.Decimal
+		want    string
+		wantErr error
+	}{
+		{name: "计算注册的公式", formula: "fee", vars: vars, want: "20"},
+		{name: "变量覆盖计算器中的变量", formula: "fee", vars: map[string]decimal.Decimal{"amount": decimal.NewFromInt(200), "rate": decimal.RequireFromString("0.5")}, want: "100"},
+		{name: "另一个公式", formula: "total", vars: vars, want: "220"},
+		{name: "未注册的公式", formula: "tax", vars: vars, wantErr: ErrFormulaNotRegistered},
+		{name: "缺少变量", formula: "fee", wantErr: ErrUndefinedVariable},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := calc.Eval(tt.formula, tt.vars)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("Eval() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil || got.String() != tt.want {
+				t.Errorf("Eval() = %v, %v, want %s", got, err, tt.want)
+			}
+		})
+	}
+
+	_, err := calc.Eval("tax", vars)
+	if ErrorCodeOf(err) != CodeFormulaNotRegistered || err.Error() != "position 0: formula tax is not registered: formula is not registered" {
+		t.Errorf("Eval() error = %v, code = %q", err, ErrorCodeOf(err))
+	}
+
+	// 列出、替换和删除公式
+	if got := calc.Formulas(); !reflect.DeepEqual(got, []string{"fee", "total"}) {
+		t.Errorf("Formulas() = %v, want [fee total]", got)
+	}
+	if _, err := calc.Register("fee", "amount * rate + 1"); err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+	if got, err := calc.Eval("fee", vars); err != nil || !got.Equal(decimal.NewFromInt(21)) {
+		t.Errorf("Eval() after replace = %v, %v, want 21", got, err)
+	}
+	if compiled, ok := calc.Formula("fee"); !ok || compiled.Expression() != "amount * rate + 1" {
+		t.Errorf("Formula() = %v, %v", compiled, ok)
+	}
+	if !calc.Unregister("fee") || calc.Unregister("fee") {
+		t.Errorf("Unregister() should succeed once")
+	}
+	if _, ok := calc.Formula("fee"); ok {
+		t.Errorf("Formula() after Unregister should return false")
+	}
+
+	// 无效的注册
+	if _, err := calc.Register("", "1"); !errors.Is(err, ErrInvalidArgument) || err.Error() != "position 0: formula name cannot be empty: invalid argument" {
+		t.Errorf("Register() error = %v, want %v", err, ErrInvalidArgument)
+	}
+	if _, err := calc.Register("bad", "1 +"); err == nil {
+		t.Errorf("Register() error = nil, want parse error")
+	}
+	if _, ok := calc.Formula("bad"); ok {
+		t.Errorf("failed Register() should not register the formula")
+	}
+
+	// 派生的计算器复制已注册的公式，之后的注册互不影响
+	derived := calc.WithPrecision(2)
+	if _, err := derived.Register("half", "amount / 2"); err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+	if got := calc.Formulas(); !reflect.DeepEqual(got, []string{"total"}) {
+		t.Errorf("Formulas() = %v, want [total]", got)
+	}
+	if got := derived.Formulas(); !reflect.DeepEqual(got, []string{"half", "total"}) {
+		t.Errorf("derived Formulas() = %v, want [half total]", got)
+	}
+}
+
+// TestCalculatorCompileThenCalculate 测试 Compile 之后 Calculate 计算传入的表达式而不是预编译的表达式
+func TestCalculatorCompileThenCalculate(t *testing.T) {
+	calc := NewCalculator(nil).WithVariable("x", decimal.NewFromInt(3))
+	compiled, err := calc.Compile("x * 100")
+	if err != nil {
+		t.Fatalf("Calculator.Compile() error = %v", err)
+	}
+
+	got, err := calc.Calculate("x + 1")
+	if err != nil || !got.Equal(decimal.NewFromInt(4)) {
+		t.Errorf("Calculator.Calculate() = %v, %v, want 4", got, err)
+	}
+	got, err = compiled.Evaluate(map[string]decimal.Decimal{"x": decimal.NewFromInt(3)})
+	if err != nil || !got.Equal(decimal.NewFromInt(300)) {
+		t.Errorf("CompiledExpression.Evaluate() = %v, %v, want 300", got, err)
+	}
+}
@@ -9,7 +9,6 @@ import (
 	"github.com/shopspring/decimal"
 
 	"github.com/ZHOUXING1997/math_calculation"
-	"github.com/ZHOUXING1997/math_calculation/internal/validator"
 	"github.com/ZHOUXING1997/math_calculation/math_config"
 )
 
@@ -20,8 +19,8 @@ func main() {
 	calc := math_calculation.NewCalculator(nil)
 
 	// 设置变量
-	calc.WithVariable("x", decimal.NewFromFloat(5.0))
-	calc.WithVariable("y", decimal.NewFromFloat(3.0))
+	calc = calc.WithVariable("x", decimal.NewFromFloat(5.0))
+	calc = calc.WithVariable("y", decimal.NewFromFloat(3.0))
 
 	// 1. 表达式预编译
 	fmt.Println("\n1. 表达式预编译")
@@ -47,7 +46,7 @@ func main() {
 	fmt.Printf("预编译表达式结果: %s\n", result)
 
 	// 修改配置并重新计算
-	compiled.WithPrecisionFinalResult()
+	compiled = compiled.WithPrecisionFinalResult()
 	result, err = compiled.Evaluate(map[string]decimal.Decimal{
 		"x": decimal.NewFromFloat(5.0),
 		"y": decimal.NewFromFloat(3.0),
@@ -61,7 +60,7 @@ func main() {
 
 	// 2. 调试模式
 	fmt.Println("\n2. 调试模式")
-	calc.WithDebugMode(math_config.DebugDetailed)
+	calc = calc.WithDebugMode(math_config.DebugDetailed)
 
 	// 使用调试模式计算
 	result, debugInfo, err := calc.CalculateWithDebug("1/3 + 1/3 + 1/3")
@@ -78,7 +77,7 @@ func main() {
 	fmt.Println("\n3. 输入验证和限制")
 
 	// 设置验证选项
-	validationOptions := validator.ValidationOptions{
+	validationOptions := math_calculation.ValidationOptions{
 		MaxExpressionLength:   100,
 		MaxNestedParentheses:  5,
 		MaxFunctionArguments:  3,
@@ -89,8 +88,8 @@ func main() {
 		MaxNumberLength:       10,
 	}
 
-	calc.WithValidationOptions(validationOptions)
-	calc.WithDebugMode(math_config.DebugDetailed)
+	calc = calc.WithValidationOptions(validationOptions)
+	calc = calc.WithDebugMode(math_config.DebugDetailed)
 
 	// 验证有效表达式
 	result, err = calc.Calculate("sqrt(x) + abs(-5) + pow(2, 3)")
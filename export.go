@@ -1,9 +1,213 @@
 package math_calculation
 
 import (
+	"errors"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/ZHOUXING1997/math_calculation/internal"
+	"github.com/ZHOUXING1997/math_calculation/internal/binder"
 	"github.com/ZHOUXING1997/math_calculation/internal/croe"
+	"github.com/ZHOUXING1997/math_calculation/internal/debug"
+	"github.com/ZHOUXING1997/math_calculation/internal/math_node"
+	"github.com/ZHOUXING1997/math_calculation/internal/math_utils"
+	"github.com/ZHOUXING1997/math_calculation/internal/render"
+	"github.com/ZHOUXING1997/math_calculation/internal/symbolic"
+	"github.com/ZHOUXING1997/math_calculation/internal/validator"
+	"github.com/ZHOUXING1997/math_calculation/internal/workbook"
 )
 
+// 以下类型是 internal 包中类型的别名，外部模块可以通过本包直接使用
+// 别名与原类型完全相同，已有代码返回或接收的值无需任何转换
+
+// CompiledExpression 预编译表达式
+type CompiledExpression = croe.CompiledExpression
+
+// BatchOptions 批量计算选项
+type BatchOptions = croe.BatchOptions
+
+// DefaultBatchChunkSize 未设置 ChunkSize 时每个分块的行数
+const DefaultBatchChunkSize = croe.DefaultBatchChunkSize
+
+// VariableProvider 变量提供者，计算时按名称延迟获取变量的值
+type VariableProvider = math_utils.VariableProvider
+
+// MapProvider 使用 map 提供变量
+type MapProvider = math_utils.MapProvider
+
+// ProviderFunc 将函数适配为 VariableProvider
+type ProviderFunc = math_utils.ProviderFunc
+
+// Workbook 一组相互引用的命名公式，按依赖顺序计算，输入变化时只重新计算受影响的公式
+type Workbook = workbook.Workbook
+
+// NullDecimal 可以为空的计算结果，Valid 为 false 表示结果为空
+type NullDecimal = decimal.NullDecimal
+
+// NestedMapProvider 从嵌套的 map 和切片中按路径获取变量，如 order.items[0].price
+type NestedMapProvider = math_utils.NestedMapProvider
+
+// StructProvider 将结构体字段作为变量提供的变量提供者
+type StructProvider = binder.StructProvider
+
+// Analysis 表达式分析结果
+type Analysis = croe.Analysis
+
+// ValidationOptions 表达式验证选项
+type ValidationOptions = validator.ValidationOptions
+
+// ValidationError 表达式验证错误
+type ValidationError = validator.ValidationError
+
+// DiagnoseOptions 多错误诊断选项
+type DiagnoseOptions = validator.DiagnoseOptions
+
+// DebugInfo 调试信息
+type DebugInfo = debug.DebugInfo
+
+// DebugStep 调试过程中的单个计算步骤
+type DebugStep = debug.DebugStep
+
+// ParseError 解析和计算错误，包含错误位置和原始错误
+type ParseError = internal.ParseError
+
+// FormatOptions 表达式格式化选项
+type FormatOptions = math_node.FormatOptions
+
+// NormalizeOptions 化简和规范化选项
+type NormalizeOptions = symbolic.NormalizeOptions
+
+// RenderOptions LaTeX 和 MathML 渲染选项
+type RenderOptions = render.Options
+
+// ASTSchemaVersion 预编译表达式 JSON 格式的版本号
+const ASTSchemaVersion = croe.ASTSchemaVersion
+
+// ErrorCode 机器可读的错误码
+type ErrorCode = internal.ErrorCode
+
+// 错误码，取值保持稳定
+const (
+	CodeUnknown              = internal.CodeUnknown
+	CodeDivisionByZero       = internal.CodeDivisionByZero
+	CodeUndefinedVariable    = internal.CodeUndefinedVariable
+	CodeUnsupportedOperator  = internal.CodeUnsupportedOperator
+	CodeInvalidExpression    = internal.CodeInvalidExpression
+	CodeInvalidArgument      = internal.CodeInvalidArgument
+	CodeMaxRecursionDepth    = internal.CodeMaxRecursionDepth
+	CodeExecutionTimeout     = internal.CodeExecutionTimeout
+	CodeNotDifferentiable    = internal.CodeNotDifferentiable
+	CodeValidationFailed     = internal.CodeValidationFailed
+	CodeResourceLimit        = internal.CodeResourceLimit
+	CodeCanceled             = internal.CodeCanceled
+	CodeNullResult           = internal.CodeNullResult
+	CodeCircularReference    = internal.CodeCircularReference
+	CodeFormulaNotRegistered = internal.CodeFormulaNotRegistered
+	CodeInternal             = internal.CodeInternal
+)
+
+// 错误类型，可以通过 errors.Is 判断错误原因
+var (
+	ErrDivisionByZero       = internal.ErrDivisionByZero
+	ErrUndefinedVariable    = internal.ErrUndefinedVariable
+	ErrUnsupportedOperator  = internal.ErrUnsupportedOperator
+	ErrInvalidExpression    = internal.ErrInvalidExpression
+	ErrInvalidArgument      = internal.ErrInvalidArgument
+	ErrMaxRecursionDepth    = internal.ErrMaxRecursionDepth
+	ErrExecutionTimeout     = internal.ErrExecutionTimeout
+	ErrNotDifferentiable    = internal.ErrNotDifferentiable
+	ErrValidationFailed     = internal.ErrValidationFailed
+	ErrResourceLimit        = internal.ErrResourceLimit
+	ErrCanceled             = internal.ErrCanceled
+	ErrNullResult           = internal.ErrNullResult
+	ErrCircularReference    = internal.ErrCircularReference
+	ErrFormulaNotRegistered = internal.ErrFormulaNotRegistered
+	ErrInternal             = internal.ErrInternal
+)
+
+// ResourceLimitError 超过资源限制的错误，可以通过 errors.As 获取超过的限制和限制值
+type ResourceLimitError = internal.ResourceLimitError
+
+// CycleError 公式之间存在循环引用的错误，可以通过 errors.As 获取循环经过的公式
+type CycleError = internal.CycleError
+
+// ResourceLimit 资源限制的种类
+type ResourceLimit = internal.ResourceLimit
+
+// 资源限制的种类
+const (
+	LimitExponent   = internal.LimitExponent
+	LimitDigits     = internal.LimitDigits
+	LimitIterations = internal.LimitIterations
+	LimitOperations = internal.LimitOperations
+)
+
+// ErrorCodeOf 返回错误对应的错误码，err 为 nil 时返回空字符串
+// ParseError 和 ValidationError 被包装后也能识别
+func ErrorCodeOf(err error) ErrorCode {
+	return internal.CodeOf(err)
+}
+
+// 内置的错误消息语言
+const (
+	LocaleZh = internal.LocaleZh
+	LocaleEn = internal.LocaleEn
+)
+
+// Translator 自定义翻译函数，参数为语言、消息键和消息参数，返回 false 时使用已注册的消息
+type Translator = internal.Translator
+
+// RegisterMessages 注册某种语言的错误消息，键为错误码（错误原因）或消息键（ParseError.Key、ValidationError.Key）
+// 已存在的消息会被覆盖，未注册的消息依次回退到英文和中文
+func RegisterMessages(locale string, messages map[string]string) {
+	internal.RegisterMessages(locale, messages)
+}
+
+// SetTranslator 设置自定义翻译函数，优先于已注册的消息，传入 nil 取消
+func SetTranslator(t Translator) {
+	internal.SetTranslator(t)
+}
+
+// LocalizeError 返回按指定语言输出消息的错误副本，errors.Is 和 errors.As 的判断结果不变
+func LocalizeError(err error, locale string) error {
+	return internal.Localize(err, locale)
+}
+
+// Diagnostic 返回错误的诊断信息，包含行号、列号、出错的表达式行和 ^~~~ 标记
+// 错误没有记录表达式时返回 err.Error()，err 为 nil 时返回空字符串
+func Diagnostic(err error) string {
+	if err == nil {
+		return ""
+	}
+	var d interface{ Diagnostic() string }
+	if errors.As(err, &d) {
+		return d.Diagnostic()
+	}
+	return err.Error()
+}
+
+// SuggestionsOf 返回错误中与未知函数名或未定义变量名相近的名称，可用于提供一键修正
+// 没有建议或 err 不是 ParseError 时返回 nil
+func SuggestionsOf(err error) []string {
+	var parseErr *ParseError
+	if errors.As(err, &parseErr) {
+		return parseErr.Suggestions
+	}
+	return nil
+}
+
+// NewDefaultValidationOptions 返回默认验证选项的副本，修改返回值不会影响默认值
+func NewDefaultValidationOptions() ValidationOptions {
+	options := validator.DefaultValidationOptions
+	options.AllowedFunctions = append([]string{}, options.AllowedFunctions...)
+	options.DisallowedFunctions = append([]string{}, options.DisallowedFunctions...)
+	options.AllowedVariables = append([]string(nil), options.AllowedVariables...)
+	options.AllowedVariablePrefixes = append([]string(nil), options.AllowedVariablePrefixes...)
+	options.AllowedVariablePatterns = append([]string(nil), options.AllowedVariablePatterns...)
+	options.RequiredVariables = append([]string(nil), options.RequiredVariables...)
+	return options
+}
+
 func SetLexerCacheCapacity(capacity int) {
 	croe.SetLexerCacheCapacity(capacity)
 }
@@ -0,0 +1,240 @@
+package math_calculation
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/ZHOUXING1997/math_calculation/math_config"
+
+	"github.com/ZHOUXING1997/math_calculation/internal/validator"
+)
+
+// TestNewDefaultValidationOptions 测试默认验证选项
+func TestNewDefaultValidationOptions(t *testing.T) {
+	options := NewDefaultValidationOptions()
+	if options.MaxExpressionLength != validator.DefaultValidationOptions.MaxExpressionLength {
+		t.Errorf("MaxExpressionLength = %d, want %d", options.MaxExpressionLength, validator.DefaultValidationOptions.MaxExpressionLength)
+	}
+
+	// 修改返回值不影响默认值
+	options.DisallowedFunctions = append(options.DisallowedFunctions, "pow")
+	if len(validator.DefaultValidationOptions.DisallowedFunctions) != 0 {
+		t.Errorf("DefaultValidationOptions modified: %v", validator.DefaultValidationOptions.DisallowedFunctions)
+	}
+
+	// 可以直接传给计算器
+	if _, err := NewCalculator(nil).WithValidationOptions(options).Calculate("pow(2, 3)"); err == nil {
+		t.Errorf("Calculate() expected error for disallowed function")
+	}
+}
+
+// TestExportedErrors 测试导出的错误类型
+func TestExportedErrors(t *testing.T) {
+	_, err := Calculate("1 / 0", nil, nil)
+
+	var parseErr *ParseError
+	if !errors.As(err, &parseErr) {
+		t.Fatalf("Calculate() error = %v, want *ParseError", err)
+	}
+	if parseErr.Cause != ErrDivisionByZero {
+		t.Errorf("Cause = %v, want %v", parseErr.Cause, ErrDivisionByZero)
+	}
+
+	var compiled *CompiledExpression
+	compiled, err = NewCalculator(nil).Compile("x * 2")
+	if err != nil || compiled == nil {
+		t.Fatalf("Compile() = %v, %v", compiled, err)
+	}
+}
+
+// TestErrorCodeOf 测试错误码
+func TestErrorCodeOf(t *testing.T) {
+	tests := []struct {
+		name       string
+		expression string
+		vars       map[string]decimal.Decimal
+		wantErr    error
+		wantCode   ErrorCode
+		wantToken  string
+	}{
+		{name: "除以零", expression: "x / 0", vars: map[string]decimal.Decimal{"x": decimal.NewFromInt(1)}, wantErr: ErrDivisionByZero, wantCode: CodeDivisionByZero, wantToken: "/"},
+		{name: "未定义的变量", expression: "price * 2", wantErr: ErrUndefinedVariable, wantCode: CodeUndefinedVariable, wantToken: "price"},
+		{name: "无效的参数", expression: "sqrt(-4)", wantErr: ErrInvalidArgument, wantCode: CodeInvalidArgument, wantToken: "sqrt"},
+		{name: "语法错误", expression: "1 + * 2", wantErr: ErrInvalidExpression, wantCode: CodeInvalidExpression, wantToken: "*"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Calculate(tt.expression, tt.vars, nil)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Calculate() error = %v, want %v", err, tt.wantErr)
+			}
+			if got := ErrorCodeOf(err); got != tt.wantCode {
+				t.Errorf("ErrorCodeOf() = %q, want %q", got, tt.wantCode)
+			}
+			var parseErr *ParseError
+			if !errors.As(err, &parseErr) || parseErr.Token != tt.wantToken {
+				t.Errorf("Token = %q, want %q", parseErr.Token, tt.wantToken)
+			}
+		})
+	}
+
+	// 验证错误
+	options := NewDefaultValidationOptions()
+	options.DisallowedFunctions = []string{"pow"}
+	_, err := NewCalculator(nil).WithValidationOptions(options).Calculate("pow(2, 3)")
+	if !errors.Is(err, ErrValidationFailed) || ErrorCodeOf(err) != CodeValidationFailed {
+		t.Errorf("Calculate() error = %v, code = %q", err, ErrorCodeOf(err))
+	}
+	var validationErr *ValidationError
+	if !errors.As(err, &validationErr) || validationErr.Token != "pow" {
+		t.Errorf("ValidationError = %+v", validationErr)
+	}
+}
+
+// TestLocale 测试错误消息语言
+func TestLocale(t *testing.T) {
+	_, err := NewCalculator(nil).WithLocale(LocaleEn).Calculate("1 / 0")
+	if got, want := err.Error(), "position 2: divisor cannot be zero: division by zero"; got != want {
+		t.Errorf("Calculate() error = %q, want %q", got, want)
+	}
+	if !errors.Is(err, ErrDivisionByZero) {
+		t.Errorf("errors.Is(err, ErrDivisionByZero) = false")
+	}
+
+	// 验证错误
+	options := NewDefaultValidationOptions()
+	options.DisallowedFunctions = []string{"pow"}
+	_, err = NewCalculator(nil).WithLocale(LocaleEn).WithValidationOptions(options).Calculate("pow(2, 3)")
+	if got, want := err.Error(), "position 0: function pow is disallowed"; got != want {
+		t.Errorf("Calculate() error = %q, want %q", got, want)
+	}
+
+	// 预编译表达式使用编译时的配置
+	compiled, err := NewCalculator(&math_config.CalcConfig{Locale: LocaleEn, Timeout: time.Second, MaxRecursionDepth: 10}).Compile("x + 1")
+	if err != nil {
+		t.Fatalf("Compile() error = %v", err)
+	}
+	if _, err := compiled.Evaluate(nil); err == nil || err.Error() != "position 0: undefined variable: x: undefined variable" {
+		t.Errorf("Evaluate() error = %v", err)
+	}
+}
+
+// TestDiagnostic 测试诊断信息
+func TestDiagnostic(t *testing.T) {
+	_, err := Calculate("total /\n  (qty - qty)", map[string]decimal.Decimal{
+		"total": decimal.NewFromInt(10),
+		"qty":   decimal.NewFromInt(2),
+	}, nil)
+
+	want := "第 1 行，第 7 列: 除数不能为零: 除以零错误\n 1 | total /\n   |       ^"
+	if got := Diagnostic(err); got != want {
+		t.Errorf("Diagnostic() = %q, want %q", got, want)
+	}
+
+	// 计算器清理表达式后，错误仍然定位到调用方传入的表达式
+	calc := NewCalculator(nil).WithVariable("x", decimal.NewFromInt(1))
+	for _, tt := range []struct {
+		expression string
+		want       string
+	}{
+		{"   1 +-+ 2 / 0", "第 1 行，第 12 列: 除数不能为零: 除以零错误\n 1 |    1 +-+ 2 / 0\n   |            ^"},
+		{"x --+ foo(2)", "第 1 行，第 7 列: 不支持的函数: foo: 不支持的运算符\n 1 | x --+ foo(2)\n   |       ^~~"},
+	} {
+		_, err := calc.Calculate(tt.expression)
+		if got := Diagnostic(err); got != tt.want {
+			t.Errorf("Calculator.Calculate(%q) diagnostic = %q, want %q", tt.expression, got, tt.want)
+		}
+		_, _, err = calc.WithDebugMode(math_config.DebugBasic).CalculateWithDebug(tt.expression)
+		if got := Diagnostic(err); got != tt.want {
+			t.Errorf("Calculator.CalculateWithDebug(%q) diagnostic = %q, want %q", tt.expression, got, tt.want)
+		}
+	}
+
+	if got := Diagnostic(nil); got != "" {
+		t.Errorf("Diagnostic(nil) = %q, want empty", got)
+	}
+	if got := Diagnostic(ErrDivisionByZero); got != ErrDivisionByZero.Error() {
+		t.Errorf("Diagnostic() = %q, want %q", got, ErrDivisionByZero.Error())
+	}
+}
+
+func TestSuggestionsOf(t *testing.T) {
+	_, err := Calculate("prcie * 2", map[string]decimal.Decimal{"price": decimal.NewFromInt(1)}, nil)
+	if got := SuggestionsOf(fmt.Errorf("wrapped: %w", err)); !reflect.DeepEqual(got, []string{"price"}) {
+		t.Errorf("SuggestionsOf() = %q, want [price]", got)
+	}
+
+	_, err = Calculate("sqr(4)", nil, nil)
+	if got := SuggestionsOf(err); !reflect.DeepEqual(got, []string{"sqrt"}) {
+		t.Errorf("SuggestionsOf() = %q, want [sqrt]", got)
+	}
+
+	if got := SuggestionsOf(errors.New("other")); got != nil {
+		t.Errorf("SuggestionsOf() = %q, want nil", got)
+	}
+}
+
+// TestResourceLimits 测试资源限制
+func TestResourceLimits(t *testing.T) {
+	tests := []struct {
+		name       string
+		expression string
+		config     func(cfg *math_config.CalcConfig)
+		wantLimit  ResourceLimit
+	}{
+		{name: "指数过大", expression: "99999^99999999", wantLimit: LimitExponent},
+		{name: "默认指数上限", expression: "2^20000", wantLimit: LimitExponent},
+		{name: "pow指数过大", expression: "pow(99999, 99999999)", wantLimit: LimitExponent},
+		{name: "中间结果过大", expression: "99999^9999", wantLimit: LimitDigits},
+		{name: "字面量过大", expression: "1e999999999 + 1", wantLimit: LimitDigits},
+		{name: "自定义位数限制", expression: "123456 * 123456", config: func(cfg *math_config.CalcConfig) { cfg.MaxDigits = 10 }, wantLimit: LimitDigits},
+		{name: "运算次数过多", expression: "1+1+1+1+1+1+1+1+1+1", config: func(cfg *math_config.CalcConfig) { cfg.MaxOperations = 5 }, wantLimit: LimitOperations},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := math_config.NewDefaultCalcConfig()
+			if tt.config != nil {
+				tt.config(cfg)
+			}
+			start := time.Now()
+			_, err := Calculate(tt.expression, nil, cfg)
+			if elapsed := time.Since(start); elapsed > time.Second {
+				t.Errorf("Calculate() took %v", elapsed)
+			}
+			var limitErr *ResourceLimitError
+			if !errors.As(err, &limitErr) || limitErr.Limit != tt.wantLimit {
+				t.Fatalf("Calculate() error = %v, want %s limit", err, tt.wantLimit)
+			}
+			if !errors.Is(err, ErrResourceLimit) {
+				t.Errorf("errors.Is(err, ErrResourceLimit) = false")
+			}
+			if code := ErrorCodeOf(err); code != CodeResourceLimit {
+				t.Errorf("ErrorCodeOf() = %q, want %q", code, CodeResourceLimit)
+			}
+		})
+	}
+
+	// 关闭限制后可以计算较大的结果
+	cfg := math_config.NewDefaultCalcConfig()
+	cfg.MaxDigits = 0
+	if _, err := Calculate("99999^9999", nil, cfg); err != nil {
+		t.Errorf("Calculate() error = %v, want nil", err)
+	}
+
+	// README 中的迁移配置恢复旧版本的行为
+	cfg = math_config.NewDefaultCalcConfig()
+	cfg.MaxExponent = 0
+	cfg.MaxDigits = 0
+	cfg.MaxOperations = 0
+	cfg.MaxIterations = 1000
+	if _, err := Calculate("2^20000", nil, cfg); err != nil {
+		t.Errorf("Calculate() error = %v, want nil", err)
+	}
+}
@@ -0,0 +1,244 @@
+// Package binder 将 Go 结构体的字段作为表达式变量提供
+package binder
+
+import (
+	"context"
+	"math"
+	"math/big"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/ZHOUXING1997/math_calculation/internal"
+)
+
+// TagName 结构体标签的名称，`calc:"name"` 指定变量名，`calc:"-"` 忽略字段
+const TagName = "calc"
+
+// decimalType decimal.Decimal 的类型
+var decimalType = reflect.TypeOf(decimal.Decimal{})
+
+// converter 将字段的值转换为 decimal，字段为 nil 指针等没有值的情况返回 false
+type converter func(value reflect.Value) (decimal.Decimal, bool, error)
+
+// field 一个可以作为变量的字段
+type field struct {
+	index   []int     // 从根结构体到字段的索引路径，经过的指针在访问时解引用
+	convert converter // 字段值的转换函数
+}
+
+// plan 一种结构体类型的字段访问计划，按变量名索引
+type plan struct {
+	fields map[string]*field
+	names  []string
+}
+
+// plans 按类型缓存的字段访问计划
+var plans sync.Map
+
+// StructProvider 将结构体字段作为变量提供的变量提供者
+// 字段名默认转换为蛇形命名（UnitPrice -> unit_price），可以用 `calc:"name"` 标签指定，
+// 嵌套结构体的字段用点号访问，如 customer.level，匿名嵌入的导出结构体的字段直接提升
+type StructProvider struct {
+	value reflect.Value
+	plan  *plan
+}
+
+// Bind 绑定结构体或结构体指针，绑定指针时计算使用字段的当前值
+func Bind(v interface{}) (*StructProvider, error) {
+	value := reflect.ValueOf(v)
+	if value.Kind() == reflect.Ptr && !value.IsNil() && value.Elem().Kind() == reflect.Struct {
+		value = value.Elem()
+	}
+	if value.Kind() != reflect.Struct {
+		return nil, internal.NewParseError(0, internal.ErrInvalidArgument, "", internal.MsgBindNotStruct, v)
+	}
+	return &StructProvider{value: value, plan: planOf(value.Type())}, nil
+}
+
+// Lookup 实现 VariableProvider 接口
+func (p *StructProvider) Lookup(_ context.Context, name string) (decimal.Decimal, bool, error) {
+	f, ok := p.plan.fields[name]
+	if !ok {
+		return decimal.Zero, false, nil
+	}
+	value, ok := fieldByIndex(p.value, f.index)
+	if !ok {
+		return decimal.Zero, false, nil
+	}
+	// 转换错误由计算时的获取变量失败错误补充变量名和位置
+	return f.convert(value)
+}
+
+// Names 返回所有可用的变量名，按名称排序，可以作为 Diagnose 的已声明变量
+func (p *StructProvider) Names() []string {
+	return append([]string(nil), p.plan.names...)
+}
+
+// fieldByIndex 按索引路径访问字段，路径上有 nil 指针时返回 false
+func fieldByIndex(value reflect.Value, index []int) (reflect.Value, bool) {
+	for _, i := range index {
+		if value.Kind() == reflect.Ptr {
+			if value.IsNil() {
+				return reflect.Value{}, false
+			}
+			value = value.Elem()
+		}
+		value = value.Field(i)
+	}
+	return value, true
+}
+
+// planOf 返回结构体类型的字段访问计划，同一类型只生成一次
+func planOf(t reflect.Type) *plan {
+	if cached, ok := plans.Load(t); ok {
+		return cached.(*plan)
+	}
+	p := &plan{fields: make(map[string]*field)}
+	collect(p, t, "", nil, map[reflect.Type]bool{t: true})
+	for name := range p.fields {
+		p.names = append(p.names, name)
+	}
+	sort.Strings(p.names)
+	cached, _ := plans.LoadOrStore(t, p)
+	return cached.(*plan)
+}
+
+// collect 收集结构体类型 t 中可以作为变量的字段，prefix 为变量名前缀，visiting 用于避免递归类型无限展开
+func collect(p *plan, t reflect.Type, prefix string, index []int, visiting map[reflect.Type]bool) {
+	// 先处理普通字段，再处理匿名嵌入的结构体，使外层字段优先于提升的同名字段
+	order := make([]int, 0, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		if !t.Field(i).Anonymous {
+			order = append(order, i)
+		}
+	}
+	for i := 0; i < t.NumField(); i++ {
+		if t.Field(i).Anonymous {
+			order = append(order, i)
+		}
+	}
+
+	for _, i := range order {
+		sf := t.Field(i)
+		tag := sf.Tag.Get(TagName)
+		if tag == "-" || !sf.IsExported() {
+			continue
+		}
+
+		fieldIndex := append(append([]int(nil), index...), i)
+		fieldType := sf.Type
+		if fieldType.Kind() == reflect.Ptr {
+			fieldType = fieldType.Elem()
+		}
+
+		// 数值字段
+		if convert := converterOf(sf.Type); convert != nil {
+			name := tag
+			if name == "" {
+				name = SnakeCase(sf.Name)
+			}
+			// 已经存在的同名字段优先
+			if _, exists := p.fields[prefix+name]; !exists {
+				p.fields[prefix+name] = &field{index: fieldIndex, convert: convert}
+			}
+			continue
+		}
+
+		// 嵌套结构体
+		if fieldType.Kind() != reflect.Struct || visiting[fieldType] {
+			continue
+		}
+		nestedPrefix := prefix
+		if !sf.Anonymous || tag != "" {
+			name := tag
+			if name == "" {
+				name = SnakeCase(sf.Name)
+			}
+			nestedPrefix = prefix + name + "."
+		}
+		visiting[fieldType] = true
+		collect(p, fieldType, nestedPrefix, fieldIndex, visiting)
+		delete(visiting, fieldType)
+	}
+}
+
+// converterOf 返回字段类型的转换函数，不是数值类型时返回 nil
+// 支持整数、浮点数、数字字符串、decimal.Decimal 以及它们的指针
+func converterOf(t reflect.Type) converter {
+	if t.Kind() == reflect.Ptr {
+		elem := converterOf(t.Elem())
+		if elem == nil {
+			return nil
+		}
+		return func(value reflect.Value) (decimal.Decimal, bool, error) {
+			if value.IsNil() {
+				return decimal.Zero, false, nil
+			}
+			return elem(value.Elem())
+		}
+	}
+
+	if t == decimalType {
+		return func(value reflect.Value) (decimal.Decimal, bool, error) {
+			return value.Interface().(decimal.Decimal), true, nil
+		}
+	}
+
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return func(value reflect.Value) (decimal.Decimal, bool, error) {
+			return decimal.NewFromInt(value.Int()), true, nil
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return func(value reflect.Value) (decimal.Decimal, bool, error) {
+			return decimal.NewFromBigInt(new(big.Int).SetUint64(value.Uint()), 0), true, nil
+		}
+	case reflect.Float32, reflect.Float64:
+		return func(value reflect.Value) (decimal.Decimal, bool, error) {
+			f := value.Float()
+			if math.IsNaN(f) || math.IsInf(f, 0) {
+				return decimal.Zero, false, internal.NewParseError(0, internal.ErrInvalidArgument, "", internal.MsgNotFiniteNumber, f)
+			}
+			if t.Kind() == reflect.Float32 {
+				return decimal.NewFromFloat32(float32(f)), true, nil
+			}
+			return decimal.NewFromFloat(f), true, nil
+		}
+	case reflect.String:
+		return func(value reflect.Value) (decimal.Decimal, bool, error) {
+			s := strings.TrimSpace(value.String())
+			if s == "" {
+				return decimal.Zero, false, nil
+			}
+			d, err := decimal.NewFromString(s)
+			if err != nil {
+				return decimal.Zero, false, internal.NewParseError(0, internal.ErrInvalidArgument, "", internal.MsgInvalidNumberString, s)
+			}
+			return d, true, nil
+		}
+	}
+	return nil
+}
+
+// SnakeCase 将 Go 字段名转换为蛇形命名，如 UnitPrice -> unit_price、OrderID -> order_id
+func SnakeCase(name string) string {
+	runes := []rune(name)
+	var sb strings.Builder
+	for i, r := range runes {
+		if unicode.IsUpper(r) {
+			// 小写字母或数字之后的大写字母，以及连续大写字母中后面跟着小写字母的那一个开始一个新单词
+			if i > 0 && (unicode.IsLower(runes[i-1]) || unicode.IsDigit(runes[i-1]) ||
+				(unicode.IsUpper(runes[i-1]) && i+1 < len(runes) && unicode.IsLower(runes[i+1]))) {
+				sb.WriteByte('_')
+			}
+			r = unicode.ToLower(r)
+		}
+		sb.WriteRune(r)
+	}
+	return sb.String()
+}
@@ -0,0 +1,193 @@
+package binder
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/ZHOUXING1997/math_calculation/internal"
+)
+
+type customer struct {
+	Level    int
+	Discount string
+}
+
+type Base struct {
+	Version uint
+	Qty     int // 与外层字段同名，被外层字段覆盖
+}
+
+type node struct {
+	Value int
+	Next  *node // 递归类型不会无限展开
+}
+
+type order struct {
+	Base
+	Audit     Base `calc:"audit"`
+	hidden    Base
+	Qty       int
+	UnitPrice decimal.Decimal
+	Weight    float64
+	Rate      *float32
+	Tax       *decimal.Decimal
+	Customer  customer
+	Referrer  *customer
+	OrderID   int64  `calc:"id"`
+	Secret    int    `calc:"-"`
+	Note      string `calc:"note"`
+	Tags      []string
+	Node      node
+	internal  int
+}
+
+func TestStructProvider_Lookup(t *testing.T) {
+	rate := float32(0.5)
+	o := &order{
+		Audit:     Base{Version: 3},
+		Qty:       2,
+		UnitPrice: decimal.RequireFromString("9.99"),
+		Weight:    1.25,
+		Rate:      &rate,
+		Customer:  customer{Level: 4, Discount: " 0.15 "},
+		OrderID:   42,
+		Secret:    7,
+		Note:      "abc",
+		Node:      node{Value: 1},
+		internal:  1,
+	}
+	o.Base.Version = 9
+	o.Base.Qty = 100
+
+	provider, err := Bind(o)
+	if err != nil {
+		t.Fatalf("Bind() error = %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		variable  string
+		want      decimal.Decimal
+		wantFound bool
+		wantErr   bool
+	}{
+		{name: "整数", variable: "qty", want: decimal.NewFromInt(2), wantFound: true},
+		{name: "decimal", variable: "unit_price", want: decimal.RequireFromString("9.99"), wantFound: true},
+		{name: "浮点数", variable: "weight", want: decimal.RequireFromString("1.25"), wantFound: true},
+		{name: "浮点数指针", variable: "rate", want: decimal.RequireFromString("0.5"), wantFound: true},
+		{name: "nil指针", variable: "tax"},
+		{name: "嵌套结构体", variable: "customer.level", want: decimal.NewFromInt(4), wantFound: true},
+		{name: "数字字符串", variable: "customer.discount", want: decimal.RequireFromString("0.15"), wantFound: true},
+		{name: "nil嵌套指针", variable: "referrer.level"},
+		{name: "标签", variable: "id", want: decimal.NewFromInt(42), wantFound: true},
+		{name: "忽略的字段", variable: "secret"},
+		{name: "非数字字符串", variable: "note", wantErr: true},
+		{name: "匿名嵌入的字段被提升", variable: "version", want: decimal.NewFromInt(9), wantFound: true},
+		{name: "带标签的嵌入字段", variable: "audit.version", want: decimal.NewFromInt(3), wantFound: true},
+		{name: "递归类型", variable: "node.value", want: decimal.NewFromInt(1), wantFound: true},
+		{name: "不支持的类型", variable: "tags"},
+		{name: "未导出的字段", variable: "internal"},
+		{name: "不存在的字段", variable: "missing"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, found, err := provider.Lookup(context.Background(), tt.variable)
+			if tt.wantErr {
+				if !errors.Is(err, internal.ErrInvalidArgument) {
+					t.Errorf("Lookup() error = %v, want %v", err, internal.ErrInvalidArgument)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Lookup() error = %v", err)
+			}
+			if found != tt.wantFound || !got.Equal(tt.want) {
+				t.Errorf("Lookup() = %v, %v, want %v, %v", got, found, tt.want, tt.wantFound)
+			}
+		})
+	}
+
+	// 绑定指针时使用字段的当前值
+	o.Qty = 5
+	if got, _, _ := provider.Lookup(context.Background(), "qty"); !got.Equal(decimal.NewFromInt(5)) {
+		t.Errorf("Lookup(qty) = %v, want 5", got)
+	}
+}
+
+func TestStructProvider_Names(t *testing.T) {
+	provider, err := Bind(customer{})
+	if err != nil {
+		t.Fatalf("Bind() error = %v", err)
+	}
+	want := []string{"discount", "level"}
+	if got := provider.Names(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Names() = %v, want %v", got, want)
+	}
+}
+
+func TestBind(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   interface{}
+		wantErr bool
+	}{
+		{name: "结构体", value: customer{}},
+		{name: "结构体指针", value: &customer{}},
+		{name: "nil", value: nil, wantErr: true},
+		{name: "nil指针", value: (*customer)(nil), wantErr: true},
+		{name: "map", value: map[string]int{}, wantErr: true},
+		{name: "整数", value: 1, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Bind(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Bind() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	// 同一类型的访问计划只生成一次
+	p1, _ := Bind(customer{})
+	p2, _ := Bind(&customer{})
+	if p1.plan != p2.plan {
+		t.Errorf("Bind() plans are not cached per type")
+	}
+}
+
+func TestSnakeCase(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{input: "Qty", want: "qty"},
+		{input: "UnitPrice", want: "unit_price"},
+		{input: "OrderID", want: "order_id"},
+		{input: "HTTPStatus", want: "http_status"},
+		{input: "Level2Price", want: "level2_price"},
+		{input: "already_snake", want: "already_snake"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			if got := SnakeCase(tt.input); got != tt.want {
+				t.Errorf("SnakeCase(%q) = %q, want %q", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func BenchmarkStructProvider_Lookup(b *testing.B) {
+	provider, _ := Bind(&order{Customer: customer{Level: 3}})
+	ctx := context.Background()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, _, _ = provider.Lookup(ctx, "customer.level")
+	}
+}
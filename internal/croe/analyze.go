@@ -0,0 +1,119 @@
+package croe
+
+import (
+	"sort"
+
+	"github.com/ZHOUXING1997/math_calculation/math_config"
+
+	"github.com/ZHOUXING1997/math_calculation/internal/math_node"
+)
+
+// Analysis 表达式的静态分析结果，不需要变量值也不会计算表达式
+type Analysis struct {
+	Variables []string // 引用的变量，去重后按在表达式中首次出现的顺序排列
+	Functions []string // 调用的函数，去重后按首次出现的顺序排列
+	Operators []string // 使用的运算符，去重后按首次出现的顺序排列（一元负号与减号都记为 "-"）
+	NodeCount int      // 语法树节点总数
+	Depth     int      // 语法树最大深度，单个数字或变量的深度为 1
+}
+
+// Analyze 解析表达式并返回其静态分析结果
+func Analyze(expression string, config *math_config.CalcConfig) (*Analysis, error) {
+	compiled, err := Compile(expression, config)
+	if err != nil {
+		return nil, err
+	}
+	return compiled.Analyze(), nil
+}
+
+// AnalyzeNode 分析表达式树
+func AnalyzeNode(node math_node.Node) *Analysis {
+	// 按位置收集标识符，位置相同（例如求导生成的节点）时保持遍历顺序
+	type occurrence struct {
+		pos  int
+		name string
+	}
+	var variables, functions, operators []occurrence
+
+	analysis := &Analysis{
+		Variables: []string{},
+		Functions: []string{},
+		Operators: []string{},
+		Depth:     depth(node),
+	}
+	math_node.Walk(node, func(n math_node.Node) bool {
+		analysis.NodeCount++
+		switch v := n.(type) {
+		case *math_node.VariableNode:
+			variables = append(variables, occurrence{v.Pos, v.VarName})
+		case *math_node.FunctionNode:
+			functions = append(functions, occurrence{v.Pos, v.FuncName})
+		case *math_node.BinaryOpNode:
+			operators = append(operators, occurrence{v.Pos, v.Operator})
+		case *math_node.UnaryOpNode:
+			operators = append(operators, occurrence{v.Pos, v.Operator})
+		}
+		return true
+	})
+
+	collect := func(items []occurrence) []string {
+		sort.SliceStable(items, func(i, j int) bool { return items[i].pos < items[j].pos })
+		seen := make(map[string]bool, len(items))
+		result := make([]string, 0, len(items))
+		for _, item := range items {
+			if !seen[item.name] {
+				seen[item.name] = true
+				result = append(result, item.name)
+			}
+		}
+		return result
+	}
+	analysis.Variables = collect(variables)
+	analysis.Functions = collect(functions)
+	analysis.Operators = collect(operators)
+	return analysis
+}
+
+// depth 计算语法树的最大深度
+func depth(node math_node.Node) int {
+	if node == nil {
+		return 0
+	}
+	max := 0
+	for _, child := range math_node.Children(node) {
+		if d := depth(child); d > max {
+			max = d
+		}
+	}
+	return max + 1
+}
+
+// Analyze 返回预编译表达式的静态分析结果
+func (ce *CompiledExpression) Analyze() *Analysis {
+	return AnalyzeNode(ce.ast)
+}
+
+// Variables 返回表达式引用的变量，按首次出现的顺序排列
+func (ce *CompiledExpression) Variables() []string {
+	return ce.Analyze().Variables
+}
+
+// Functions 返回表达式调用的函数，按首次出现的顺序排列
+func (ce *CompiledExpression) Functions() []string {
+	return ce.Analyze().Functions
+}
+
+// Operators 返回表达式使用的运算符，按首次出现的顺序排列
+func (ce *CompiledExpression) Operators() []string {
+	return ce.Analyze().Operators
+}
+
+// NodeCount 返回语法树节点总数
+func (ce *CompiledExpression) NodeCount() int {
+	return ce.Analyze().NodeCount
+}
+
+// Depth 返回语法树最大深度
+func (ce *CompiledExpression) Depth() int {
+	return ce.Analyze().Depth
+}
@@ -0,0 +1,83 @@
+package croe
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/ZHOUXING1997/math_calculation/math_config"
+)
+
+func TestAnalyze(t *testing.T) {
+	tests := []struct {
+		name       string
+		expression string
+		want       *Analysis
+	}{
+		{
+			name:       "单个数字",
+			expression: "42",
+			want:       &Analysis{Variables: []string{}, Functions: []string{}, Operators: []string{}, NodeCount: 1, Depth: 1},
+		},
+		{
+			name:       "按出现顺序去重",
+			expression: "qty * unit_price - qty * discount + max(fee, qty)",
+			want: &Analysis{
+				Variables: []string{"qty", "unit_price", "discount", "fee"},
+				Functions: []string{"max"},
+				Operators: []string{"*", "-", "+"},
+				NodeCount: 11,
+				Depth:     4,
+			},
+		},
+		{
+			name:       "嵌套函数和一元运算符",
+			expression: "round(sqrt(-x), 2) ^ 2",
+			want: &Analysis{
+				Variables: []string{"x"},
+				Functions: []string{"round", "sqrt"},
+				Operators: []string{"-", "^"},
+				NodeCount: 7,
+				Depth:     5,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Analyze(tt.expression, math_config.NewDefaultCalcConfig())
+			if err != nil {
+				t.Fatalf("Analyze() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Analyze() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := Analyze("x +", nil); err == nil {
+		t.Errorf("Analyze() expected error for invalid expression")
+	}
+}
+
+func TestCompiledExpression_Introspection(t *testing.T) {
+	compiled, err := Compile("a + b * sqrt(a)", math_config.NewDefaultCalcConfig())
+	if err != nil {
+		t.Fatalf("Compile() error = %v", err)
+	}
+
+	if got := compiled.Variables(); !reflect.DeepEqual(got, []string{"a", "b"}) {
+		t.Errorf("Variables() = %v", got)
+	}
+	if got := compiled.Functions(); !reflect.DeepEqual(got, []string{"sqrt"}) {
+		t.Errorf("Functions() = %v", got)
+	}
+	if got := compiled.Operators(); !reflect.DeepEqual(got, []string{"+", "*"}) {
+		t.Errorf("Operators() = %v", got)
+	}
+	if got := compiled.NodeCount(); got != 6 {
+		t.Errorf("NodeCount() = %d, want 6", got)
+	}
+	if got := compiled.Depth(); got != 4 {
+		t.Errorf("Depth() = %d, want 4", got)
+	}
+}
@@ -0,0 +1,171 @@
+package croe
+
+import (
+	"context"
+	"runtime"
+	"sync"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/ZHOUXING1997/math_calculation/internal"
+	"github.com/ZHOUXING1997/math_calculation/internal/math_node"
+	"github.com/ZHOUXING1997/math_calculation/internal/math_utils"
+)
+
+// DefaultBatchChunkSize 未设置 ChunkSize 时每个分块的行数
+const DefaultBatchChunkSize = 1024
+
+// BatchOptions 批量计算选项
+type BatchOptions struct {
+	ChunkSize   int // 每个分块的行数，0 表示 DefaultBatchChunkSize
+	Concurrency int // 同时计算的分块数，0 表示 runtime.GOMAXPROCS(0)
+}
+
+// normalize 返回填充了默认值的选项
+func (o BatchOptions) normalize() BatchOptions {
+	if o.ChunkSize <= 0 {
+		o.ChunkSize = DefaultBatchChunkSize
+	}
+	if o.Concurrency <= 0 {
+		o.Concurrency = runtime.GOMAXPROCS(0)
+	}
+	return o
+}
+
+// EvaluateBatch 使用预编译表达式计算多行变量，返回每一行的结果和错误，与逐行调用 Evaluate 的结果相同
+// 不同的是配置的超时时间对整批计算生效，而不是对每一行，超时后尚未完成的行返回 ErrExecutionTimeout
+func (ce *CompiledExpression) EvaluateBatch(rows []map[string]decimal.Decimal) ([]decimal.Decimal, []error) {
+	return ce.EvaluateBatchContext(context.Background(), rows, BatchOptions{})
+}
+
+// EvaluateBatchContext 使用调用方的上下文和选项计算多行变量
+// 所有行都包含表达式引用的全部变量时按列计算，否则逐行计算；计算期间不会修改 rows 中的 map
+func (ce *CompiledExpression) EvaluateBatchContext(ctx context.Context, rows []map[string]decimal.Decimal, options BatchOptions) ([]decimal.Decimal, []error) {
+	if columns, ok := ce.toColumns(rows); ok {
+		return ce.evaluateColumns(ctx, columns, len(rows), options)
+	}
+	return ce.evaluateChunks(ctx, len(rows), options, func(ctx context.Context, start, end int, results []decimal.Decimal, errs []error) {
+		for i := start; i < end; i++ {
+			results[i], errs[i] = ce.ast.Eval(math_utils.RowContext(ctx, ce.config), rows[i], ce.config)
+		}
+	})
+}
+
+// EvaluateColumns 按列计算多行变量，columns 中每个变量的值为一列，第 i 行使用每列的第 i 个值
+// 所有列的长度必须相同，否则返回 ErrInvalidArgument
+func (ce *CompiledExpression) EvaluateColumns(columns map[string][]decimal.Decimal) ([]decimal.Decimal, []error, error) {
+	return ce.EvaluateColumnsContext(context.Background(), columns, BatchOptions{})
+}
+
+// EvaluateColumnsContext 使用调用方的上下文和选项按列计算多行变量
+func (ce *CompiledExpression) EvaluateColumnsContext(ctx context.Context, columns map[string][]decimal.Decimal, options BatchOptions) ([]decimal.Decimal, []error, error) {
+	rows := -1
+	for name, values := range columns {
+		if rows >= 0 && len(values) != rows {
+			err := internal.NewParseError(0, internal.ErrInvalidArgument, "", internal.MsgColumnLength, name, len(values), rows)
+			return nil, nil, internal.Localize(err, ce.config.Locale)
+		}
+		rows = len(values)
+	}
+	if rows < 0 {
+		rows = 0
+	}
+	results, errs := ce.evaluateColumns(ctx, columns, rows, options)
+	return results, errs, nil
+}
+
+// evaluateColumns 分块按列计算
+func (ce *CompiledExpression) evaluateColumns(ctx context.Context, columns map[string][]decimal.Decimal, rows int, options BatchOptions) ([]decimal.Decimal, []error) {
+	return ce.evaluateChunks(ctx, rows, options, func(ctx context.Context, start, end int, results []decimal.Decimal, errs []error) {
+		chunk := make(map[string][]decimal.Decimal, len(columns))
+		for name, values := range columns {
+			chunk[name] = values[start:end]
+		}
+		values, chunkErrs := math_node.EvalColumns(ctx, ce.ast, chunk, end-start, ce.config)
+		copy(results[start:end], values)
+		copy(errs[start:end], chunkErrs)
+	})
+}
+
+// toColumns 将每一行都包含表达式引用的全部变量的多行变量转换为列，有行缺少变量时返回 false
+func (ce *CompiledExpression) toColumns(rows []map[string]decimal.Decimal) (map[string][]decimal.Decimal, bool) {
+	names := ce.Variables()
+	columns := make(map[string][]decimal.Decimal, len(names))
+	for _, name := range names {
+		columns[name] = make([]decimal.Decimal, len(rows))
+	}
+	for i, row := range rows {
+		for _, name := range names {
+			val, ok := row[name]
+			if !ok {
+				return nil, false
+			}
+			columns[name][i] = val
+		}
+	}
+	return columns, true
+}
+
+// evaluateChunks 将 rows 行分块，最多 options.Concurrency 个分块同时调用 evaluate 计算
+// 所有分块共用一个 BatchContext，配置的超时时间对整批计算生效；ctx 结束或超时后尚未开始的分块返回取消或超时错误
+// 计算完成后对错误补充位置和语言，只在最终结果控制精度时对结果应用精度
+func (ce *CompiledExpression) evaluateChunks(ctx context.Context, rows int, options BatchOptions,
+	evaluate func(ctx context.Context, start, end int, results []decimal.Decimal, errs []error)) ([]decimal.Decimal, []error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	options = options.normalize()
+	results := make([]decimal.Decimal, rows)
+	errs := make([]error, rows)
+
+	batchCtx, cancel := math_utils.BatchContext(ctx, ce.config)
+	defer cancel()
+
+	sem := make(chan struct{}, options.Concurrency)
+	wg := sync.WaitGroup{}
+	for start := 0; start < rows; start += options.ChunkSize {
+		end := start + options.ChunkSize
+		if end > rows {
+			end = rows
+		}
+		select {
+		case sem <- struct{}{}:
+		case <-batchCtx.Done():
+		}
+		// ctx 结束或超时后不再启动新的分块
+		if batchCtx.Err() != nil {
+			err := math_utils.ContextError(batchCtx)
+			for i := start; i < rows; i++ {
+				errs[i] = err
+			}
+			break
+		}
+		wg.Add(1)
+		go func(start, end int) {
+			defer func() {
+				// 捕获panic，先写入结果再通知分块完成，避免 wg.Wait 返回后仍在写 results 和 errs
+				if r := recover(); r != nil {
+					err := internal.Localize(internal.NewParseError(0, internal.ErrInternal, "", internal.MsgPanic, r), ce.config.Locale)
+					for i := start; i < end; i++ {
+						results[i], errs[i] = decimal.Zero, err
+					}
+				}
+				<-sem
+				wg.Done()
+			}()
+
+			evaluate(batchCtx, start, end, results, errs)
+
+			for i := start; i < end; i++ {
+				if errs[i] != nil {
+					results[i] = decimal.Zero
+					errs[i] = internal.Localize(internal.Locate(errs[i], ce.expression), ce.config.Locale)
+				} else if !ce.config.ApplyPrecisionEachStep {
+					results[i] = math_utils.SetPrecision(results[i], ce.config.Precision, ce.config.PrecisionMode)
+				}
+			}
+		}(start, end)
+	}
+	wg.Wait()
+	return results, errs
+}
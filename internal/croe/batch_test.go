@@ -0,0 +1,194 @@
+package croe
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/ZHOUXING1997/math_calculation/internal"
+	"github.com/ZHOUXING1997/math_calculation/internal/math_utils"
+	"github.com/ZHOUXING1997/math_calculation/math_config"
+)
+
+// batchRows 生成 n 行变量，每 7 行有一行的 qty 为 0
+func batchRows(n int) []map[string]decimal.Decimal {
+	rows := make([]map[string]decimal.Decimal, n)
+	for i := range rows {
+		rows[i] = map[string]decimal.Decimal{
+			"price": decimal.NewFromInt(int64(i)),
+			"qty":   decimal.NewFromInt(int64(i % 7)),
+		}
+	}
+	return rows
+}
+
+func TestCompiledExpression_EvaluateBatch(t *testing.T) {
+	config := math_config.NewDefaultCalcConfig()
+	config.Precision = 4
+	compiled, err := Compile("price * 1.13 / qty", config)
+	if err != nil {
+		t.Fatalf("Compile() error = %v", err)
+	}
+
+	rows := batchRows(100)
+	missing := batchRows(100)
+	delete(missing[50], "qty")
+
+	tests := []struct {
+		name    string
+		rows    []map[string]decimal.Decimal
+		options BatchOptions
+	}{
+		{name: "按列计算", rows: rows},
+		{name: "分块并行", rows: rows, options: BatchOptions{ChunkSize: 8, Concurrency: 4}},
+		{name: "有行缺少变量时逐行计算", rows: missing, options: BatchOptions{ChunkSize: 16}},
+		{name: "没有行", rows: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			results, errs := compiled.EvaluateBatchContext(context.Background(), tt.rows, tt.options)
+			if len(results) != len(tt.rows) || len(errs) != len(tt.rows) {
+				t.Fatalf("EvaluateBatchContext() returned %d results and %d errors, want %d", len(results), len(errs), len(tt.rows))
+			}
+			for i, row := range tt.rows {
+				want, wantErr := compiled.Evaluate(row)
+				if (errs[i] == nil) != (wantErr == nil) || (wantErr != nil && errs[i].Error() != wantErr.Error()) {
+					t.Errorf("row %d error = %v, want %v", i, errs[i], wantErr)
+					continue
+				}
+				if !results[i].Equal(want) {
+					t.Errorf("row %d = %v, want %v", i, results[i], want)
+				}
+			}
+		})
+	}
+
+	// 错误包含位置
+	_, errs := compiled.EvaluateBatch(rows[:1])
+	var parseErr *internal.ParseError
+	if !errors.As(errs[0], &parseErr) || !errors.Is(errs[0], internal.ErrDivisionByZero) || parseErr.Line != 1 {
+		t.Errorf("EvaluateBatch() error = %#v, want located division by zero", errs[0])
+	}
+}
+
+func TestCompiledExpression_EvaluateColumns(t *testing.T) {
+	config := math_config.NewDefaultCalcConfig()
+	config.ApplyPrecisionEachStep = false
+	config.Precision = 2
+	config.PrecisionMode = math_config.RoundPrecision
+	compiled, err := Compile("a / b + 1", config)
+	if err != nil {
+		t.Fatalf("Compile() error = %v", err)
+	}
+
+	results, errs, err := compiled.EvaluateColumns(map[string][]decimal.Decimal{
+		"a": {decimal.NewFromInt(1), decimal.NewFromInt(2), decimal.NewFromInt(3)},
+		"b": {decimal.NewFromInt(3), decimal.Zero, decimal.NewFromInt(4)},
+	})
+	if err != nil {
+		t.Fatalf("EvaluateColumns() error = %v", err)
+	}
+	want := []string{"1.33", "", "1.75"}
+	for i := range want {
+		if want[i] == "" {
+			if !errors.Is(errs[i], internal.ErrDivisionByZero) {
+				t.Errorf("row %d error = %v, want %v", i, errs[i], internal.ErrDivisionByZero)
+			}
+			continue
+		}
+		if errs[i] != nil || results[i].String() != want[i] {
+			t.Errorf("row %d = %v, %v, want %s", i, results[i], errs[i], want[i])
+		}
+	}
+
+	// 列的长度不同
+	_, _, err = compiled.EvaluateColumns(map[string][]decimal.Decimal{
+		"a": {decimal.NewFromInt(1)},
+		"b": {decimal.NewFromInt(1), decimal.NewFromInt(2)},
+	})
+	if !errors.Is(err, internal.ErrInvalidArgument) {
+		t.Errorf("EvaluateColumns() error = %v, want %v", err, internal.ErrInvalidArgument)
+	}
+	if message := internal.Localize(err, internal.LocaleEn).Error(); !strings.Contains(message, "other columns have") {
+		t.Errorf("EvaluateColumns() error = %q, want localized message", message)
+	}
+}
+
+func TestCompiledExpression_EvaluateBatchCanceled(t *testing.T) {
+	compiled, err := Compile("x + 1", nil)
+	if err != nil {
+		t.Fatalf("Compile() error = %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	rows := make([]map[string]decimal.Decimal, 10)
+	for i := range rows {
+		rows[i] = map[string]decimal.Decimal{"x": decimal.NewFromInt(int64(i))}
+	}
+	_, errs := compiled.EvaluateBatchContext(ctx, rows, BatchOptions{ChunkSize: 3})
+	for i, err := range errs {
+		if !errors.Is(err, internal.ErrCanceled) || !errors.Is(err, context.Canceled) {
+			t.Errorf("row %d error = %v, want %v", i, err, internal.ErrCanceled)
+		}
+	}
+}
+
+func TestCompiledExpression_EvaluateBatchTimeout(t *testing.T) {
+	// 每一行都不超时，但整批计算超过配置的超时时间
+	config := math_config.NewDefaultCalcConfig()
+	config.Timeout = 100 * time.Millisecond
+	compiled, err := Compile("slow + 1", config)
+	if err != nil {
+		t.Fatalf("Compile() error = %v", err)
+	}
+	provider := math_utils.ProviderFunc(func(ctx context.Context, name string) (decimal.Decimal, bool, error) {
+		time.Sleep(30 * time.Millisecond)
+		return decimal.NewFromInt(1), true, nil
+	})
+
+	rows := make([]map[string]decimal.Decimal, 10)
+	for i := range rows {
+		rows[i] = map[string]decimal.Decimal{}
+	}
+	_, errs := compiled.EvaluateBatchContext(math_utils.WithProvider(context.Background(), provider), rows, BatchOptions{ChunkSize: 1, Concurrency: 1})
+	if errs[0] != nil {
+		t.Errorf("row 0 error = %v, want nil", errs[0])
+	}
+	if last := errs[len(errs)-1]; !errors.Is(last, internal.ErrExecutionTimeout) {
+		t.Errorf("row %d error = %v, want %v", len(errs)-1, last, internal.ErrExecutionTimeout)
+	}
+}
+
+func BenchmarkCompiledExpression_EvaluateBatch(b *testing.B) {
+	compiled, err := Compile("price * (1 + 0.13) - discount", nil)
+	if err != nil {
+		b.Fatal(err)
+	}
+	rows := make([]map[string]decimal.Decimal, 10000)
+	for i := range rows {
+		rows[i] = map[string]decimal.Decimal{
+			"price":    decimal.NewFromInt(int64(i)),
+			"discount": decimal.RequireFromString(fmt.Sprintf("%d.5", i%10)),
+		}
+	}
+
+	b.Run("Evaluate", func(b *testing.B) {
+		for n := 0; n < b.N; n++ {
+			for _, row := range rows {
+				_, _ = compiled.Evaluate(row)
+			}
+		}
+	})
+	b.Run("EvaluateBatch", func(b *testing.B) {
+		for n := 0; n < b.N; n++ {
+			_, _ = compiled.EvaluateBatch(rows)
+		}
+	})
+}
@@ -12,30 +12,27 @@ import (
 	"github.com/ZHOUXING1997/math_calculation/internal"
 	"github.com/ZHOUXING1997/math_calculation/internal/math_node"
 	"github.com/ZHOUXING1997/math_calculation/internal/math_utils"
+	"github.com/ZHOUXING1997/math_calculation/internal/render"
+	"github.com/ZHOUXING1997/math_calculation/internal/symbolic"
 )
 
 // CompiledExpression 预编译表达式结构体
 type CompiledExpression struct {
-	ast       math_node.Node          // 抽象语法树
-	config    *math_config.CalcConfig // 计算配置
-	mutex     sync.RWMutex            // 用于并发安全
-	lastError error                   // 最后一次错误
+	expression string                  // 原始表达式
+	ast        math_node.Node          // 抽象语法树
+	config     *math_config.CalcConfig // 计算配置，创建后不再修改，With 系列方法返回使用配置副本的新预编译表达式
+	mutex      sync.RWMutex            // 保护 lastError
+	lastError  error                   // 最后一次错误
 }
 
-// Compile 预编译表达式
+// Compile 预编译表达式，使用 config 的副本，之后修改 config 不会影响预编译表达式
 func Compile(expression string, config *math_config.CalcConfig) (*CompiledExpression, error) {
+	// 复制配置，为 nil 时使用默认配置
+	config = config.Clone()
+
 	// 验证表达式
 	if len(expression) == 0 {
-		return nil, &internal.ParseError{
-			Pos:     0,
-			Message: "空表达式",
-			Cause:   internal.ErrInvalidExpression,
-		}
-	}
-
-	// 验证配置
-	if config == nil {
-		config = math_config.NewDefaultCalcConfig()
+		return nil, internal.Localize(internal.NewParseError(0, internal.ErrInvalidExpression, "", internal.MsgEmptyExpression), config.Locale)
 	}
 
 	// 创建解析器
@@ -44,20 +41,27 @@ func Compile(expression string, config *math_config.CalcConfig) (*CompiledExpres
 	// 解析表达式
 	ast, err := parser.Parse(expression)
 	if err != nil {
-		return nil, err
+		return nil, internal.Localize(err, config.Locale)
 	}
 
 	// 创建预编译表达式
 	return &CompiledExpression{
-		ast:    ast,
-		config: config,
+		expression: expression,
+		ast:        ast,
+		config:     config,
 	}, nil
 }
 
 // Evaluate 使用预编译表达式计算结果
 func (ce *CompiledExpression) Evaluate(vars map[string]decimal.Decimal) (decimal.Decimal, error) {
-	// 创建上下文并设置超时
-	ctx, cancel := context.WithTimeout(context.Background(), ce.config.Timeout)
+	return ce.EvaluateContext(context.Background(), vars)
+}
+
+// EvaluateContext 使用调用方的上下文和预编译表达式计算结果
+// ctx 取消或截止时间到达时返回 ErrCanceled，配置的超时时间到达时返回 ErrExecutionTimeout
+func (ce *CompiledExpression) EvaluateContext(ctx context.Context, vars map[string]decimal.Decimal) (decimal.Decimal, error) {
+	// 从调用方的上下文派生计算使用的上下文，设置超时和运算次数预算
+	ctx, cancel := math_utils.EvalContext(ctx, ce.config)
 	defer cancel()
 
 	// 创建变量副本，避免并发问题
@@ -69,6 +73,7 @@ func (ce *CompiledExpression) Evaluate(vars map[string]decimal.Decimal) (decimal
 	// 计算表达式
 	result, err := ce.ast.Eval(ctx, varsCopy, ce.config)
 	if err != nil {
+		err = internal.Localize(internal.Locate(err, ce.expression), ce.config.Locale)
 		ce.mutex.Lock()
 		ce.lastError = err
 		ce.mutex.Unlock()
@@ -83,6 +88,80 @@ func (ce *CompiledExpression) Evaluate(vars map[string]decimal.Decimal) (decimal
 	return result, nil
 }
 
+// EvaluateNullable 使用预编译表达式计算结果，结果为空时返回无效的 NullDecimal 而不是 ErrNullResult
+func (ce *CompiledExpression) EvaluateNullable(vars map[string]decimal.Decimal) (decimal.NullDecimal, error) {
+	return math_utils.Nullable(ce.Evaluate(vars))
+}
+
+// EvaluateWithProvider 使用变量提供者计算预编译表达式，只查询表达式用到的变量，同一次计算内每个变量只查询一次
+func (ce *CompiledExpression) EvaluateWithProvider(ctx context.Context, provider math_utils.VariableProvider) (decimal.Decimal, error) {
+	return ce.EvaluateContext(math_utils.WithProvider(ctx, provider), nil)
+}
+
+// Derive 对预编译表达式关于变量 name 求导，返回导数的预编译表达式
+func (ce *CompiledExpression) Derive(name string) (*CompiledExpression, error) {
+	ast, err := symbolic.Derive(ce.ast, name)
+	if err != nil {
+		return nil, internal.Localize(internal.Locate(err, ce.expression), ce.config.Locale)
+	}
+
+	return &CompiledExpression{
+		expression: math_node.Format(ast),
+		ast:        ast,
+		config:     ce.config,
+	}, nil
+}
+
+// Simplify 返回化简为规范形式的新预编译表达式，合并同类项并统一操作数顺序
+func (ce *CompiledExpression) Simplify() *CompiledExpression {
+	return ce.SimplifyWithOptions(symbolic.NormalizeOptions{})
+}
+
+// SimplifyWithOptions 使用指定的规范化选项化简预编译表达式
+func (ce *CompiledExpression) SimplifyWithOptions(options symbolic.NormalizeOptions) *CompiledExpression {
+	ast := symbolic.NormalizeWithOptions(ce.ast, options)
+	return &CompiledExpression{
+		expression: math_node.Format(ast),
+		ast:        ast,
+		config:     ce.config,
+	}
+}
+
+// Canonical 返回预编译表达式的规范字符串，代数上等价的常见写法得到相同的结果
+func (ce *CompiledExpression) Canonical() string {
+	return symbolic.Canonical(ce.ast)
+}
+
+// CanonicalWithOptions 使用指定的规范化选项返回预编译表达式的规范字符串
+func (ce *CompiledExpression) CanonicalWithOptions(options symbolic.NormalizeOptions) string {
+	return symbolic.CanonicalWithOptions(ce.ast, options)
+}
+
+// Expression 返回编译时的原始表达式，求导和化简得到的表达式返回其格式化结果
+func (ce *CompiledExpression) Expression() string {
+	return ce.expression
+}
+
+// String 返回预编译表达式的字符串表示
+func (ce *CompiledExpression) String() string {
+	return math_node.Format(ce.ast)
+}
+
+// Format 使用指定选项格式化预编译表达式
+func (ce *CompiledExpression) Format(options math_node.FormatOptions) string {
+	return math_node.FormatWithOptions(ce.ast, options)
+}
+
+// LaTeX 将预编译表达式渲染为 LaTeX 公式
+func (ce *CompiledExpression) LaTeX(options render.Options) string {
+	return render.LaTeX(ce.ast, options)
+}
+
+// MathML 将预编译表达式渲染为 MathML 标记
+func (ce *CompiledExpression) MathML(options render.Options) string {
+	return render.MathML(ce.ast, options)
+}
+
 // GetLastError 获取最后一次错误
 func (ce *CompiledExpression) GetLastError() error {
 	ce.mutex.RLock()
@@ -90,53 +169,60 @@ func (ce *CompiledExpression) GetLastError() error {
 	return ce.lastError
 }
 
-// WithConfig 设置新的配置
+// derive 返回使用修改后的配置副本的新预编译表达式，与原预编译表达式共用语法树，原预编译表达式不受影响
+func (ce *CompiledExpression) derive(update func(config *math_config.CalcConfig)) *CompiledExpression {
+	config := ce.config.Clone()
+	update(config)
+	return &CompiledExpression{
+		expression: ce.expression,
+		ast:        ce.ast,
+		config:     config,
+	}
+}
+
+// WithConfig 返回使用 config 副本的新预编译表达式，config 为 nil 时返回 ce
 func (ce *CompiledExpression) WithConfig(config *math_config.CalcConfig) *CompiledExpression {
 	if config == nil {
 		return ce
 	}
-	ce.mutex.Lock()
-	ce.config = config
-	ce.mutex.Unlock()
-	return ce
+	return &CompiledExpression{
+		expression: ce.expression,
+		ast:        ce.ast,
+		config:     config.Clone(),
+	}
 }
 
-// WithTimeout 设置超时时间
+// WithTimeout 返回设置了超时时间的新预编译表达式
 func (ce *CompiledExpression) WithTimeout(timeout time.Duration) *CompiledExpression {
-	ce.mutex.Lock()
-	ce.config.Timeout = timeout
-	ce.mutex.Unlock()
-	return ce
+	return ce.derive(func(c *math_config.CalcConfig) {
+		c.Timeout = timeout
+	})
 }
 
-// WithPrecision 设置精度
+// WithPrecision 返回设置了精度的新预编译表达式
 func (ce *CompiledExpression) WithPrecision(precision int32) *CompiledExpression {
-	ce.mutex.Lock()
-	ce.config.Precision = precision
-	ce.mutex.Unlock()
-	return ce
+	return ce.derive(func(c *math_config.CalcConfig) {
+		c.Precision = precision
+	})
 }
 
-// WithPrecisionMode 设置精度模式
+// WithPrecisionMode 返回设置了精度模式的新预编译表达式
 func (ce *CompiledExpression) WithPrecisionMode(mode math_config.PrecisionMode) *CompiledExpression {
-	ce.mutex.Lock()
-	ce.config.PrecisionMode = mode
-	ce.mutex.Unlock()
-	return ce
+	return ce.derive(func(c *math_config.CalcConfig) {
+		c.PrecisionMode = mode
+	})
 }
 
-// WithPrecisionEachStep 在每一步应用精度控制
+// WithPrecisionEachStep 返回在每一步应用精度控制的新预编译表达式
 func (ce *CompiledExpression) WithPrecisionEachStep() *CompiledExpression {
-	ce.mutex.Lock()
-	ce.config.ApplyPrecisionEachStep = true
-	ce.mutex.Unlock()
-	return ce
+	return ce.derive(func(c *math_config.CalcConfig) {
+		c.ApplyPrecisionEachStep = true
+	})
 }
 
-// WithPrecisionFinalResult 只在最终结果应用精度控制
+// WithPrecisionFinalResult 返回只在最终结果应用精度控制的新预编译表达式
 func (ce *CompiledExpression) WithPrecisionFinalResult() *CompiledExpression {
-	ce.mutex.Lock()
-	ce.config.ApplyPrecisionEachStep = false
-	ce.mutex.Unlock()
-	return ce
+	return ce.derive(func(c *math_config.CalcConfig) {
+		c.ApplyPrecisionEachStep = false
+	})
 }
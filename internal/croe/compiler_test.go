@@ -1,6 +1,7 @@
 package croe
 
 import (
+	"sync"
 	"testing"
 
 	"github.com/shopspring/decimal"
@@ -113,7 +114,7 @@ func TestCompiledExpression_WithConfig(t *testing.T) {
 	config1.ApplyPrecisionEachStep = true
 	config1.PrecisionMode = math_config.TruncatePrecision
 
-	compiled.WithConfig(config1)
+	compiled = compiled.WithConfig(config1)
 
 	result1, err := compiled.Evaluate(nil)
 	if err != nil {
@@ -127,7 +128,7 @@ func TestCompiledExpression_WithConfig(t *testing.T) {
 	config2.ApplyPrecisionEachStep = false
 	config2.PrecisionMode = math_config.RoundPrecision
 
-	compiled.WithConfig(config2)
+	compiled = compiled.WithConfig(config2)
 
 	result2, err := compiled.Evaluate(nil)
 	if err != nil {
@@ -155,7 +156,7 @@ func TestCompiledExpression_WithTimeout(t *testing.T) {
 	}
 
 	// 设置超时
-	compiled.WithTimeout(1000000000) // 1秒
+	compiled = compiled.WithTimeout(1000000000) // 1秒
 
 	// 正常计算应该成功
 	_, err = compiled.Evaluate(vars)
@@ -174,7 +175,7 @@ func TestCompiledExpression_WithPrecision(t *testing.T) {
 	}
 
 	// 设置精度为2
-	compiled.WithPrecision(2)
+	compiled = compiled.WithPrecision(2)
 
 	result, err := compiled.Evaluate(nil)
 	if err != nil {
@@ -198,7 +199,7 @@ func TestCompiledExpression_WithPrecisionMode(t *testing.T) {
 	}
 
 	// 测试四舍五入模式
-	compiled.WithPrecision(2).WithPrecisionMode(math_config.RoundPrecision)
+	compiled = compiled.WithPrecision(2).WithPrecisionMode(math_config.RoundPrecision)
 
 	result1, err := compiled.Evaluate(nil)
 	if err != nil {
@@ -212,7 +213,7 @@ func TestCompiledExpression_WithPrecisionMode(t *testing.T) {
 	}
 
 	// 测试向上取整模式
-	compiled.WithPrecision(2).WithPrecisionMode(math_config.CeilPrecision)
+	compiled = compiled.WithPrecision(2).WithPrecisionMode(math_config.CeilPrecision)
 
 	result2, err := compiled.Evaluate(nil)
 	if err != nil {
@@ -226,7 +227,7 @@ func TestCompiledExpression_WithPrecisionMode(t *testing.T) {
 	}
 
 	// 测试向下取整模式
-	compiled.WithPrecision(2).WithPrecisionMode(math_config.FloorPrecision)
+	compiled = compiled.WithPrecision(2).WithPrecisionMode(math_config.FloorPrecision)
 
 	result3, err := compiled.Evaluate(nil)
 	if err != nil {
@@ -240,7 +241,7 @@ func TestCompiledExpression_WithPrecisionMode(t *testing.T) {
 	}
 
 	// 测试截断模式
-	compiled.WithPrecision(2).WithPrecisionMode(math_config.TruncatePrecision)
+	compiled = compiled.WithPrecision(2).WithPrecisionMode(math_config.TruncatePrecision)
 
 	result4, err := compiled.Evaluate(nil)
 	if err != nil {
@@ -264,7 +265,7 @@ func TestCompiledExpression_WithPrecisionEachStep(t *testing.T) {
 	}
 
 	// 设置每步控制精度
-	compiled.WithPrecisionEachStep()
+	compiled = compiled.WithPrecisionEachStep()
 
 	result, err := compiled.Evaluate(nil)
 	if err != nil {
@@ -288,7 +289,7 @@ func TestCompiledExpression_WithPrecisionFinalResult(t *testing.T) {
 	}
 
 	// 设置只在最终结果控制精度
-	compiled.WithPrecisionFinalResult()
+	compiled = compiled.WithPrecisionFinalResult()
 
 	result, err := compiled.Evaluate(nil)
 	if err != nil {
@@ -326,3 +327,59 @@ func TestCompiledExpression_GetLastError(t *testing.T) {
 		t.Errorf("CompiledExpression.GetLastError() = nil, want error")
 	}
 }
+
+func TestCompiledExpression_WithIsImmutable(t *testing.T) {
+	config := math_config.NewDefaultCalcConfig()
+	compiled, err := Compile("10 / 3", config)
+	if err != nil {
+		t.Fatalf("Compile() error = %v", err)
+	}
+
+	// 修改编译时传入的配置不影响预编译表达式
+	config.Precision = 1
+
+	rounded := compiled.WithPrecision(2).WithPrecisionMode(math_config.RoundPrecision)
+	tests := []struct {
+		name     string
+		compiled *CompiledExpression
+		want     string
+	}{
+		{name: "原预编译表达式", compiled: compiled, want: "3.3333333333"},
+		{name: "派生的预编译表达式", compiled: rounded, want: "3.33"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.compiled.Evaluate(nil)
+			if err != nil || got.String() != tt.want {
+				t.Errorf("Evaluate() = %v, %v, want %s", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompiledExpression_ConcurrentWith(t *testing.T) {
+	compiled, err := Compile("x / 3", nil)
+	if err != nil {
+		t.Fatalf("Compile() error = %v", err)
+	}
+
+	// 并发计算和派生新的预编译表达式，使用 -race 检查数据竞争
+	wg := sync.WaitGroup{}
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			derived := compiled.WithPrecision(int32(i))
+			for j := 0; j < 50; j++ {
+				vars := map[string]decimal.Decimal{"x": decimal.NewFromInt(int64(j))}
+				if _, err := compiled.Evaluate(vars); err != nil {
+					t.Errorf("Evaluate() error = %v", err)
+				}
+				if _, err := derived.Evaluate(vars); err != nil {
+					t.Errorf("Evaluate() error = %v", err)
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+}
@@ -0,0 +1,77 @@
+package croe
+
+import (
+	"encoding/json"
+
+	"github.com/ZHOUXING1997/math_calculation/math_config"
+
+	"github.com/ZHOUXING1997/math_calculation/internal"
+	"github.com/ZHOUXING1997/math_calculation/internal/math_node"
+)
+
+// ASTSchemaVersion 预编译表达式 JSON 格式的版本号，格式发生不兼容变化时递增
+const ASTSchemaVersion = 1
+
+// compiledExpressionJSON 预编译表达式的 JSON 表示
+type compiledExpressionJSON struct {
+	Version    int                 `json:"version"`              // 格式版本
+	Expression string              `json:"expression,omitempty"` // 原始表达式，节点位置相对于它
+	AST        *math_node.JSONNode `json:"ast"`                  // 抽象语法树
+}
+
+// MarshalJSON 将预编译表达式序列化为带版本号的 JSON
+func (ce *CompiledExpression) MarshalJSON() ([]byte, error) {
+	ast, err := math_node.ToJSONNode(ce.ast)
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(compiledExpressionJSON{
+		Version:    ASTSchemaVersion,
+		Expression: ce.expression,
+		AST:        ast,
+	})
+}
+
+// UnmarshalJSON 从 JSON 重建预编译表达式，不会重新解析表达式；未设置配置时使用默认配置
+// 只能反序列化到零值或 CompileJSON 创建的新实例，已经编译的预编译表达式可能正在被其他 goroutine 使用，
+// 对它调用时返回错误且不做任何修改
+func (ce *CompiledExpression) UnmarshalJSON(data []byte) error {
+	var doc compiledExpressionJSON
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return internal.NewParseError(0, err, "", internal.MsgInvalidJSON)
+	}
+
+	if doc.Version != ASTSchemaVersion {
+		return internal.NewParseError(0, internal.ErrInvalidExpression, "", internal.MsgUnsupportedJSONVersion, doc.Version)
+	}
+
+	ast, err := math_node.FromJSONNode(doc.AST)
+	if err != nil {
+		return err
+	}
+
+	ce.mutex.Lock()
+	defer ce.mutex.Unlock()
+	if ce.ast != nil {
+		return internal.NewParseError(0, internal.ErrInvalidArgument, "", internal.MsgUnmarshalCompiled)
+	}
+	ce.expression = doc.Expression
+	ce.ast = ast
+	if ce.config == nil {
+		ce.config = math_config.NewDefaultCalcConfig()
+	}
+	return nil
+}
+
+// CompileJSON 从 JSON 重建预编译表达式，使用 config 的副本
+func CompileJSON(data []byte, config *math_config.CalcConfig) (*CompiledExpression, error) {
+	// 复制配置，为 nil 时使用默认配置
+	config = config.Clone()
+
+	ce := &CompiledExpression{config: config}
+	if err := ce.UnmarshalJSON(data); err != nil {
+		return nil, internal.Localize(err, config.Locale)
+	}
+	return ce, nil
+}
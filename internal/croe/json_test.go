@@ -0,0 +1,95 @@
+package croe
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/ZHOUXING1997/math_calculation/internal"
+	"github.com/ZHOUXING1997/math_calculation/math_config"
+)
+
+func TestCompiledExpression_JSON(t *testing.T) {
+	compiled, err := Compile("qty * price - min(discount, 5)", math_config.NewDefaultCalcConfig())
+	if err != nil {
+		t.Fatalf("Compile() error = %v", err)
+	}
+
+	data, err := json.Marshal(compiled)
+	if err != nil {
+		t.Fatalf("json.Marshal() error = %v", err)
+	}
+	if !strings.Contains(string(data), `"version":1`) || !strings.Contains(string(data), `"expression":"qty * price - min(discount, 5)"`) {
+		t.Errorf("json.Marshal() = %s, missing version or expression", data)
+	}
+
+	restored, err := CompileJSON(data, nil)
+	if err != nil {
+		t.Fatalf("CompileJSON() error = %v", err)
+	}
+	if restored.String() != compiled.String() || restored.Expression() != compiled.Expression() {
+		t.Errorf("CompileJSON() = %q, want %q", restored.String(), compiled.String())
+	}
+
+	vars := map[string]decimal.Decimal{
+		"qty":      decimal.NewFromInt(3),
+		"price":    decimal.NewFromInt(10),
+		"discount": decimal.NewFromInt(8),
+	}
+	got, err := restored.Evaluate(vars)
+	if err != nil {
+		t.Fatalf("Evaluate() error = %v", err)
+	}
+	if !got.Equal(decimal.NewFromInt(25)) {
+		t.Errorf("Evaluate() = %v, want 25", got)
+	}
+
+	// 也可以直接反序列化到零值
+	var decoded CompiledExpression
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("json.Unmarshal() error = %v", err)
+	}
+	if decoded.String() != compiled.String() {
+		t.Errorf("json.Unmarshal() = %q, want %q", decoded.String(), compiled.String())
+	}
+
+	// 已经编译的预编译表达式可能被共享，不能被覆盖
+	other, err := Compile("a + b", nil)
+	if err != nil {
+		t.Fatalf("Compile() error = %v", err)
+	}
+	data, _ = json.Marshal(other)
+	err = json.Unmarshal(data, compiled)
+	if !errors.Is(err, internal.ErrInvalidArgument) || internal.Localize(err, internal.LocaleEn).Error() != "position 0: can only unmarshal into a new compiled expression: invalid argument" {
+		t.Errorf("json.Unmarshal() into a compiled expression error = %v", err)
+	}
+	if compiled.String() != "qty * price - min(discount, 5)" {
+		t.Errorf("json.Unmarshal() changed the compiled expression to %q", compiled.String())
+	}
+}
+
+func TestCompileJSON_Invalid(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     string
+		errorMsg string
+	}{
+		{name: "无效JSON", data: `{`, errorMsg: "无效的表达式 JSON"},
+		{name: "缺少版本", data: `{"ast":{"kind":"number","value":"1"}}`, errorMsg: "不支持的表达式 JSON 版本: 0"},
+		{name: "未来版本", data: `{"version":99,"ast":{"kind":"number","value":"1"}}`, errorMsg: "不支持的表达式 JSON 版本: 99"},
+		{name: "缺少语法树", data: `{"version":1}`, errorMsg: "节点为空"},
+		{name: "无效节点", data: `{"version":1,"ast":{"kind":"binary","operator":"+"}}`, errorMsg: "二元运算符需要 2 个子节点"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := CompileJSON([]byte(tt.data), nil)
+			if err == nil || !strings.Contains(err.Error(), tt.errorMsg) {
+				t.Errorf("CompileJSON() error = %v, want containing %q", err, tt.errorMsg)
+			}
+		})
+	}
+}
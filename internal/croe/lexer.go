@@ -2,6 +2,7 @@ package croe
 
 import (
 	"regexp"
+	"unicode/utf8"
 
 	"github.com/ZHOUXING1997/math_calculation/internal/math_utils"
 	"github.com/ZHOUXING1997/math_calculation/math_config"
@@ -35,12 +36,12 @@ type Token struct {
 
 // 正则表达式定义
 var (
-	numberRegex   = regexp.MustCompile(`^-?\d+(\.\d*)?$`)
+	numberRegex   = regexp.MustCompile(`^-?\d+(\.\d*)?([eE][-+]?\d+)?$`)
 	variableRegex = regexp.MustCompile(`^[a-zA-Z_]\w*$`)
 	// 匹配后跟开括号的单词
 	functionRegex = regexp.MustCompile(`^[a-zA-Z_]\w*\(`)
 	// 分离数字、变量、运算符和括号的正则表达式
-	tokenRegex = regexp.MustCompile(`\s*(-?\d+(\.\d*)?|[a-zA-Z_]\w*|[-+*/^(),])`)
+	tokenRegex = regexp.MustCompile(`\s*(-?\d+(\.\d*)?([eE][-+]?\d+)?|[a-zA-Z_]\w*|[-+*/^(),])`)
 )
 
 // Lexer 词法分析器结构体
@@ -91,41 +92,50 @@ func (l *Lexer) Lex(input string) []Token {
 
 	for pos < len_bytes {
 		// 跳过空白字符
-		if bytes[pos] == ' ' {
+		if math_utils.IsSpace(bytes[pos]) {
 			pos++
 			continue
 		}
 
-		// 检查函数名或变量名（字母或下划线开头）
-		if pos < len_bytes && (math_utils.IsAlpha(bytes[pos]) || bytes[pos] == '_') {
+		// 检查函数名或变量名：字母（包括中文等 Unicode 字母）或下划线开头，或者是反引号、方括号引用的名称
+		// 变量名可以是点号分隔的路径，如 order.total、item[0].price、`unit price`、[单价]
+		if math_utils.IsSegmentStart(input, pos) {
 			start := pos
-			// 读取标识符
-			for pos < len_bytes && math_utils.IsIdentifierChar(bytes[pos]) {
-				pos++
+			end, segments, quoted, ok := math_utils.ScanIdentifier(input, pos)
+			pos = end
+			if !ok {
+				// 引用没有结束或为空，整个片段作为错误标记
+				token := GetToken()
+				token.Type = TokenError
+				token.Value = input[start:end]
+				token.Pos = start
+				tokens = append(tokens, *token)
+				PutToken(token)
+				continue
 			}
 
 			// 跳过空白字符
 			tempPos := pos
-			for tempPos < len_bytes && bytes[tempPos] == ' ' {
+			for tempPos < len_bytes && math_utils.IsSpace(bytes[tempPos]) {
 				tempPos++
 			}
 
-			// 检查是否是函数调用
-			if tempPos < len_bytes && bytes[tempPos] == '(' {
+			// 检查是否是函数调用，只有普通名称可以作为函数名
+			if !quoted && len(segments) == 1 && tempPos < len_bytes && bytes[tempPos] == '(' {
 				// 使用对象池获取Token对象
 				token := GetToken()
 				token.Type = TokenFunc
-				token.Value = string(bytes[start:pos])
+				token.Value = input[start:pos]
 				token.Pos = start
 				tokens = append(tokens, *token)
 				PutToken(token) // 归还到对象池
 				pos = tempPos   // 更新位置
 				continue
 			} else {
-				// 这是一个变量
+				// 这是一个变量，标记的值保留原始写法，由解析器解析为变量名和路径
 				token := GetToken()
 				token.Type = TokenVariable
-				token.Value = string(bytes[start:pos])
+				token.Value = input[start:pos]
 				token.Pos = start
 				tokens = append(tokens, *token)
 				PutToken(token)
@@ -168,6 +178,20 @@ func (l *Lexer) Lex(input string) []Token {
 				}
 			}
 
+			// 处理科学计数法的指数部分，e 之后必须有数字，否则 e 属于之后的变量名
+			if pos < len_bytes && (bytes[pos] == 'e' || bytes[pos] == 'E') {
+				exp := pos + 1
+				if exp < len_bytes && (bytes[exp] == '+' || bytes[exp] == '-') {
+					exp++
+				}
+				if exp < len_bytes && math_utils.IsDigit(bytes[exp]) {
+					pos = exp
+					for pos < len_bytes && math_utils.IsDigit(bytes[pos]) {
+						pos++
+					}
+				}
+			}
+
 			token := GetToken()
 			token.Type = TokenNumber
 			token.Value = string(bytes[start:pos])
@@ -208,8 +232,14 @@ func (l *Lexer) Lex(input string) []Token {
 			token.Value = ","
 		default:
 			// 只取当前字符作为错误标记，而不是尝试读取更多字符
+			// 多字节字符（如中文）整体作为一个标记，保证错误位置和长度落在字符边界上
+			_, size := utf8.DecodeRune(bytes[pos:])
 			token.Type = TokenError
-			token.Value = string([]byte{bytes[pos]})
+			token.Value = string(bytes[pos : pos+size])
+			tokens = append(tokens, *token)
+			PutToken(token)
+			pos += size
+			continue
 		}
 
 		tokens = append(tokens, *token)
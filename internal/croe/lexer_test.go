@@ -47,6 +47,34 @@ func TestLexer_Lex(t *testing.T) {
 				{Type: TokenNumber, Value: "-123.456", Pos: 0},
 			},
 		},
+		{
+			name:  "科学计数法",
+			input: "1e5 * -2.5E-3",
+			expected: []Token{
+				{Type: TokenNumber, Value: "1e5", Pos: 0},
+				{Type: TokenAsterisk, Value: "*", Pos: 4},
+				{Type: TokenNumber, Value: "-2.5E-3", Pos: 6},
+			},
+		},
+		{
+			name:  "e 之后没有数字时属于变量",
+			input: "2e+x",
+			expected: []Token{
+				{Type: TokenNumber, Value: "2", Pos: 0},
+				{Type: TokenVariable, Value: "e", Pos: 1},
+				{Type: TokenPlus, Value: "+", Pos: 2},
+				{Type: TokenVariable, Value: "x", Pos: 3},
+			},
+		},
+		{
+			name:  "垂直制表符和换页符",
+			input: "x\v+\fy",
+			expected: []Token{
+				{Type: TokenVariable, Value: "x", Pos: 0},
+				{Type: TokenPlus, Value: "+", Pos: 2},
+				{Type: TokenVariable, Value: "y", Pos: 4},
+			},
+		},
 		{
 			name:  "单个变量",
 			input: "x",
@@ -178,6 +206,64 @@ func TestLexer_Lex(t *testing.T) {
 				{Type: TokenRParen, Value: ")", Pos: 26},
 			},
 		},
+		{
+			name:  "多行表达式",
+			input: "x\t+\n  sqrt (y)",
+			expected: []Token{
+				{Type: TokenVariable, Value: "x", Pos: 0},
+				{Type: TokenPlus, Value: "+", Pos: 2},
+				{Type: TokenFunc, Value: "sqrt", Pos: 6},
+				{Type: TokenLParen, Value: "(", Pos: 11},
+				{Type: TokenVariable, Value: "y", Pos: 12},
+				{Type: TokenRParen, Value: ")", Pos: 13},
+			},
+		},
+		{
+			name:  "点号分隔的变量路径",
+			input: "customer.level * order._qty",
+			expected: []Token{
+				{Type: TokenVariable, Value: "customer.level", Pos: 0},
+				{Type: TokenAsterisk, Value: "*", Pos: 15},
+				{Type: TokenVariable, Value: "order._qty", Pos: 17},
+			},
+		},
+		{
+			name:  "反引号和方括号引用的变量",
+			input: "`unit price` * [单价]",
+			expected: []Token{
+				{Type: TokenVariable, Value: "`unit price`", Pos: 0},
+				{Type: TokenAsterisk, Value: "*", Pos: 13},
+				{Type: TokenVariable, Value: "[单价]", Pos: 15},
+			},
+		},
+		{
+			name:  "下标和 Unicode 变量名",
+			input: "order.items[0].price+数量",
+			expected: []Token{
+				{Type: TokenVariable, Value: "order.items[0].price", Pos: 0},
+				{Type: TokenPlus, Value: "+", Pos: 20},
+				{Type: TokenVariable, Value: "数量", Pos: 21},
+			},
+		},
+		{
+			name:  "引用的名称后面的括号不是函数调用",
+			input: "`max`(1)",
+			expected: []Token{
+				{Type: TokenVariable, Value: "`max`", Pos: 0},
+				{Type: TokenLParen, Value: "(", Pos: 5},
+				{Type: TokenNumber, Value: "1", Pos: 6},
+				{Type: TokenRParen, Value: ")", Pos: 7},
+			},
+		},
+		{
+			name:  "没有结束的引用",
+			input: "1 + `unit price",
+			expected: []Token{
+				{Type: TokenNumber, Value: "1", Pos: 0},
+				{Type: TokenPlus, Value: "+", Pos: 2},
+				{Type: TokenError, Value: "`unit price", Pos: 4},
+			},
+		},
 		{
 			name:  "带错误字符的表达式",
 			input: "x + y @ z",
@@ -1,12 +1,11 @@
 package croe
 
 import (
-	"fmt"
-
 	"github.com/shopspring/decimal"
 
 	"github.com/ZHOUXING1997/math_calculation/internal"
 	"github.com/ZHOUXING1997/math_calculation/internal/math_node"
+	"github.com/ZHOUXING1997/math_calculation/internal/math_utils"
 	"github.com/ZHOUXING1997/math_calculation/math_config"
 )
 
@@ -17,6 +16,8 @@ type Parser struct {
 	vars       map[string]decimal.Decimal // 变量映射
 	expression string                     // 原始表达式
 	config     *math_config.CalcConfig    // 计算配置
+	recovering bool                       // 是否处于错误恢复模式
+	errs       []*internal.ParseError     // 错误恢复模式下收集的错误
 }
 
 // NewParser 创建新的解析器
@@ -32,24 +33,65 @@ func NewParser(vars map[string]decimal.Decimal, config *math_config.CalcConfig)
 	}
 }
 
-// Parse 解析表达式
+// Parse 解析表达式，错误中记录了表达式以便输出行号、列号和诊断信息
 func (p *Parser) Parse(expression string) (math_node.Node, error) {
-	// 检查表达式是否为空
+	node, err := p.parse(expression)
+	if err != nil {
+		return nil, internal.Locate(err, expression)
+	}
+	return node, nil
+}
+
+// ParseAll 以错误恢复模式解析表达式，遇到错误时在逗号、右括号处同步后继续解析，返回所有语法错误
+// 存在错误时返回的表达式树中出错的部分被替换为占位的数字 0，只能用于分析，不能用于计算；不使用缓存
+func (p *Parser) ParseAll(expression string) (math_node.Node, []error) {
 	if len(expression) == 0 {
-		return nil, &internal.ParseError{
-			Pos:     0,
-			Message: "空表达式",
-			Cause:   internal.ErrInvalidExpression,
+		return nil, []error{internal.Locate(internal.NewParseError(0, internal.ErrInvalidExpression, "", internal.MsgEmptyExpression), expression)}
+	}
+
+	p.expression = expression
+	p.tokens = NewLexer(p.config).Lex(expression)
+	p.pos = 0
+	p.recovering = true
+	p.errs = nil
+	defer func() {
+		p.recovering = false
+		p.errs = nil
+	}()
+
+	// 解析表达式，错误恢复模式下不会返回错误
+	node, _ := p.parseExpr()
+
+	// 剩余的标记逐个报告为意外的标记，并继续解析之后的部分以发现更多错误
+	// 停在同步点的标记可能已经报告过，不重复报告
+	for p.pos < len(p.tokens) {
+		token := p.tokens[p.pos]
+		if !p.reported(token.Pos) {
+			p.errs = append(p.errs, internal.NewParseError(token.Pos, internal.ErrInvalidExpression, token.Value, internal.MsgUnexpectedToken, token.Value))
 		}
+		p.pos++
+		if p.pos < len(p.tokens) && p.tokens[p.pos].Type != TokenComma && p.tokens[p.pos].Type != TokenRParen {
+			_, _ = p.parseExpr()
+		}
+	}
+
+	errs := make([]error, len(p.errs))
+	for i, err := range p.errs {
+		errs[i] = internal.Locate(err, expression)
+	}
+	return node, errs
+}
+
+// parse 解析表达式
+func (p *Parser) parse(expression string) (math_node.Node, error) {
+	// 检查表达式是否为空
+	if len(expression) == 0 {
+		return nil, internal.NewParseError(0, internal.ErrInvalidExpression, "", internal.MsgEmptyExpression)
 	}
 
 	// 如果表达式过长，可能是恶意输入，直接拒绝
 	if len(expression) > 10000 {
-		return nil, &internal.ParseError{
-			Pos:     0,
-			Message: "表达式过长",
-			Cause:   internal.ErrInvalidExpression,
-		}
+		return nil, internal.NewParseError(0, internal.ErrInvalidExpression, "", internal.MsgExpressionTooLong)
 	}
 
 	// 空指针检查
@@ -74,11 +116,7 @@ func (p *Parser) Parse(expression string) (math_node.Node, error) {
 
 	// 检查标记数量，防止恶意输入
 	if len(p.tokens) > 1000 {
-		return nil, &internal.ParseError{
-			Pos:     0,
-			Message: "表达式复杂度过高",
-			Cause:   internal.ErrInvalidExpression,
-		}
+		return nil, internal.NewParseError(0, internal.ErrInvalidExpression, "", internal.MsgExpressionTooComplex)
 	}
 
 	// 解析表达式
@@ -110,18 +148,10 @@ func (p *Parser) Parse(expression string) (math_node.Node, error) {
 			// 继续检查是否还有未消耗的标记
 			if p.pos < len(p.tokens) {
 				token = p.tokens[p.pos]
-				return nil, &internal.ParseError{
-					Pos:     token.Pos,
-					Message: fmt.Sprintf("意外的标记: %s", token.Value),
-					Cause:   internal.ErrInvalidExpression,
-				}
+				return nil, internal.NewParseError(token.Pos, internal.ErrInvalidExpression, token.Value, internal.MsgUnexpectedToken, token.Value)
 			}
 		} else {
-			return nil, &internal.ParseError{
-				Pos:     token.Pos,
-				Message: fmt.Sprintf("意外的标记: %s", token.Value),
-				Cause:   internal.ErrInvalidExpression,
-			}
+			return nil, internal.NewParseError(token.Pos, internal.ErrInvalidExpression, token.Value, internal.MsgUnexpectedToken, token.Value)
 		}
 	}
 
@@ -259,11 +289,7 @@ func (p *Parser) parsePower() (math_node.Node, error) {
 func (p *Parser) parseFactor() (math_node.Node, error) {
 	// 检查是否到达表达式结尾
 	if p.pos >= len(p.tokens) {
-		return nil, &internal.ParseError{
-			Pos:     len(p.expression),
-			Message: "表达式意外结束",
-			Cause:   internal.ErrInvalidExpression,
-		}
+		return p.fail(internal.NewParseError(len(p.expression), internal.ErrInvalidExpression, "", internal.MsgUnexpectedEnd))
 	}
 
 	// 获取当前标记
@@ -276,20 +302,17 @@ func (p *Parser) parseFactor() (math_node.Node, error) {
 		// 解析数字
 		val, err := decimal.NewFromString(token.Value)
 		if err != nil {
-			return nil, &internal.ParseError{
-				Pos:     token.Pos,
-				Message: fmt.Sprintf("无效的数字: %s", token.Value),
-				Cause:   err,
-			}
+			return p.fail(internal.NewParseError(token.Pos, err, token.Value, internal.MsgInvalidNumber, token.Value))
 		}
 		// 使用对象池获取NumberNode
 		node := GetNumberNode()
 		node.Value = val
+		node.Pos = token.Pos
 		return node, nil
 	case TokenVariable:
 		// 解析变量，使用对象池
 		node := GetVariableNode()
-		node.VarName = token.Value
+		node.VarName, node.Path, _ = math_utils.ParseIdentifier(token.Value)
 		node.Pos = token.Pos
 		return node, nil
 	case TokenPlus, TokenMinus:
@@ -312,13 +335,12 @@ func (p *Parser) parseFactor() (math_node.Node, error) {
 		}
 		// 检查右括号
 		if p.pos >= len(p.tokens) || p.tokens[p.pos].Type != TokenRParen {
-			return nil, &internal.ParseError{
-				Pos:     token.Pos,
-				Message: "缺少右括号",
-				Cause:   internal.ErrInvalidExpression,
+			if err := p.missingRParen(internal.NewParseError(token.Pos, internal.ErrInvalidExpression, token.Value, internal.MsgMissingRightParen)); err != nil {
+				return nil, err
 			}
+		} else {
+			p.pos++
 		}
-		p.pos++
 		return expr, nil
 	case TokenFunc:
 		// 解析函数调用
@@ -327,11 +349,7 @@ func (p *Parser) parseFactor() (math_node.Node, error) {
 
 		// 检查左括号
 		if p.pos >= len(p.tokens) || p.tokens[p.pos].Type != TokenLParen {
-			return nil, &internal.ParseError{
-				Pos:     funcPos,
-				Message: fmt.Sprintf("函数 %s 后缺少左括号", funcName),
-				Cause:   internal.ErrInvalidExpression,
-			}
+			return p.fail(internal.NewParseError(funcPos, internal.ErrInvalidExpression, funcName, internal.MsgFunctionMissingLeftParen, funcName))
 		}
 		p.pos++
 
@@ -343,13 +361,12 @@ func (p *Parser) parseFactor() (math_node.Node, error) {
 
 		// 检查右括号
 		if p.pos >= len(p.tokens) || p.tokens[p.pos].Type != TokenRParen {
-			return nil, &internal.ParseError{
-				Pos:     funcPos,
-				Message: fmt.Sprintf("函数 %s 缺少右括号", funcName),
-				Cause:   internal.ErrInvalidExpression,
+			if err := p.missingRParen(internal.NewParseError(funcPos, internal.ErrInvalidExpression, funcName, internal.MsgFunctionMissingRightParen, funcName)); err != nil {
+				return nil, err
 			}
+		} else {
+			p.pos++
 		}
-		p.pos++
 
 		// 使用对象池获取FunctionNode
 		node := GetFunctionNode()
@@ -358,13 +375,105 @@ func (p *Parser) parseFactor() (math_node.Node, error) {
 		node.Pos = funcPos
 		return node, nil
 	default:
+		// 逗号和右括号是同步点，留给外层的函数调用或括号表达式处理
+		if token.Type == TokenComma || token.Type == TokenRParen {
+			p.pos--
+		}
 		// 处理意外的标记
-		return nil, &internal.ParseError{
-			Pos:     token.Pos,
-			Message: fmt.Sprintf("意外的标记: %s", token.Value),
-			Cause:   internal.ErrInvalidExpression,
+		err := internal.NewParseError(token.Pos, internal.ErrInvalidExpression, token.Value, internal.MsgUnexpectedToken, token.Value)
+		// 错误恢复模式下跳过多余的运算符，从之后的操作数继续解析，以便发现同一层中之后的错误，如 1 + * 2 + * 3
+		if p.recovering && p.skipToOperand() {
+			p.errs = append(p.errs, err)
+			return p.parseFactor()
+		}
+		return p.fail(err)
+	}
+}
+
+// skipToOperand 跳过不能作为操作数开头的标记，停在可以作为操作数开头的标记处时返回 true，
+// 遇到逗号、右括号或结尾时返回 false
+func (p *Parser) skipToOperand() bool {
+	for ; p.pos < len(p.tokens); p.pos++ {
+		switch p.tokens[p.pos].Type {
+		case TokenNumber, TokenVariable, TokenPlus, TokenMinus, TokenLParen, TokenFunc:
+			return true
+		case TokenComma, TokenRParen:
+			return false
+		}
+	}
+	return false
+}
+
+// fail 处理解析错误：普通模式下直接返回错误
+// 错误恢复模式下记录错误，跳到下一个同步点（同一层的逗号、右括号或结尾），返回占位节点继续解析
+func (p *Parser) fail(err *internal.ParseError) (math_node.Node, error) {
+	if !p.recovering {
+		return nil, err
+	}
+	p.errs = append(p.errs, err)
+
+	depth := 0
+	for p.pos < len(p.tokens) {
+		switch p.tokens[p.pos].Type {
+		case TokenLParen:
+			depth++
+		case TokenRParen:
+			if depth == 0 {
+				return &math_node.NumberNode{Value: decimal.Zero, Pos: err.Pos}, nil
+			}
+			depth--
+		case TokenComma:
+			if depth == 0 {
+				return &math_node.NumberNode{Value: decimal.Zero, Pos: err.Pos}, nil
+			}
 		}
+		p.pos++
 	}
+	return &math_node.NumberNode{Value: decimal.Zero, Pos: err.Pos}, nil
+}
+
+// reported 判断错误恢复模式下是否已经报告过 pos 处的错误
+func (p *Parser) reported(pos int) bool {
+	for _, err := range p.errs {
+		if err.Pos == pos {
+			return true
+		}
+	}
+	return false
+}
+
+// missingRParen 处理缺少右括号的错误：普通模式下直接返回错误
+// 错误恢复模式下，如果右括号之前有多余的标记则报告该标记并跳过到同一层的右括号之后，
+// 没有找到右括号时记录 err，之后继续解析
+func (p *Parser) missingRParen(err *internal.ParseError) error {
+	if !p.recovering {
+		return err
+	}
+
+	if p.pos < len(p.tokens) {
+		// 同步点处的标记可能已经报告过，不重复报告
+		token := p.tokens[p.pos]
+		if !p.reported(token.Pos) {
+			p.errs = append(p.errs, internal.NewParseError(token.Pos, internal.ErrInvalidExpression, token.Value, internal.MsgUnexpectedToken, token.Value))
+		}
+
+		depth := 0
+		for ; p.pos < len(p.tokens); p.pos++ {
+			switch p.tokens[p.pos].Type {
+			case TokenLParen:
+				depth++
+			case TokenRParen:
+				if depth == 0 {
+					p.pos++
+					return nil
+				}
+				depth--
+			}
+		}
+	}
+
+	p.errs = append(p.errs, err)
+	return nil
 }
 
 // parseArguments 解析函数参数列表
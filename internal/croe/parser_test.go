@@ -2,12 +2,16 @@ package croe
 
 import (
 	"context"
+	"errors"
+	"reflect"
 	"strings"
 	"testing"
 
 	"github.com/shopspring/decimal"
 
 	"github.com/ZHOUXING1997/math_calculation/math_config"
+
+	"github.com/ZHOUXING1997/math_calculation/internal"
 )
 
 func TestParser_Parse(t *testing.T) {
@@ -346,3 +350,136 @@ func TestParser_ParseArguments(t *testing.T) {
 func containsString(s, substr string) bool {
 	return strings.Contains(s, substr)
 }
+
+func TestParser_ParseErrorSpan(t *testing.T) {
+	tests := []struct {
+		name       string
+		expression string
+		wantToken  string
+		wantLine   int
+		wantColumn int
+		wantLength int
+	}{
+		{
+			name:       "意外的标记",
+			expression: "1 + * 2",
+			wantToken:  "*",
+			wantLine:   1,
+			wantColumn: 5,
+			wantLength: 1,
+		},
+		{
+			name:       "多行表达式中的中文",
+			expression: "a +\n  b * ￥5",
+			wantToken:  "￥",
+			wantLine:   2,
+			wantColumn: 7,
+			wantLength: 3,
+		},
+		{
+			name:       "缺少右括号",
+			expression: "2 * (a + b",
+			wantToken:  "(",
+			wantLine:   1,
+			wantColumn: 5,
+			wantLength: 1,
+		},
+		{
+			name:       "表达式意外结束",
+			expression: "a +",
+			wantToken:  "",
+			wantLine:   1,
+			wantColumn: 4,
+			wantLength: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parser := NewParser(nil, &math_config.CalcConfig{})
+			_, err := parser.Parse(tt.expression)
+
+			var parseErr *internal.ParseError
+			if !errors.As(err, &parseErr) {
+				t.Fatalf("Parser.Parse() error = %v, want *ParseError", err)
+			}
+			if parseErr.Token != tt.wantToken || parseErr.Line != tt.wantLine ||
+				parseErr.Column != tt.wantColumn || parseErr.Length != tt.wantLength {
+				t.Errorf("Parser.Parse() error span = %q %d:%d length %d, want %q %d:%d length %d",
+					parseErr.Token, parseErr.Line, parseErr.Column, parseErr.Length,
+					tt.wantToken, tt.wantLine, tt.wantColumn, tt.wantLength)
+			}
+		})
+	}
+}
+
+func TestParser_ParseAll(t *testing.T) {
+	tests := []struct {
+		name       string
+		expression string
+		wantTokens []string
+	}{
+		{
+			name:       "没有错误",
+			expression: "max(a, b) + 1",
+			wantTokens: nil,
+		},
+		{
+			name:       "多个意外的标记",
+			expression: "1 + * 2, )",
+			wantTokens: []string{"*", ",", ")"},
+		},
+		{
+			name:       "函数参数在逗号处恢复",
+			expression: "max(, 1, *) + sqrt()",
+			wantTokens: []string{",", "*"},
+		},
+		{
+			name:       "括号中多余的标记",
+			expression: "(a, b) + (1 2)",
+			wantTokens: []string{",", "2"},
+		},
+		{
+			name:       "缺少右括号",
+			expression: "2 * (a + b",
+			wantTokens: []string{"("},
+		},
+		{
+			name:       "表达式意外结束",
+			expression: "a + (b -",
+			wantTokens: []string{"", "("},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parser := NewParser(nil, &math_config.CalcConfig{})
+			node, errs := parser.ParseAll(tt.expression)
+			if node == nil {
+				t.Fatalf("Parser.ParseAll() node = nil")
+			}
+
+			var tokens []string
+			for _, err := range errs {
+				var parseErr *internal.ParseError
+				if !errors.As(err, &parseErr) {
+					t.Fatalf("Parser.ParseAll() error = %v, want *ParseError", err)
+				}
+				if parseErr.Line == 0 {
+					t.Errorf("Parser.ParseAll() error %v is not located", err)
+				}
+				tokens = append(tokens, parseErr.Token)
+			}
+			if !reflect.DeepEqual(tokens, tt.wantTokens) {
+				t.Errorf("Parser.ParseAll() error tokens = %q, want %q", tokens, tt.wantTokens)
+			}
+		})
+	}
+
+	// 错误恢复模式不影响之后的普通解析
+	parser := NewParser(nil, &math_config.CalcConfig{})
+	parser.ParseAll("1 + * 2")
+	if _, err := parser.Parse("1 + * 2"); err == nil {
+		t.Errorf("Parser.Parse() after ParseAll() error = nil, want error")
+	}
+}
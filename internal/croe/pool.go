@@ -83,6 +83,7 @@ func GetNumberNode() *math_node.NumberNode {
 func PutNumberNode(node *math_node.NumberNode) {
 	// 重置节点
 	node.Value = decimal.Zero
+	node.Pos = 0
 	globalNodePool.numberPool.Put(node)
 }
 
@@ -95,6 +96,7 @@ func GetVariableNode() *math_node.VariableNode {
 func PutVariableNode(node *math_node.VariableNode) {
 	// 重置节点
 	node.VarName = ""
+	node.Path = nil
 	node.Pos = 0
 	globalNodePool.variablePool.Put(node)
 }
@@ -137,17 +137,18 @@ func formatStep(step DebugStep, indent int) string {
 
 // DebugCalculate 带调试信息的计算
 func DebugCalculate(expression string, vars map[string]decimal.Decimal, config *math_config.CalcConfig) (decimal.Decimal, *DebugInfo, error) {
+	return DebugCalculateContext(context.Background(), expression, vars, config)
+}
+
+// DebugCalculateContext 使用调用方的上下文进行带调试信息的计算
+func DebugCalculateContext(ctx context.Context, expression string, vars map[string]decimal.Decimal, config *math_config.CalcConfig) (decimal.Decimal, *DebugInfo, error) {
 	// 创建调试信息
 	debugInfo := NewDebugInfo(expression)
 	debugInfo.SetVariables(vars)
 
 	// 验证表达式
 	if len(expression) == 0 {
-		err := &internal.ParseError{
-			Pos:     0,
-			Message: "空表达式",
-			Cause:   internal.ErrInvalidExpression,
-		}
+		err := internal.NewParseError(0, internal.ErrInvalidExpression, "", internal.MsgEmptyExpression)
 		debugInfo.SetError(err)
 		return decimal.Zero, debugInfo, err
 	}
@@ -157,9 +158,8 @@ func DebugCalculate(expression string, vars map[string]decimal.Decimal, config *
 		config = math_config.NewDefaultCalcConfig()
 	}
 
-	// 创建上下文并设置超时
-	ctx := context.Background()
-	ctx, cancel := context.WithTimeout(ctx, config.Timeout)
+	// 从调用方的上下文派生计算使用的上下文，设置超时和运算次数预算
+	ctx, cancel := math_utils.EvalContext(ctx, config)
 	defer cancel()
 
 	// 创建解析器
@@ -0,0 +1,161 @@
+package internal
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// LineColumn 返回字节位置 pos 在表达式中的行号和列号，均从 1 开始
+// 列号按字符（rune）计数，中文标识符中的每个汉字算一列
+func LineColumn(expression string, pos int) (line, column int) {
+	pos = clampPos(expression, pos)
+	lineStart := strings.LastIndexByte(expression[:pos], '\n') + 1
+	line = strings.Count(expression[:lineStart], "\n") + 1
+	column = utf8.RuneCountInString(expression[lineStart:pos]) + 1
+	return line, column
+}
+
+// locator 可以记录出错表达式的错误
+type locator interface {
+	Locate(expression string) error
+}
+
+// Locate 返回记录了出错表达式并填充了行号和列号的错误副本，已记录过表达式的错误保持不变
+// 不支持定位的错误原样返回
+func Locate(err error, expression string) error {
+	if err == nil || expression == "" {
+		return err
+	}
+	if l, ok := err.(locator); ok {
+		return l.Locate(expression)
+	}
+	return err
+}
+
+// relocator 可以重新定位到原始表达式的错误
+type relocator interface {
+	Relocate(source string, offsets []int) error
+}
+
+// Relocate 将按改写后的表达式定位的错误映射回改写前的表达式 source，返回记录了 source 并填充了行号和列号的错误副本
+// offsets[i] 为改写后的表达式中字节位置 i 在 source 中的位置，最后一项对应改写后表达式的结尾
+// 不支持定位的错误原样返回
+func Relocate(err error, source string, offsets []int) error {
+	if err == nil || source == "" {
+		return err
+	}
+	if r, ok := err.(relocator); ok {
+		return r.Relocate(source, offsets)
+	}
+	return err
+}
+
+// MapSpan 按 offsets 将改写后的表达式中的片段 [pos, pos+length) 映射回原始表达式，返回映射后的位置和长度
+// length 为 0 时只映射位置
+func MapSpan(offsets []int, pos, length int) (int, int) {
+	start := mapOffset(offsets, pos)
+	if length <= 0 {
+		return start, length
+	}
+	return start, mapOffset(offsets, pos+length-1) + 1 - start
+}
+
+// mapOffset 按 offsets 将改写后的表达式中的位置映射为原始表达式中的位置，超出范围的位置映射到两端
+func mapOffset(offsets []int, pos int) int {
+	if len(offsets) == 0 {
+		return pos
+	}
+	if pos < 0 {
+		pos = 0
+	}
+	if pos >= len(offsets) {
+		pos = len(offsets) - 1
+	}
+	return offsets[pos]
+}
+
+// RenderDiagnostic 渲染诊断信息：标题、出错的表达式行，以及标出 [pos, pos+length) 的 ^~~~ 标记
+// 标记按终端显示宽度对齐，汉字等宽字符占两列
+func RenderDiagnostic(expression string, pos, length int, header string) string {
+	pos = clampPos(expression, pos)
+	lineStart := strings.LastIndexByte(expression[:pos], '\n') + 1
+	lineEnd := strings.IndexByte(expression[pos:], '\n')
+	if lineEnd < 0 {
+		lineEnd = len(expression)
+	} else {
+		lineEnd += pos
+	}
+	text := strings.TrimRight(expression[lineStart:lineEnd], "\r")
+
+	// 片段只标记到行尾
+	end := pos + length
+	if end > lineStart+len(text) {
+		end = lineStart + len(text)
+	}
+
+	var padding strings.Builder
+	for _, r := range expression[lineStart:pos] {
+		switch {
+		case r == '\t':
+			padding.WriteByte('\t')
+		case isWide(r):
+			padding.WriteString("  ")
+		default:
+			padding.WriteByte(' ')
+		}
+	}
+
+	width := 0
+	if end > pos {
+		for _, r := range expression[pos:end] {
+			width++
+			if isWide(r) {
+				width++
+			}
+		}
+	}
+	marker := "^"
+	if width > 1 {
+		marker += strings.Repeat("~", width-1)
+	}
+
+	line, _ := LineColumn(expression, pos)
+	gutter := strconv.Itoa(line)
+	blank := strings.Repeat(" ", len(gutter))
+
+	var sb strings.Builder
+	sb.WriteString(header)
+	sb.WriteString("\n ")
+	sb.WriteString(gutter)
+	sb.WriteString(" | ")
+	sb.WriteString(text)
+	sb.WriteString("\n ")
+	sb.WriteString(blank)
+	sb.WriteString(" | ")
+	sb.WriteString(padding.String())
+	sb.WriteString(marker)
+	return sb.String()
+}
+
+// clampPos 将位置限制在表达式范围内，并对齐到字符边界
+func clampPos(expression string, pos int) int {
+	if pos < 0 {
+		return 0
+	}
+	if pos > len(expression) {
+		return len(expression)
+	}
+	for pos > 0 && pos < len(expression) && !utf8.RuneStart(expression[pos]) {
+		pos--
+	}
+	return pos
+}
+
+// isWide 判断字符在终端中是否占两列
+func isWide(r rune) bool {
+	return unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul) ||
+		(r >= 0x3000 && r <= 0x303F) || // CJK 标点
+		(r >= 0xFF01 && r <= 0xFF60) // 全角字符
+}
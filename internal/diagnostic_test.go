@@ -0,0 +1,112 @@
+package internal
+
+import (
+	"testing"
+)
+
+func TestLineColumn(t *testing.T) {
+	tests := []struct {
+		name       string
+		expression string
+		pos        int
+		wantLine   int
+		wantColumn int
+	}{
+		{name: "开头", expression: "a + b", pos: 0, wantLine: 1, wantColumn: 1},
+		{name: "第一行", expression: "a + b", pos: 4, wantLine: 1, wantColumn: 5},
+		{name: "第二行", expression: "a +\n  b * c", pos: 8, wantLine: 2, wantColumn: 5},
+		{name: "按字符计数", expression: "价格 + x", pos: 9, wantLine: 1, wantColumn: 6},
+		{name: "表达式末尾", expression: "a +", pos: 3, wantLine: 1, wantColumn: 4},
+		{name: "超出范围", expression: "a +", pos: 10, wantLine: 1, wantColumn: 4},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			line, column := LineColumn(tt.expression, tt.pos)
+			if line != tt.wantLine || column != tt.wantColumn {
+				t.Errorf("LineColumn() = %d:%d, want %d:%d", line, column, tt.wantLine, tt.wantColumn)
+			}
+		})
+	}
+}
+
+func TestRenderDiagnostic(t *testing.T) {
+	tests := []struct {
+		name       string
+		expression string
+		pos        int
+		length     int
+		want       string
+	}{
+		{
+			name:       "单个字符",
+			expression: "1 / 0",
+			pos:        2,
+			length:     1,
+			want:       "错误\n 1 | 1 / 0\n   |   ^",
+		},
+		{
+			name:       "多个字符",
+			expression: "qty * price",
+			pos:        6,
+			length:     5,
+			want:       "错误\n 1 | qty * price\n   |       ^~~~~",
+		},
+		{
+			name:       "多行表达式",
+			expression: "a +\r\n\tb * 价格",
+			pos:        10,
+			length:     3,
+			want:       "错误\n 2 | \tb * 价格\n   | \t    ^~",
+		},
+		{
+			name:       "汉字之后",
+			expression: "价格 + x",
+			pos:        9,
+			length:     1,
+			want:       "错误\n 1 | 价格 + x\n   |        ^",
+		},
+		{
+			name:       "表达式末尾",
+			expression: "a +",
+			pos:        3,
+			length:     0,
+			want:       "错误\n 1 | a +\n   |    ^",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := RenderDiagnostic(tt.expression, tt.pos, tt.length, "错误"); got != tt.want {
+				t.Errorf("RenderDiagnostic() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseError_Diagnostic(t *testing.T) {
+	err := NewParseError(6, ErrUndefinedVariable, "price", MsgUndefinedVariable, "price")
+
+	// 未记录表达式时与 Error() 相同
+	if got := err.Diagnostic(); got != err.Error() {
+		t.Errorf("Diagnostic() = %q, want %q", got, err.Error())
+	}
+
+	located := Locate(err, "qty * price").(*ParseError)
+	if located.Line != 1 || located.Column != 7 || located.Length != 5 {
+		t.Errorf("Locate() = %d:%d length %d", located.Line, located.Column, located.Length)
+	}
+	if err.Line != 0 {
+		t.Errorf("Locate() modified original error")
+	}
+
+	want := "line 1, column 7: undefined variable: price: undefined variable\n 1 | qty * price\n   |       ^~~~~"
+	if got := Localize(located, LocaleEn).(*ParseError).Diagnostic(); got != want {
+		t.Errorf("Diagnostic() = %q, want %q", got, want)
+	}
+
+	// 已经记录过表达式的错误保持不变
+	if got := Locate(located, "other"); got != located {
+		t.Errorf("Locate() should keep the first expression")
+	}
+}
@@ -3,31 +3,285 @@ package internal
 
 import (
 	"errors"
-	"fmt"
+	"strings"
 )
 
 // 错误类型定义
 var (
-	ErrDivisionByZero      = errors.New("除以零错误")
-	ErrUndefinedVariable   = errors.New("未定义的变量")
-	ErrUnsupportedOperator = errors.New("不支持的运算符")
-	ErrInvalidExpression   = errors.New("无效的表达式")
-	ErrInvalidArgument     = errors.New("无效的参数")
-	ErrMaxRecursionDepth   = errors.New("超过最大递归深度")
-	ErrExecutionTimeout    = errors.New("执行超时")
+	ErrDivisionByZero       = errors.New("除以零错误")
+	ErrUndefinedVariable    = errors.New("未定义的变量")
+	ErrUnsupportedOperator  = errors.New("不支持的运算符")
+	ErrInvalidExpression    = errors.New("无效的表达式")
+	ErrInvalidArgument      = errors.New("无效的参数")
+	ErrMaxRecursionDepth    = errors.New("超过最大递归深度")
+	ErrExecutionTimeout     = errors.New("执行超时")
+	ErrNotDifferentiable    = errors.New("无法求导")
+	ErrValidationFailed     = errors.New("表达式验证失败")
+	ErrInternal             = errors.New("内部错误")
+	ErrResourceLimit        = errors.New("超过资源限制")
+	ErrCanceled             = errors.New("计算被取消")
+	ErrNullResult           = errors.New("结果为空")
+	ErrCircularReference    = errors.New("公式存在循环引用")
+	ErrFormulaNotRegistered = errors.New("公式未注册")
 )
 
+// ErrorCode 机器可读的错误码，取值保持稳定，可用于映射 HTTP 状态码等
+type ErrorCode string
+
+// 错误码定义
+const (
+	CodeUnknown              ErrorCode = "unknown"
+	CodeDivisionByZero       ErrorCode = "division_by_zero"
+	CodeUndefinedVariable    ErrorCode = "undefined_variable"
+	CodeUnsupportedOperator  ErrorCode = "unsupported_operator"
+	CodeInvalidExpression    ErrorCode = "invalid_expression"
+	CodeInvalidArgument      ErrorCode = "invalid_argument"
+	CodeMaxRecursionDepth    ErrorCode = "max_recursion_depth"
+	CodeExecutionTimeout     ErrorCode = "execution_timeout"
+	CodeNotDifferentiable    ErrorCode = "not_differentiable"
+	CodeValidationFailed     ErrorCode = "validation_failed"
+	CodeInternal             ErrorCode = "internal"
+	CodeResourceLimit        ErrorCode = "resource_limit"
+	CodeCanceled             ErrorCode = "canceled"
+	CodeNullResult           ErrorCode = "null_result"
+	CodeCircularReference    ErrorCode = "circular_reference"
+	CodeFormulaNotRegistered ErrorCode = "formula_not_registered"
+)
+
+// errorCodes 错误类型与错误码的对应关系
+var errorCodes = []struct {
+	err  error
+	code ErrorCode
+}{
+	{ErrDivisionByZero, CodeDivisionByZero},
+	{ErrUndefinedVariable, CodeUndefinedVariable},
+	{ErrUnsupportedOperator, CodeUnsupportedOperator},
+	{ErrInvalidExpression, CodeInvalidExpression},
+	{ErrInvalidArgument, CodeInvalidArgument},
+	{ErrMaxRecursionDepth, CodeMaxRecursionDepth},
+	{ErrExecutionTimeout, CodeExecutionTimeout},
+	{ErrNotDifferentiable, CodeNotDifferentiable},
+	{ErrValidationFailed, CodeValidationFailed},
+	{ErrInternal, CodeInternal},
+	{ErrResourceLimit, CodeResourceLimit},
+	{ErrCanceled, CodeCanceled},
+	{ErrNullResult, CodeNullResult},
+	{ErrCircularReference, CodeCircularReference},
+	{ErrFormulaNotRegistered, CodeFormulaNotRegistered},
+}
+
+// CodeOf 返回错误对应的错误码，err 为 nil 时返回空字符串
+// 原因不是预定义错误的 ParseError（例如数字格式错误）按无效表达式处理
+func CodeOf(err error) ErrorCode {
+	if err == nil {
+		return ""
+	}
+	for _, ec := range errorCodes {
+		if errors.Is(err, ec.err) {
+			return ec.code
+		}
+	}
+
+	var parseErr *ParseError
+	if errors.As(err, &parseErr) {
+		return CodeInvalidExpression
+	}
+	return CodeUnknown
+}
+
 // ParseError 解析错误结构体，包含详细的错误信息
 type ParseError struct {
-	Pos     int    // 错误位置
-	Message string // 错误消息
-	Cause   error  // 原始错误
+	Pos     int           // 错误位置（字节偏移）
+	Message string        // 错误消息（中文）
+	Cause   error         // 原始错误
+	Token   string        // 出错的标记、变量名或函数名，可能为空
+	Key     string        // 消息键，用于翻译错误消息，可能为空
+	Args    []interface{} // 消息参数
+	Length  int           // 出错片段的字节长度，0 表示只定位到一个位置
+	Line    int           // 行号，从 1 开始，未知时为 0
+	Column  int           // 列号，从 1 开始按字符计数，未知时为 0
+
+	// Suggestions 与出错的函数名或变量名相近的名称，按相似程度排序，可用于提供一键修正
+	Suggestions []string
+
+	locale string // 输出消息使用的语言，空表示中文
+	source string // 出错的表达式
+}
+
+// NewParseError 按消息键创建解析错误，Message 为对应的中文消息，出错片段为 token
+func NewParseError(pos int, cause error, token, key string, args ...interface{}) *ParseError {
+	return &ParseError{
+		Pos:     pos,
+		Message: Translate(LocaleZh, key, localizedArgs(LocaleZh, args)...),
+		Cause:   cause,
+		Token:   token,
+		Key:     key,
+		Args:    args,
+		Length:  len(token),
+	}
 }
 
 // Error 实现error接口
 func (e *ParseError) Error() string {
+	return e.LocalizedError(e.locale)
+}
+
+// LocalizedError 返回指定语言的错误消息
+func (e *ParseError) LocalizedError(locale string) string {
+	return Translate(locale, MsgPosition, e.Pos, e.detail(locale))
+}
+
+// detail 返回不含位置的错误消息
+func (e *ParseError) detail(locale string) string {
+	message := e.message(locale)
 	if e.Cause != nil {
-		return fmt.Sprintf("位置 %d: %s: %v", e.Pos, e.Message, e.Cause)
+		message += ": " + causeMessage(e.Cause, locale)
+	}
+	return message
+}
+
+// message 返回不含位置和原始错误的消息
+func (e *ParseError) message(locale string) string {
+	if e.Key == "" {
+		return e.Message
+	}
+	return Translate(locale, e.Key, localizedArgs(locale, e.Args)...)
+}
+
+// localizedArgs 返回格式化消息使用的参数，作为参数的 ParseError 替换为同一语言、不含位置和原始错误的消息
+// 例如变量提供者返回的 ParseError 作为获取变量失败的消息参数时，跟随外层错误的语言输出
+func localizedArgs(locale string, args []interface{}) []interface{} {
+	var localized []interface{}
+	for i, arg := range args {
+		if e, ok := arg.(*ParseError); ok {
+			if localized == nil {
+				localized = append([]interface{}(nil), args...)
+			}
+			localized[i] = e.message(locale)
+		}
+	}
+	if localized == nil {
+		return args
+	}
+	return localized
+}
+
+// Diagnostic 返回包含行号、列号、出错的表达式行和 ^~~~ 标记的诊断信息
+// 错误未记录表达式时返回 Error()
+func (e *ParseError) Diagnostic() string {
+	if e.source == "" {
+		return e.Error()
+	}
+	header := Translate(e.locale, MsgDiagnostic, e.Line, e.Column, e.detail(e.locale))
+	diagnostic := RenderDiagnostic(e.source, e.Pos, e.Length, header)
+	if len(e.Suggestions) > 0 {
+		diagnostic += "\n" + Translate(e.locale, MsgSuggestion, strings.Join(e.Suggestions, ", "))
+	}
+	return diagnostic
+}
+
+// WithSuggestions 设置相近的名称并返回错误本身，便于在创建错误时链式调用
+func (e *ParseError) WithSuggestions(suggestions []string) *ParseError {
+	e.Suggestions = suggestions
+	return e
+}
+
+// Localize 返回按指定语言输出消息的错误副本
+func (e *ParseError) Localize(locale string) error {
+	localized := *e
+	localized.locale = locale
+	return &localized
+}
+
+// Locate 返回记录了出错表达式的错误副本，并填充行号和列号
+func (e *ParseError) Locate(expression string) error {
+	if e.source != "" {
+		return e
 	}
-	return fmt.Sprintf("位置 %d: %s", e.Pos, e.Message)
+	located := *e
+	located.source = expression
+	located.Line, located.Column = LineColumn(expression, e.Pos)
+	return &located
+}
+
+// Relocate 返回映射回原始表达式 source 的错误副本，位置和出错片段按 offsets 映射，已记录的表达式被替换为 source
+func (e *ParseError) Relocate(source string, offsets []int) error {
+	relocated := *e
+	relocated.Pos, relocated.Length = MapSpan(offsets, e.Pos, e.Length)
+	relocated.source = source
+	relocated.Line, relocated.Column = LineColumn(source, relocated.Pos)
+	return &relocated
+}
+
+// Unwrap 返回原始错误，支持 errors.Is 和 errors.As
+func (e *ParseError) Unwrap() error {
+	return e.Cause
+}
+
+// Code 返回错误码
+func (e *ParseError) Code() ErrorCode {
+	return CodeOf(e)
+}
+
+// ResourceLimit 资源限制的种类
+type ResourceLimit string
+
+// 资源限制的种类，与 CalcConfig 中的限制一一对应
+const (
+	LimitExponent   ResourceLimit = "exponent"   // 幂运算指数的绝对值，对应 MaxExponent
+	LimitDigits     ResourceLimit = "digits"     // 中间结果的位数，对应 MaxDigits
+	LimitIterations ResourceLimit = "iterations" // 迭代算法的迭代次数，对应 MaxIterations
+	LimitOperations ResourceLimit = "operations" // 一次计算的运算次数，对应 MaxOperations
+)
+
+// ResourceLimitError 计算超过了配置的资源限制，通常作为 ParseError 的原因返回
+// errors.Is(err, ErrResourceLimit) 和 errors.As(err, &*ResourceLimitError) 都可以识别
+type ResourceLimitError struct {
+	Limit ResourceLimit // 超过的限制
+	Max   int64         // 配置的上限
+}
+
+// Error 实现error接口
+func (e *ResourceLimitError) Error() string {
+	return Translate(LocaleZh, e.key(), e.Max)
+}
+
+// Unwrap 返回 ErrResourceLimit，支持 errors.Is 判断
+func (e *ResourceLimitError) Unwrap() error {
+	return ErrResourceLimit
+}
+
+// At 返回记录了出错位置和片段的解析错误，原因为 e
+func (e *ResourceLimitError) At(pos int, token string) *ParseError {
+	return NewParseError(pos, e, token, e.key(), e.Max)
+}
+
+// key 返回限制对应的消息键
+func (e *ResourceLimitError) key() string {
+	return string(CodeResourceLimit) + "." + string(e.Limit)
+}
+
+// CycleError 公式之间存在循环引用，Path 为循环经过的公式，首尾相同，如 [a b a]
+// errors.Is(err, ErrCircularReference) 和 errors.As(err, &*CycleError) 都可以识别
+type CycleError struct {
+	Path []string
+
+	locale string // 输出消息使用的语言，空表示中文
+}
+
+// Error 实现error接口
+func (e *CycleError) Error() string {
+	return Translate(e.locale, MsgCircularReference, strings.Join(e.Path, " -> "))
+}
+
+// Localize 返回按指定语言输出消息的错误副本
+func (e *CycleError) Localize(locale string) error {
+	c := *e
+	c.locale = locale
+	return &c
+}
+
+// Unwrap 返回 ErrCircularReference，支持 errors.Is 判断
+func (e *CycleError) Unwrap() error {
+	return ErrCircularReference
 }
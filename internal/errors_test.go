@@ -0,0 +1,101 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestCodeOf(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want ErrorCode
+	}{
+		{name: "空错误", err: nil, want: ""},
+		{name: "预定义错误", err: ErrDivisionByZero, want: CodeDivisionByZero},
+		{
+			name: "解析错误",
+			err:  &ParseError{Pos: 3, Message: "未定义的变量: x", Cause: ErrUndefinedVariable, Token: "x"},
+			want: CodeUndefinedVariable,
+		},
+		{
+			name: "被包装的解析错误",
+			err:  fmt.Errorf("计算失败: %w", &ParseError{Message: "执行超时", Cause: ErrExecutionTimeout}),
+			want: CodeExecutionTimeout,
+		},
+		{
+			name: "原因不是预定义错误的解析错误",
+			err:  &ParseError{Message: "无效的数字: 1.2.3", Cause: errors.New("can't convert 1.2.3 to decimal")},
+			want: CodeInvalidExpression,
+		},
+		{
+			name: "结果为空",
+			err:  NewParseError(0, ErrNullResult, "x", MsgNullVariable, "x"),
+			want: CodeNullResult,
+		},
+		{name: "循环引用", err: &CycleError{Path: []string{"a", "a"}}, want: CodeCircularReference},
+		{name: "未知错误", err: context.Canceled, want: CodeUnknown},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := CodeOf(tt.err); got != tt.want {
+				t.Errorf("CodeOf() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseError_Unwrap(t *testing.T) {
+	err := error(&ParseError{Pos: 2, Message: "除数不能为零", Cause: ErrDivisionByZero, Token: "/"})
+
+	if !errors.Is(err, ErrDivisionByZero) {
+		t.Errorf("errors.Is(err, ErrDivisionByZero) = false")
+	}
+	if errors.Is(err, ErrInvalidArgument) {
+		t.Errorf("errors.Is(err, ErrInvalidArgument) = true")
+	}
+	if got := err.(*ParseError).Code(); got != CodeDivisionByZero {
+		t.Errorf("Code() = %q, want %q", got, CodeDivisionByZero)
+	}
+	if got, want := err.Error(), "位置 2: 除数不能为零: 除以零错误"; got != want {
+		t.Errorf("Error() = %q, want %q", got, want)
+	}
+}
+
+func TestParseError_Suggestions(t *testing.T) {
+	err := NewParseError(0, ErrUnsupportedOperator, "sqr", MsgUnsupportedFunction, "sqr").
+		WithSuggestions([]string{"sqrt"})
+
+	// 建议不影响错误消息
+	if got, want := err.Error(), "位置 0: 不支持的函数: sqr: 不支持的运算符"; got != want {
+		t.Errorf("Error() = %q, want %q", got, want)
+	}
+
+	located := Locate(err, "sqr(4)").(*ParseError)
+	want := "第 1 行，第 1 列: 不支持的函数: sqr: 不支持的运算符\n 1 | sqr(4)\n   | ^~~\n你是不是想输入: sqrt"
+	if got := located.Diagnostic(); got != want {
+		t.Errorf("Diagnostic() = %q, want %q", got, want)
+	}
+
+	localized := located.Localize(LocaleEn).(*ParseError)
+	if got := localized.Diagnostic(); !strings.HasSuffix(got, "\ndid you mean: sqrt") {
+		t.Errorf("Diagnostic() = %q, want suggestion in English", got)
+	}
+}
+
+func TestCycleError(t *testing.T) {
+	err := &CycleError{Path: []string{"a", "b", "a"}}
+	if got, want := err.Error(), "公式存在循环引用: a -> b -> a"; got != want {
+		t.Errorf("CycleError.Error() = %q, want %q", got, want)
+	}
+	if got, want := Localize(err, LocaleEn).Error(), "circular reference between formulas: a -> b -> a"; got != want {
+		t.Errorf("Localize(CycleError).Error() = %q, want %q", got, want)
+	}
+	if !errors.Is(Localize(err, LocaleEn), ErrCircularReference) {
+		t.Errorf("errors.Is(CycleError, ErrCircularReference) = false")
+	}
+}
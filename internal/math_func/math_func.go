@@ -4,12 +4,23 @@ import (
 	"math"
 
 	"github.com/shopspring/decimal"
+
+	"github.com/ZHOUXING1997/math_calculation/internal"
 )
 
+// Guard 在乘方和迭代计算的每一步检查资源限制和超时，参数为当前的中间结果，返回错误时终止计算
+type Guard func(value decimal.Decimal) error
+
 // FastPow 使用位运算优化幂运算
 func FastPow(base decimal.Decimal, exponent int64) decimal.Decimal {
+	result, _ := GuardedPow(base, exponent, nil)
+	return result
+}
+
+// GuardedPow 使用位运算计算整数次幂，每次乘法后调用 guard 检查中间结果，guard 为 nil 时不检查
+func GuardedPow(base decimal.Decimal, exponent int64, guard Guard) (decimal.Decimal, error) {
 	if exponent == 0 {
-		return decimal.NewFromInt(1)
+		return decimal.NewFromInt(1), nil
 	}
 
 	// 处理负指数
@@ -22,44 +33,31 @@ func FastPow(base decimal.Decimal, exponent int64) decimal.Decimal {
 	for exponent > 0 {
 		if exponent&1 == 1 {
 			result = result.Mul(base)
+			if guard != nil {
+				if err := guard(result); err != nil {
+					return decimal.Zero, err
+				}
+			}
 		}
-		base = base.Mul(base)
 		exponent >>= 1
+		// 最后一轮不再需要平方，避免无用的大数乘法
+		if exponent > 0 {
+			base = base.Mul(base)
+			if guard != nil {
+				if err := guard(base); err != nil {
+					return decimal.Zero, err
+				}
+			}
+		}
 	}
 
-	return result
+	return result, nil
 }
 
 // fastSqrt 使用牛顿迭代法优化平方根计算
 func fastSqrt(value decimal.Decimal) decimal.Decimal {
-	// 特殊情况处理
-	if value.IsZero() {
-		return value
-	}
-	if value.LessThan(decimal.Zero) {
-		return decimal.Zero
-	}
-
-	// 使用牛顿迭代法计算平方根
-	// x_{n+1} = (x_n + value/x_n) / 2
-
-	// 初始猜测值
-	x := decimal.NewFromFloat(math.Sqrt(value.InexactFloat64()))
-
-	// 迭代精度
-	precision := decimal.NewFromFloat(1e-10)
-
-	for {
-		// 计算下一个近似值
-		next := x.Add(value.Div(x)).Div(decimal.NewFromInt(2))
-
-		// 检查是否收敛
-		if next.Sub(x).Abs().LessThan(precision) {
-			return next
-		}
-
-		x = next
-	}
+	result, _ := GuardedSqrt(value, 100, nil)
+	return result
 }
 
 // OptimizedDecimalSqrt 优化的平方根计算
@@ -78,49 +76,79 @@ func OptimizedDecimalSqrt(value decimal.Decimal) decimal.Decimal {
 	return DecimalSqrt(value)
 }
 
-// DecimalSqrt 使用牛顿-拉夫森法计算小数的平方根
+// DecimalSqrt 使用牛顿-拉夫森法计算小数的平方根，达到最大迭代次数时返回当前最佳近似值
 func DecimalSqrt(value decimal.Decimal) decimal.Decimal {
+	result, _ := GuardedSqrt(value, 100, nil)
+	return result
+}
+
+// GuardedSqrt 使用牛顿-拉夫森法计算平方根，每次迭代后调用 guard 检查中间结果，guard 为 nil 时不检查
+// 超过 maxIterations 次迭代仍未收敛时返回当前最佳近似值和 LimitIterations 错误
+func GuardedSqrt(value decimal.Decimal, maxIterations int, guard Guard) (decimal.Decimal, error) {
 	// 特殊情况处理
 	if value.IsZero() {
-		return value
+		return value, nil
 	}
 	if value.LessThan(decimal.Zero) {
-		return decimal.Zero
+		return decimal.Zero, nil
 	}
 
 	// 使用牛顿迭代法计算平方根
 	// x_{n+1} = (x_n + value/x_n) / 2
-
-	// 初始猜测值 - 使用浮点数平方根作为初始值可以加快收敛
-	x := decimal.NewFromFloat(math.Sqrt(value.InexactFloat64()))
-
-	// 如果初始值为零（可能是因为值太大导致InexactFloat64溢出），使用值的一半作为初始猜测
-	if x.IsZero() {
-		x = value.Div(decimal.NewFromInt(2))
-	}
+	x := initialSqrtGuess(value)
 
 	// 迭代精度
 	precision := decimal.NewFromFloat(1e-10)
+	two := decimal.NewFromInt(2)
 
-	// 最大迭代次数，防止无限循环
-	maxIterations := 100
-	iterations := 0
-
-	for iterations < maxIterations {
+	for iterations := 0; iterations < maxIterations; iterations++ {
 		// 计算下一个近似值
-		next := x.Add(value.Div(x)).Div(decimal.NewFromInt(2))
+		next := x.Add(value.Div(x)).Div(two)
+		if guard != nil {
+			if err := guard(next); err != nil {
+				return decimal.Zero, err
+			}
+		}
 
 		// 检查是否收敛
 		if next.Sub(x).Abs().LessThan(precision) {
-			return next
+			return next, nil
 		}
 
 		x = next
-		iterations++
 	}
 
 	// 达到最大迭代次数，返回当前最佳近似值
-	return x
+	return x, &internal.ResourceLimitError{Limit: internal.LimitIterations, Max: int64(maxIterations)}
+}
+
+// initialSqrtGuess 返回牛顿迭代的初始猜测值
+// 使用浮点数平方根可以加快收敛；数值超出浮点数范围时按位数估算
+func initialSqrtGuess(value decimal.Decimal) decimal.Decimal {
+	f := math.Sqrt(value.InexactFloat64())
+	if f > 0 && !math.IsInf(f, 0) {
+		return decimal.NewFromFloat(f)
+	}
+	// 10^(位数/2) 与平方根在同一数量级
+	exp := (int32(value.NumDigits()) + value.Exponent()) / 2
+	return decimal.New(1, exp)
+}
+
+// Digits 返回数字以普通小数形式书写时的位数（整数位数与小数位数之和，不含符号和前导零）
+// 用于限制中间结果的大小：1e100 有 101 位，1e-100 有 100 位
+func Digits(d decimal.Decimal) int {
+	if d.IsZero() {
+		return 1
+	}
+	n := d.NumDigits()
+	exp := int(d.Exponent())
+	if exp >= 0 {
+		return n + exp
+	}
+	if -exp > n {
+		return -exp
+	}
+	return n
 }
 
 // RoundToPlaces 四舍五入到指定小数位
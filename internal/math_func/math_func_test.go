@@ -1,9 +1,12 @@
 package math_func
 
 import (
+	"errors"
 	"testing"
 
 	"github.com/shopspring/decimal"
+
+	"github.com/ZHOUXING1997/math_calculation/internal"
 )
 
 func TestFastPow(t *testing.T) {
@@ -451,3 +454,147 @@ func TestTruncateToPlaces(t *testing.T) {
 		})
 	}
 }
+
+func TestGuardedPow(t *testing.T) {
+	errLimit := errors.New("limit")
+	tests := []struct {
+		name     string
+		base     decimal.Decimal
+		exponent int64
+		limit    int
+		want     decimal.Decimal
+		wantErr  bool
+	}{
+		{
+			name:     "不限制",
+			base:     decimal.NewFromInt(2),
+			exponent: 10,
+			want:     decimal.NewFromInt(1024),
+		},
+		{
+			name:     "负指数",
+			base:     decimal.NewFromInt(2),
+			exponent: -2,
+			want:     decimal.NewFromFloat(0.25),
+		},
+		{
+			name:     "未超过位数限制",
+			base:     decimal.NewFromInt(10),
+			exponent: 5,
+			limit:    6,
+			want:     decimal.NewFromInt(100000),
+		},
+		{
+			name:     "超过位数限制",
+			base:     decimal.NewFromInt(99999),
+			exponent: 99999999,
+			limit:    100,
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var guard Guard
+			if tt.limit > 0 {
+				guard = func(value decimal.Decimal) error {
+					if Digits(value) > tt.limit {
+						return errLimit
+					}
+					return nil
+				}
+			}
+			got, err := GuardedPow(tt.base, tt.exponent, guard)
+			if tt.wantErr {
+				if !errors.Is(err, errLimit) {
+					t.Errorf("GuardedPow() error = %v, want %v", err, errLimit)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("GuardedPow() error = %v", err)
+			}
+			if !got.Equal(tt.want) {
+				t.Errorf("GuardedPow() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGuardedSqrt(t *testing.T) {
+	tests := []struct {
+		name          string
+		value         decimal.Decimal
+		maxIterations int
+		want          decimal.Decimal
+		wantErr       bool
+	}{
+		{
+			name:          "完全平方数",
+			value:         decimal.NewFromInt(144),
+			maxIterations: 100,
+			want:          decimal.NewFromInt(12),
+		},
+		{
+			name:          "零",
+			value:         decimal.Zero,
+			maxIterations: 100,
+			want:          decimal.Zero,
+		},
+		{
+			name:          "超出浮点数范围",
+			value:         decimal.New(4, 400),
+			maxIterations: 100,
+			want:          decimal.New(2, 200),
+		},
+		{
+			name:          "超过迭代次数",
+			value:         decimal.New(2, 1000),
+			maxIterations: 1,
+			wantErr:       true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := GuardedSqrt(tt.value, tt.maxIterations, nil)
+			if tt.wantErr {
+				var limitErr *internal.ResourceLimitError
+				if !errors.As(err, &limitErr) || limitErr.Limit != internal.LimitIterations {
+					t.Errorf("GuardedSqrt() error = %v, want iterations limit", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("GuardedSqrt() error = %v", err)
+			}
+			if !got.Round(10).Equal(tt.want) {
+				t.Errorf("GuardedSqrt() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDigits(t *testing.T) {
+	tests := []struct {
+		name  string
+		value decimal.Decimal
+		want  int
+	}{
+		{name: "零", value: decimal.Zero, want: 1},
+		{name: "整数", value: decimal.NewFromInt(12345), want: 5},
+		{name: "负数", value: decimal.NewFromInt(-12345), want: 5},
+		{name: "小数", value: decimal.RequireFromString("12.345"), want: 5},
+		{name: "纯小数", value: decimal.RequireFromString("0.001"), want: 3},
+		{name: "大正指数", value: decimal.New(1, 100), want: 101},
+		{name: "大负指数", value: decimal.New(1, -100), want: 100},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Digits(tt.value); got != tt.want {
+				t.Errorf("Digits(%v) = %d, want %d", tt.value, got, tt.want)
+			}
+		})
+	}
+}
@@ -2,11 +2,11 @@ package math_node
 
 import (
 	"context"
-	"fmt"
 
 	"github.com/shopspring/decimal"
 
 	"github.com/ZHOUXING1997/math_calculation/internal"
+	"github.com/ZHOUXING1997/math_calculation/internal/math_func"
 	"github.com/ZHOUXING1997/math_calculation/internal/math_utils"
 	"github.com/ZHOUXING1997/math_calculation/math_config"
 )
@@ -29,6 +29,10 @@ func (n *BinaryOpNode) Eval(ctx context.Context, vars map[string]decimal.Decimal
 	if err := math_utils.CheckContext(ctx, config.MaxRecursionDepth); err != nil {
 		return decimal.Zero, err
 	}
+	// 消耗一次运算
+	if err := math_utils.Spend(ctx, 1); err != nil {
+		return decimal.Zero, math_utils.LimitError(err, n.Pos, n.Operator)
+	}
 
 	// 计算左操作数
 	leftVal, err := n.Left.Eval(ctx, vars, config)
@@ -42,8 +46,14 @@ func (n *BinaryOpNode) Eval(ctx context.Context, vars map[string]decimal.Decimal
 		return decimal.Zero, err
 	}
 
+	return n.apply(ctx, leftVal, rightVal, config)
+}
+
+// apply 对已经计算出的操作数执行运算，检查结果的位数并应用精度控制
+func (n *BinaryOpNode) apply(ctx context.Context, leftVal, rightVal decimal.Decimal, config *math_config.CalcConfig) (decimal.Decimal, error) {
 	// 根据运算符执行相应的运算
 	var result decimal.Decimal
+	var err error
 	switch n.Operator {
 	case "+":
 		result = leftVal.Add(rightVal)
@@ -53,47 +63,31 @@ func (n *BinaryOpNode) Eval(ctx context.Context, vars map[string]decimal.Decimal
 		result = leftVal.Mul(rightVal)
 	case "/":
 		if rightVal.IsZero() {
-			return decimal.Zero, &internal.ParseError{
-				Pos:     n.Pos,
-				Message: "除数不能为零",
-				Cause:   internal.ErrDivisionByZero,
-			}
+			return decimal.Zero, internal.NewParseError(n.Pos, internal.ErrDivisionByZero, n.Operator, internal.MsgDivisorZero)
 		}
 		result = leftVal.Div(rightVal)
 	case "^":
-		// 处理负指数
-		if rightVal.LessThan(decimal.Zero) {
-			// 对于负指数，计算 1/(base^|exponent|)
-			invertedBase := decimal.New(1, 0).Div(leftVal)
-			exponent := rightVal.Abs().IntPart()
-			result = decimal.New(1, 0)
-			base := invertedBase
-			for exponent > 0 {
-				if exponent&1 == 1 {
-					result = result.Mul(base)
-				}
-				base = base.Mul(base)
-				exponent >>= 1
-			}
-		} else {
-			// 处理正指数
-			exponent := rightVal.IntPart()
-			result = decimal.New(1, 0)
-			base := leftVal
-			for exponent > 0 {
-				if exponent&1 == 1 {
-					result = result.Mul(base)
-				}
-				base = base.Mul(base)
-				exponent >>= 1
-			}
+		// 检查指数大小，指数的小数部分被截断
+		if err := math_utils.CheckExponent(rightVal, config); err != nil {
+			return decimal.Zero, math_utils.LimitError(err, n.Pos, n.Operator)
 		}
-	default:
-		return decimal.Zero, &internal.ParseError{
-			Pos:     n.Pos,
-			Message: fmt.Sprintf("不支持的运算符: %s", n.Operator),
-			Cause:   internal.ErrUnsupportedOperator,
+		exponent := rightVal.IntPart()
+		// 零的负数次幂相当于除以零
+		if leftVal.IsZero() && exponent < 0 {
+			return decimal.Zero, internal.NewParseError(n.Pos, internal.ErrDivisionByZero, n.Operator, internal.MsgDivisorZero)
+		}
+		// 使用位运算计算幂，每次乘法后检查超时、运算次数和中间结果的位数
+		result, err = math_func.GuardedPow(leftVal, exponent, math_utils.Guard(ctx, config))
+		if err != nil {
+			return decimal.Zero, math_utils.LimitError(err, n.Pos, n.Operator)
 		}
+	default:
+		return decimal.Zero, internal.NewParseError(n.Pos, internal.ErrUnsupportedOperator, n.Operator, internal.MsgUnsupportedOperator, n.Operator)
+	}
+
+	// 检查结果的位数
+	if err := math_utils.CheckDigits(result, config); err != nil {
+		return decimal.Zero, math_utils.LimitError(err, n.Pos, n.Operator)
 	}
 
 	// 根据精度控制策略决定是否应用精度控制
@@ -102,3 +96,8 @@ func (n *BinaryOpNode) Eval(ctx context.Context, vars map[string]decimal.Decimal
 	}
 	return result, nil
 }
+
+// String 返回 BinaryOpNode 的表达式字符串
+func (n *BinaryOpNode) String() string {
+	return Format(n)
+}
@@ -2,11 +2,13 @@ package math_node
 
 import (
 	"context"
+	"errors"
 	"testing"
 
 	"github.com/shopspring/decimal"
 
 	"github.com/ZHOUXING1997/math_calculation/internal"
+	"github.com/ZHOUXING1997/math_calculation/internal/math_utils"
 	"github.com/ZHOUXING1997/math_calculation/math_config"
 )
 
@@ -219,3 +221,76 @@ func TestBinaryOpNode_EvalWithOperandErrors(t *testing.T) {
 		}
 	})
 }
+
+// 测试超过资源限制的情况
+func TestBinaryOpNode_EvalWithResourceLimits(t *testing.T) {
+	num := func(s string) Node { return &NumberNode{Value: decimal.RequireFromString(s)} }
+
+	tests := []struct {
+		name      string
+		node      Node
+		config    func(config *math_config.CalcConfig)
+		wantLimit internal.ResourceLimit
+		wantErr   error
+	}{
+		{
+			name:      "指数超过限制",
+			node:      &BinaryOpNode{Left: num("99999"), Operator: "^", Right: num("99999999"), Pos: 5},
+			wantLimit: internal.LimitExponent,
+		},
+		{
+			name:      "负指数超过限制",
+			node:      &BinaryOpNode{Left: num("2"), Operator: "^", Right: num("-99999999"), Pos: 1},
+			wantLimit: internal.LimitExponent,
+		},
+		{
+			name:      "中间结果位数超过限制",
+			node:      &BinaryOpNode{Left: num("99999"), Operator: "^", Right: num("9999"), Pos: 5},
+			config:    func(config *math_config.CalcConfig) { config.MaxDigits = 1000 },
+			wantLimit: internal.LimitDigits,
+		},
+		{
+			name:      "乘法结果位数超过限制",
+			node:      &BinaryOpNode{Left: num("123456"), Operator: "*", Right: num("123456"), Pos: 6},
+			config:    func(config *math_config.CalcConfig) { config.MaxDigits = 10 },
+			wantLimit: internal.LimitDigits,
+		},
+		{
+			name:      "运算次数超过限制",
+			node:      &BinaryOpNode{Left: &BinaryOpNode{Left: num("1"), Operator: "+", Right: num("2")}, Operator: "+", Right: num("3")},
+			config:    func(config *math_config.CalcConfig) { config.MaxOperations = 1 },
+			wantLimit: internal.LimitOperations,
+		},
+		{
+			name:    "零的负数次幂",
+			node:    &BinaryOpNode{Left: num("0"), Operator: "^", Right: num("-1"), Pos: 1},
+			wantErr: internal.ErrDivisionByZero,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := math_config.NewDefaultCalcConfig()
+			config.ApplyPrecisionEachStep = false
+			if tt.config != nil {
+				tt.config(config)
+			}
+			ctx := math_utils.WithBudget(context.Background(), config)
+			_, err := tt.node.Eval(ctx, nil, config)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("BinaryOpNode.Eval() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			var limitErr *internal.ResourceLimitError
+			if !errors.As(err, &limitErr) || limitErr.Limit != tt.wantLimit {
+				t.Fatalf("BinaryOpNode.Eval() error = %v, want %s limit", err, tt.wantLimit)
+			}
+			var parseErr *internal.ParseError
+			if !errors.As(err, &parseErr) || parseErr.Token == "" {
+				t.Errorf("BinaryOpNode.Eval() error = %v, want ParseError with token", err)
+			}
+		})
+	}
+}
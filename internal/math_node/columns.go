@@ -0,0 +1,206 @@
+package math_node
+
+import (
+	"context"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/ZHOUXING1997/math_calculation/internal/math_utils"
+	"github.com/ZHOUXING1997/math_calculation/math_config"
+)
+
+// column 按列计算的中间结果，constant 为 true 时所有行的值都是 values[0]
+type column struct {
+	values   []decimal.Decimal
+	constant bool
+}
+
+// at 返回第 i 行的值
+func (c column) at(i int) decimal.Decimal {
+	if c.constant {
+		return c.values[0]
+	}
+	return c.values[i]
+}
+
+// columnEval 一次按列计算的状态
+type columnEval struct {
+	ctx     context.Context              // 整批计算共用的上下文
+	rows    []context.Context            // 每一行的上下文，包含这一行的运算次数预算
+	columns map[string][]decimal.Decimal // 每个变量一列
+	config  *math_config.CalcConfig
+	errs    []error                    // 每一行的错误，出错的行不再计算
+	vars    map[string]decimal.Decimal // 逐行计算时复用的变量
+}
+
+// EvalColumns 按列计算表达式，columns 中每个变量的值为一列，每列都有 rows 行
+// 每个节点对所有行计算一次，不依赖变量的运算只计算一次，结果和错误与逐行调用 Eval 相同。
+// ctx 应由 math_utils.BatchContext 派生，每一行使用 math_utils.RowContext 派生的上下文，
+// 运算次数等资源限制对每一行分别生效。coalesce、isdefined 以及不在 columns 中的变量逐行计算
+func EvalColumns(ctx context.Context, node Node, columns map[string][]decimal.Decimal, rows int, config *math_config.CalcConfig) ([]decimal.Decimal, []error) {
+	if config == nil {
+		config = math_config.NewDefaultCalcConfig()
+	}
+	e := &columnEval{
+		ctx:     ctx,
+		rows:    make([]context.Context, rows),
+		columns: columns,
+		config:  config,
+		errs:    make([]error, rows),
+	}
+	for i := range e.rows {
+		e.rows[i] = math_utils.RowContext(ctx, config)
+	}
+
+	col := e.eval(node)
+	results := make([]decimal.Decimal, rows)
+	for i := range results {
+		if e.errs[i] == nil {
+			results[i] = col.at(i)
+		}
+	}
+	return results, e.errs
+}
+
+// eval 对所有未出错的行计算节点
+func (e *columnEval) eval(node Node) column {
+	if e.ctx.Err() != nil {
+		e.failAll(math_utils.ContextError(e.ctx))
+		return e.constant(decimal.Zero)
+	}
+
+	switch n := node.(type) {
+	case *NumberNode:
+		val, err := n.Eval(e.ctx, nil, e.config)
+		if err != nil {
+			e.failAll(err)
+		}
+		return e.constant(val)
+	case *VariableNode:
+		values, ok := e.columns[n.VarName]
+		if !ok {
+			return e.rowWise(n)
+		}
+		return e.each(func(i int) (decimal.Decimal, error) {
+			return n.value(values[i], e.config)
+		})
+	case *UnaryOpNode:
+		e.spend(n.Pos, n.Operator)
+		operand := e.eval(n.Operand)
+		if operand.constant {
+			return e.once(func() (decimal.Decimal, error) {
+				return n.apply(operand.values[0], e.config)
+			})
+		}
+		return e.each(func(i int) (decimal.Decimal, error) {
+			return n.apply(operand.values[i], e.config)
+		})
+	case *BinaryOpNode:
+		e.spend(n.Pos, n.Operator)
+		left := e.eval(n.Left)
+		right := e.eval(n.Right)
+		// 乘方的每次乘法都消耗这一行的运算次数，需要逐行计算
+		if left.constant && right.constant && n.Operator != "^" {
+			return e.once(func() (decimal.Decimal, error) {
+				return n.apply(e.ctx, left.values[0], right.values[0], e.config)
+			})
+		}
+		return e.each(func(i int) (decimal.Decimal, error) {
+			return n.apply(e.rows[i], left.at(i), right.at(i), e.config)
+		})
+	case *FunctionNode:
+		// 判断变量是否有值的函数按需计算参数
+		if n.FuncName == "coalesce" || n.FuncName == "isdefined" {
+			return e.rowWise(n)
+		}
+		e.spend(n.Pos, n.FuncName)
+		args := make([]column, len(n.Args))
+		constant := n.FuncName != "sqrt" && n.FuncName != "pow"
+		for j, arg := range n.Args {
+			args[j] = e.eval(arg)
+			constant = constant && args[j].constant
+		}
+		values := make([]decimal.Decimal, len(args))
+		call := func(ctx context.Context, i int) (decimal.Decimal, error) {
+			for j, arg := range args {
+				values[j] = arg.at(i)
+			}
+			return n.call(ctx, values, e.config)
+		}
+		// 平方根和乘方的迭代消耗这一行的运算次数，需要逐行计算
+		if constant {
+			return e.once(func() (decimal.Decimal, error) {
+				return call(e.ctx, 0)
+			})
+		}
+		return e.each(func(i int) (decimal.Decimal, error) {
+			return call(e.rows[i], i)
+		})
+	}
+	return e.rowWise(node)
+}
+
+// spend 每个未出错的行消耗一次运算，与 Eval 在计算子节点之前消耗运算的顺序一致
+func (e *columnEval) spend(pos int, token string) {
+	for i, ctx := range e.rows {
+		if e.errs[i] != nil {
+			continue
+		}
+		if err := math_utils.Spend(ctx, 1); err != nil {
+			e.errs[i] = math_utils.LimitError(err, pos, token)
+		}
+	}
+}
+
+// each 对每个未出错的行计算一次
+func (e *columnEval) each(f func(i int) (decimal.Decimal, error)) column {
+	values := make([]decimal.Decimal, len(e.rows))
+	for i := range values {
+		if e.errs[i] != nil {
+			continue
+		}
+		val, err := f(i)
+		if err != nil {
+			e.errs[i] = err
+			continue
+		}
+		values[i] = val
+	}
+	return column{values: values}
+}
+
+// once 只计算一次，结果用于所有行，出错时所有未出错的行都返回这个错误
+func (e *columnEval) once(f func() (decimal.Decimal, error)) column {
+	val, err := f()
+	if err != nil {
+		e.failAll(err)
+	}
+	return e.constant(val)
+}
+
+// rowWise 逐行调用节点的 Eval，用于需要完整变量或按需计算参数的节点
+func (e *columnEval) rowWise(node Node) column {
+	if e.vars == nil {
+		e.vars = make(map[string]decimal.Decimal, len(e.columns))
+	}
+	return e.each(func(i int) (decimal.Decimal, error) {
+		for name, values := range e.columns {
+			e.vars[name] = values[i]
+		}
+		return node.Eval(e.rows[i], e.vars, e.config)
+	})
+}
+
+// constant 返回所有行都是 val 的列
+func (e *columnEval) constant(val decimal.Decimal) column {
+	return column{values: []decimal.Decimal{val}, constant: true}
+}
+
+// failAll 将所有未出错的行标记为 err
+func (e *columnEval) failAll(err error) {
+	for i := range e.errs {
+		if e.errs[i] == nil {
+			e.errs[i] = err
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package math_node
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/ZHOUXING1997/math_calculation/internal"
+	"github.com/ZHOUXING1997/math_calculation/internal/math_utils"
+	"github.com/ZHOUXING1997/math_calculation/math_config"
+)
+
+// 按列计算的结果和错误与逐行调用 Eval 相同
+func TestEvalColumns(t *testing.T) {
+	x := &VariableNode{VarName: "x", Pos: 0}
+	y := &VariableNode{VarName: "y", Pos: 4}
+	z := &VariableNode{VarName: "z", Pos: 8}
+	num := func(v string) *NumberNode { return &NumberNode{Value: decimal.RequireFromString(v)} }
+	columns := map[string][]decimal.Decimal{
+		"x": {decimal.NewFromInt(1), decimal.NewFromInt(-4), decimal.RequireFromString("2.5"), decimal.Zero},
+		"y": {decimal.NewFromInt(2), decimal.Zero, decimal.NewFromInt(3), decimal.NewFromInt(7)},
+	}
+	limited := math_config.NewDefaultCalcConfig()
+	limited.MaxOperations = 6
+
+	tests := []struct {
+		name   string
+		node   Node
+		config *math_config.CalcConfig
+	}{
+		{name: "变量运算", node: &BinaryOpNode{Left: x, Operator: "*", Right: y}},
+		{name: "常量子表达式", node: &BinaryOpNode{Left: x, Operator: "+", Right: &BinaryOpNode{Left: num("1"), Operator: "/", Right: num("3")}}},
+		{name: "部分行除以零", node: &BinaryOpNode{Left: x, Operator: "/", Right: y, Pos: 2}},
+		{name: "常量除以零", node: &BinaryOpNode{Left: x, Operator: "+", Right: &BinaryOpNode{Left: num("1"), Operator: "/", Right: num("0")}}},
+		{name: "一元运算符", node: &UnaryOpNode{Operator: "-", Operand: x}},
+		{name: "乘方", node: &BinaryOpNode{Left: x, Operator: "^", Right: y}},
+		{name: "函数", node: &FunctionNode{FuncName: "max", Args: []Node{x, y, num("2")}}},
+		{name: "部分行参数无效", node: &FunctionNode{FuncName: "sqrt", Args: []Node{x}}},
+		{name: "常量参数的函数", node: &FunctionNode{FuncName: "round", Args: []Node{num("2.567"), num("1")}}},
+		{name: "未定义的变量", node: &BinaryOpNode{Left: x, Operator: "+", Right: z}},
+		{name: "coalesce 逐行计算", node: &FunctionNode{FuncName: "coalesce", Args: []Node{z, y}}},
+		{name: "运算次数限制", node: &BinaryOpNode{Left: x, Operator: "^", Right: y}, config: limited},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := tt.config
+			if config == nil {
+				config = math_config.NewDefaultCalcConfig()
+			}
+			ctx, cancel := math_utils.BatchContext(context.Background(), config)
+			defer cancel()
+
+			results, errs := EvalColumns(ctx, tt.node, columns, 4, config)
+			for i := 0; i < 4; i++ {
+				vars := map[string]decimal.Decimal{"x": columns["x"][i], "y": columns["y"][i]}
+				want, wantErr := tt.node.Eval(math_utils.RowContext(ctx, config), vars, config)
+				if (errs[i] == nil) != (wantErr == nil) || (wantErr != nil && errs[i].Error() != wantErr.Error()) {
+					t.Errorf("row %d error = %v, want %v", i, errs[i], wantErr)
+					continue
+				}
+				if !results[i].Equal(want) {
+					t.Errorf("row %d = %v, want %v", i, results[i], want)
+				}
+			}
+		})
+	}
+}
+
+func TestEvalColumns_Canceled(t *testing.T) {
+	config := math_config.NewDefaultCalcConfig()
+	parent, cancelParent := context.WithCancel(context.Background())
+	ctx, cancel := math_utils.BatchContext(parent, config)
+	defer cancel()
+	cancelParent()
+
+	columns := map[string][]decimal.Decimal{"x": {decimal.NewFromInt(1), decimal.NewFromInt(2)}}
+	_, errs := EvalColumns(ctx, &VariableNode{VarName: "x"}, columns, 2, config)
+	for i, err := range errs {
+		if !errors.Is(err, internal.ErrCanceled) {
+			t.Errorf("row %d error = %v, want %v", i, err, internal.ErrCanceled)
+		}
+	}
+}
@@ -0,0 +1,254 @@
+package math_node
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"github.com/ZHOUXING1997/math_calculation/internal/math_utils"
+)
+
+// 运算符优先级，数值越大绑定越紧
+const (
+	precAdditive       = 1 // + -
+	precMultiplicative = 2 // * /
+	precPower          = 3 // ^
+	precUnary          = 4 // 一元运算符
+	precAtom           = 5 // 数字、变量、函数调用
+)
+
+// FormatOptions 格式化选项，零值表示单行、运算符两侧带空格的输出
+type FormatOptions struct {
+	Compact   bool   // 紧凑模式，运算符两侧和逗号后不加空格
+	MultiLine bool   // 多行模式，超过 MaxWidth 的子表达式按结构换行缩进
+	Indent    string // 多行模式下每一级的缩进，为空时使用两个空格
+	MaxWidth  int    // 多行模式下单行的最大宽度（按字符计），小于等于 0 时使用 80
+}
+
+// DefaultFormatOptions 默认格式化选项
+var DefaultFormatOptions = FormatOptions{}
+
+// Format 将表达式树格式化为中缀表达式字符串，只在必要时添加括号
+// 输出可以被解析器重新解析为结构相同的表达式树
+func Format(node Node) string {
+	return FormatWithOptions(node, DefaultFormatOptions)
+}
+
+// FormatWithOptions 使用指定选项格式化表达式树
+func FormatWithOptions(node Node, options FormatOptions) string {
+	if options.Indent == "" {
+		options.Indent = "  "
+	}
+	if options.MaxWidth <= 0 {
+		options.MaxWidth = 80
+	}
+
+	f := &formatter{options: options}
+	if options.MultiLine {
+		return f.layout(node, 0)
+	}
+
+	var sb strings.Builder
+	f.writeNode(&sb, node)
+	return sb.String()
+}
+
+// formatter 表达式格式化器
+type formatter struct {
+	options FormatOptions
+}
+
+// flat 返回节点的单行表示
+func (f *formatter) flat(node Node) string {
+	var sb strings.Builder
+	f.writeNode(&sb, node)
+	return sb.String()
+}
+
+// writeNode 将节点的单行表示写入缓冲区
+func (f *formatter) writeNode(sb *strings.Builder, node Node) {
+	switch n := node.(type) {
+	case *NumberNode:
+		sb.WriteString(n.Value.String())
+	case *VariableNode:
+		sb.WriteString(math_utils.FormatIdentifier(n.VarName, n.Path))
+	case *UnaryOpNode:
+		sb.WriteString(n.Operator)
+		// 一元运算符的操作数只要不是原子或一元节点就需要括号
+		f.writeOperand(sb, n.Operand, precedence(n.Operand) < precUnary)
+	case *BinaryOpNode:
+		prec := binaryPrecedence(n.Operator)
+		// 所有二元运算符都是左结合的，右操作数优先级相同时也需要括号
+		f.writeOperand(sb, n.Left, precedence(n.Left) < prec)
+		sb.WriteString(f.operator(n.Operator))
+		f.writeOperand(sb, n.Right, precedence(n.Right) <= prec)
+	case *FunctionNode:
+		sb.WriteString(n.FuncName)
+		sb.WriteString("(")
+		for i, arg := range n.Args {
+			if i > 0 {
+				sb.WriteString(f.separator())
+			}
+			f.writeNode(sb, arg)
+		}
+		sb.WriteString(")")
+	case nil:
+		// 空节点不输出任何内容
+	default:
+		sb.WriteString("?")
+	}
+}
+
+// writeOperand 写入操作数，必要时添加括号
+func (f *formatter) writeOperand(sb *strings.Builder, node Node, paren bool) {
+	if paren {
+		sb.WriteString("(")
+		f.writeNode(sb, node)
+		sb.WriteString(")")
+		return
+	}
+	f.writeNode(sb, node)
+}
+
+// operator 返回带空格的二元运算符
+func (f *formatter) operator(operator string) string {
+	if f.options.Compact {
+		return operator
+	}
+	return " " + operator + " "
+}
+
+// separator 返回函数参数分隔符
+func (f *formatter) separator() string {
+	if f.options.Compact {
+		return ","
+	}
+	return ", "
+}
+
+// fits 判断单行文本在指定缩进级别下是否不超过最大宽度
+func (f *formatter) fits(text string, level int) bool {
+	return utf8.RuneCountInString(f.indent(level))+utf8.RuneCountInString(text) <= f.options.MaxWidth
+}
+
+// indent 返回指定级别的缩进
+func (f *formatter) indent(level int) string {
+	return strings.Repeat(f.options.Indent, level)
+}
+
+// layout 多行模式下格式化节点，能放进一行的子表达式保持单行
+// 返回值的第一行不带缩进，后续各行带有绝对缩进
+func (f *formatter) layout(node Node, level int) string {
+	flat := f.flat(node)
+	if f.fits(flat, level) {
+		return flat
+	}
+
+	switch n := node.(type) {
+	case *BinaryOpNode:
+		// 把同一优先级的左结合链展开，每个后续操作数另起一行
+		prec := binaryPrecedence(n.Operator)
+		operands, operators := flattenChain(n, prec)
+
+		var sb strings.Builder
+		sb.WriteString(f.layoutOperand(operands[0], level, precedence(operands[0]) < prec))
+		for i, operator := range operators {
+			operand := operands[i+1]
+			sb.WriteString("\n")
+			sb.WriteString(f.indent(level + 1))
+			sb.WriteString(operator)
+			if !f.options.Compact {
+				sb.WriteString(" ")
+			}
+			sb.WriteString(f.layoutOperand(operand, level+1, precedence(operand) <= prec))
+		}
+		return sb.String()
+	case *FunctionNode:
+		if len(n.Args) == 0 {
+			return flat
+		}
+		var sb strings.Builder
+		sb.WriteString(n.FuncName)
+		sb.WriteString("(\n")
+		for i, arg := range n.Args {
+			sb.WriteString(f.indent(level + 1))
+			sb.WriteString(f.layout(arg, level+1))
+			if i < len(n.Args)-1 {
+				sb.WriteString(",")
+			}
+			sb.WriteString("\n")
+		}
+		sb.WriteString(f.indent(level))
+		sb.WriteString(")")
+		return sb.String()
+	case *UnaryOpNode:
+		return n.Operator + f.layoutOperand(n.Operand, level, precedence(n.Operand) < precUnary)
+	}
+
+	return flat
+}
+
+// layoutOperand 多行模式下格式化操作数，放不进一行的括号内容单独缩进
+func (f *formatter) layoutOperand(node Node, level int, paren bool) string {
+	if !paren {
+		return f.layout(node, level)
+	}
+
+	flat := "(" + f.flat(node) + ")"
+	if f.fits(flat, level) {
+		return flat
+	}
+	return "(\n" + f.indent(level+1) + f.layout(node, level+1) + "\n" + f.indent(level) + ")"
+}
+
+// flattenChain 展开同一优先级的左结合二元运算链
+func flattenChain(node *BinaryOpNode, prec int) ([]Node, []string) {
+	var operands []Node
+	var operators []string
+
+	var current Node = node
+	for {
+		n, ok := current.(*BinaryOpNode)
+		if !ok || binaryPrecedence(n.Operator) != prec {
+			break
+		}
+		operands = append(operands, n.Right)
+		operators = append(operators, n.Operator)
+		current = n.Left
+	}
+	operands = append(operands, current)
+
+	// 链是从右向左收集的，反转为书写顺序
+	for i, j := 0, len(operands)-1; i < j; i, j = i+1, j-1 {
+		operands[i], operands[j] = operands[j], operands[i]
+	}
+	for i, j := 0, len(operators)-1; i < j; i, j = i+1, j-1 {
+		operators[i], operators[j] = operators[j], operators[i]
+	}
+	return operands, operators
+}
+
+// precedence 返回节点作为操作数时的优先级
+func precedence(node Node) int {
+	switch n := node.(type) {
+	case *BinaryOpNode:
+		return binaryPrecedence(n.Operator)
+	case *UnaryOpNode:
+		return precUnary
+	default:
+		return precAtom
+	}
+}
+
+// binaryPrecedence 返回二元运算符的优先级
+func binaryPrecedence(operator string) int {
+	switch operator {
+	case "+", "-":
+		return precAdditive
+	case "*", "/":
+		return precMultiplicative
+	case "^":
+		return precPower
+	default:
+		return precAdditive
+	}
+}
@@ -0,0 +1,171 @@
+package math_node
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestFormat(t *testing.T) {
+	x := &VariableNode{VarName: "x"}
+	y := &VariableNode{VarName: "y"}
+	two := &NumberNode{Value: decimal.NewFromInt(2)}
+
+	tests := []struct {
+		name string
+		node Node
+		want string
+	}{
+		{
+			name: "数字",
+			node: &NumberNode{Value: decimal.NewFromFloat(3.5)},
+			want: "3.5",
+		},
+		{
+			name: "优先级不需要括号",
+			node: &BinaryOpNode{Left: x, Operator: "+", Right: &BinaryOpNode{Left: y, Operator: "*", Right: two}},
+			want: "x + y * 2",
+		},
+		{
+			name: "优先级需要括号",
+			node: &BinaryOpNode{Left: &BinaryOpNode{Left: x, Operator: "+", Right: y}, Operator: "*", Right: two},
+			want: "(x + y) * 2",
+		},
+		{
+			name: "左结合",
+			node: &BinaryOpNode{Left: &BinaryOpNode{Left: x, Operator: "-", Right: y}, Operator: "-", Right: two},
+			want: "x - y - 2",
+		},
+		{
+			name: "右操作数同优先级需要括号",
+			node: &BinaryOpNode{Left: x, Operator: "-", Right: &BinaryOpNode{Left: y, Operator: "-", Right: two}},
+			want: "x - (y - 2)",
+		},
+		{
+			name: "一元运算符",
+			node: &UnaryOpNode{Operator: "-", Operand: &BinaryOpNode{Left: x, Operator: "^", Right: two}},
+			want: "-(x ^ 2)",
+		},
+		{
+			name: "函数调用",
+			node: &FunctionNode{FuncName: "max", Args: []Node{x, &UnaryOpNode{Operator: "-", Operand: y}}},
+			want: "max(x, -y)",
+		},
+		{
+			name: "变量路径和需要引用的名称",
+			node: &BinaryOpNode{
+				Left:     &VariableNode{VarName: "order.items[0].unit price", Path: []string{"order", "items", "0", "unit price"}},
+				Operator: "*",
+				Right:    &VariableNode{VarName: "数量"},
+			},
+			want: "order.items[0].`unit price` * 数量",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Format(tt.node); got != tt.want {
+				t.Errorf("Format() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormatWithOptions(t *testing.T) {
+	a := &VariableNode{VarName: "unit_price"}
+	b := &VariableNode{VarName: "quantity"}
+	c := &VariableNode{VarName: "discount_rate"}
+	sum := &BinaryOpNode{
+		Left:     &BinaryOpNode{Left: &BinaryOpNode{Left: a, Operator: "*", Right: b}, Operator: "-", Right: c},
+		Operator: "+",
+		Right:    &FunctionNode{FuncName: "max", Args: []Node{a, b}},
+	}
+
+	tests := []struct {
+		name    string
+		node    Node
+		options FormatOptions
+		want    string
+	}{
+		{
+			name:    "默认",
+			node:    sum,
+			options: DefaultFormatOptions,
+			want:    "unit_price * quantity - discount_rate + max(unit_price, quantity)",
+		},
+		{
+			name:    "紧凑",
+			node:    sum,
+			options: FormatOptions{Compact: true},
+			want:    "unit_price*quantity-discount_rate+max(unit_price,quantity)",
+		},
+		{
+			name:    "多行放得下时保持单行",
+			node:    sum,
+			options: FormatOptions{MultiLine: true},
+			want:    "unit_price * quantity - discount_rate + max(unit_price, quantity)",
+		},
+		{
+			name:    "多行拆分加减链",
+			node:    sum,
+			options: FormatOptions{MultiLine: true, MaxWidth: 40},
+			want: "unit_price * quantity\n" +
+				"  - discount_rate\n" +
+				"  + max(unit_price, quantity)",
+		},
+		{
+			name:    "多行拆分函数参数和括号",
+			node:    &BinaryOpNode{Left: sum, Operator: "*", Right: &FunctionNode{FuncName: "min", Args: []Node{sum, c}}},
+			options: FormatOptions{MultiLine: true, MaxWidth: 30, Indent: "    "},
+			want: "(\n" +
+				"    unit_price * quantity\n" +
+				"        - discount_rate\n" +
+				"        + max(\n" +
+				"            unit_price,\n" +
+				"            quantity\n" +
+				"        )\n" +
+				")\n" +
+				"    * min(\n" +
+				"        unit_price * quantity\n" +
+				"            - discount_rate\n" +
+				"            + max(\n" +
+				"                unit_price,\n" +
+				"                quantity\n" +
+				"            ),\n" +
+				"        discount_rate\n" +
+				"    )",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := FormatWithOptions(tt.node, tt.options); got != tt.want {
+				t.Errorf("FormatWithOptions() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNode_String(t *testing.T) {
+	x := &VariableNode{VarName: "x"}
+	tests := []struct {
+		name string
+		node Node
+		want string
+	}{
+		{name: "NumberNode", node: &NumberNode{Value: decimal.NewFromInt(-2)}, want: "-2"},
+		{name: "VariableNode", node: x, want: "x"},
+		{name: "UnaryOpNode", node: &UnaryOpNode{Operator: "-", Operand: x}, want: "-x"},
+		{name: "BinaryOpNode", node: &BinaryOpNode{Left: x, Operator: "^", Right: x}, want: "x ^ x"},
+		{name: "FunctionNode", node: &FunctionNode{FuncName: "abs", Args: []Node{x}}, want: "abs(x)"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := fmt.Sprint(tt.node); got != tt.want {
+				t.Errorf("String() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
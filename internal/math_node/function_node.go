@@ -2,7 +2,7 @@ package math_node
 
 import (
 	"context"
-	"fmt"
+	"sort"
 
 	"github.com/shopspring/decimal"
 
@@ -19,6 +19,37 @@ type FunctionNode struct {
 	Pos      int // 函数在表达式中的位置，用于错误报告
 }
 
+// Arity 函数的参数数量范围，Max 为 -1 表示不限
+type Arity struct {
+	Min int
+	Max int
+}
+
+// BuiltinFunctions 内置函数及其参数数量，需要与 Eval 中支持的函数保持一致
+var BuiltinFunctions = map[string]Arity{
+	"sqrt":  {Min: 1, Max: 1},
+	"abs":   {Min: 1, Max: 1},
+	"round": {Min: 1, Max: 2},
+	"ceil":  {Min: 1, Max: 2},
+	"floor": {Min: 1, Max: 2},
+	"pow":   {Min: 2, Max: 2},
+	"min":   {Min: 1, Max: -1},
+	"max":   {Min: 1, Max: -1},
+
+	"coalesce":  {Min: 1, Max: -1},
+	"isdefined": {Min: 1, Max: 1},
+}
+
+// FunctionNames 返回所有内置函数名，按名称排序
+func FunctionNames() []string {
+	names := make([]string, 0, len(BuiltinFunctions))
+	for name := range BuiltinFunctions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
 // Eval 实现 FunctionNode 的 Eval 方法
 func (n *FunctionNode) Eval(ctx context.Context, vars map[string]decimal.Decimal, config *math_config.CalcConfig) (decimal.Decimal, error) {
 	// 空指针检查
@@ -29,6 +60,18 @@ func (n *FunctionNode) Eval(ctx context.Context, vars map[string]decimal.Decimal
 	if err := math_utils.CheckContext(ctx, config.MaxRecursionDepth); err != nil {
 		return decimal.Zero, err
 	}
+	// 消耗一次运算
+	if err := math_utils.Spend(ctx, 1); err != nil {
+		return decimal.Zero, math_utils.LimitError(err, n.Pos, n.FuncName)
+	}
+
+	// 判断变量是否有值的函数按需计算参数
+	switch n.FuncName {
+	case "coalesce":
+		return n.evalCoalesce(ctx, vars, config)
+	case "isdefined":
+		return n.evalIsDefined(ctx, vars, config)
+	}
 
 	// 计算所有参数
 	var args []decimal.Decimal
@@ -40,44 +83,37 @@ func (n *FunctionNode) Eval(ctx context.Context, vars map[string]decimal.Decimal
 		args = append(args, result)
 	}
 
+	return n.call(ctx, args, config)
+}
+
+// call 使用已经计算出的参数调用函数，检查结果的位数并应用精度控制
+func (n *FunctionNode) call(ctx context.Context, args []decimal.Decimal, config *math_config.CalcConfig) (decimal.Decimal, error) {
 	// 根据函数名执行相应的函数
 	var result decimal.Decimal
 
 	switch n.FuncName {
 	case "sqrt":
 		if len(args) != 1 {
-			return decimal.Zero, &internal.ParseError{
-				Pos:     n.Pos,
-				Message: fmt.Sprintf("sqrt 函数需要正好 1 个参数，实际收到 %d 个", len(args)),
-				Cause:   internal.ErrInvalidArgument,
-			}
+			return decimal.Zero, internal.NewParseError(n.Pos, internal.ErrInvalidArgument, n.FuncName, internal.MsgArgumentCountExact, "sqrt", 1, len(args))
 		}
 		val := args[0]
 		if val.LessThan(decimal.Zero) {
-			return decimal.Zero, &internal.ParseError{
-				Pos:     n.Pos,
-				Message: fmt.Sprintf("不能计算负数的平方根: %s", val),
-				Cause:   internal.ErrInvalidArgument,
-			}
+			return decimal.Zero, internal.NewParseError(n.Pos, internal.ErrInvalidArgument, n.FuncName, internal.MsgNegativeSqrt, val)
 		}
-		// 使用优化的平方根计算
-		result = math_func.OptimizedDecimalSqrt(val)
+		// 使用牛顿迭代法计算平方根，限制迭代次数并在每次迭代后检查资源限制
+		sqrt, err := math_func.GuardedSqrt(val, math_utils.MaxIterations(config), math_utils.Guard(ctx, config))
+		if err != nil {
+			return decimal.Zero, math_utils.LimitError(err, n.Pos, n.FuncName)
+		}
+		result = sqrt
 	case "abs":
 		if len(args) != 1 {
-			return decimal.Zero, &internal.ParseError{
-				Pos:     n.Pos,
-				Message: fmt.Sprintf("abs 函数需要正好 1 个参数，实际收到 %d 个", len(args)),
-				Cause:   internal.ErrInvalidArgument,
-			}
+			return decimal.Zero, internal.NewParseError(n.Pos, internal.ErrInvalidArgument, n.FuncName, internal.MsgArgumentCountExact, "abs", 1, len(args))
 		}
 		result = args[0].Abs()
 	case "round": // 四舍五入
 		if len(args) < 1 || len(args) > 2 {
-			return decimal.Zero, &internal.ParseError{
-				Pos:     n.Pos,
-				Message: fmt.Sprintf("round 函数需要 1 或 2 个参数，实际收到 %d 个", len(args)),
-				Cause:   internal.ErrInvalidArgument,
-			}
+			return decimal.Zero, internal.NewParseError(n.Pos, internal.ErrInvalidArgument, n.FuncName, internal.MsgArgumentCountRange, "round", 1, 2, len(args))
 		}
 
 		// 如果只有一个参数，四舍五入到整数
@@ -87,21 +123,13 @@ func (n *FunctionNode) Eval(ctx context.Context, vars map[string]decimal.Decimal
 			// 如果有两个参数，第二个参数指定小数位数
 			places := args[1]
 			if !places.Equal(places.Floor()) || places.LessThan(decimal.Zero) {
-				return decimal.Zero, &internal.ParseError{
-					Pos:     n.Pos,
-					Message: "小数位数必须是非负整数",
-					Cause:   internal.ErrInvalidArgument,
-				}
+				return decimal.Zero, internal.NewParseError(n.Pos, internal.ErrInvalidArgument, n.FuncName, internal.MsgInvalidDecimalPlaces)
 			}
 			result = math_func.RoundToPlaces(args[0], int32(places.IntPart()))
 		}
 	case "ceil": // 向上取整
 		if len(args) < 1 || len(args) > 2 {
-			return decimal.Zero, &internal.ParseError{
-				Pos:     n.Pos,
-				Message: fmt.Sprintf("ceil 函数需要 1 或 2 个参数，实际收到 %d 个", len(args)),
-				Cause:   internal.ErrInvalidArgument,
-			}
+			return decimal.Zero, internal.NewParseError(n.Pos, internal.ErrInvalidArgument, n.FuncName, internal.MsgArgumentCountRange, "ceil", 1, 2, len(args))
 		}
 
 		// 如果只有一个参数，向上取整到整数
@@ -111,22 +139,14 @@ func (n *FunctionNode) Eval(ctx context.Context, vars map[string]decimal.Decimal
 			// 如果有两个参数，第二个参数指定小数位数
 			places := args[1]
 			if !places.Equal(places.Floor()) || places.LessThan(decimal.Zero) {
-				return decimal.Zero, &internal.ParseError{
-					Pos:     n.Pos,
-					Message: "小数位数必须是非负整数",
-					Cause:   internal.ErrInvalidArgument,
-				}
+				return decimal.Zero, internal.NewParseError(n.Pos, internal.ErrInvalidArgument, n.FuncName, internal.MsgInvalidDecimalPlaces)
 			}
 
 			result = math_func.CeilToPlaces(args[0], int32(places.IntPart()))
 		}
 	case "floor": // 向下取整
 		if len(args) < 1 || len(args) > 2 {
-			return decimal.Zero, &internal.ParseError{
-				Pos:     n.Pos,
-				Message: fmt.Sprintf("floor 函数需要 1 或 2 个参数，实际收到 %d 个", len(args)),
-				Cause:   internal.ErrInvalidArgument,
-			}
+			return decimal.Zero, internal.NewParseError(n.Pos, internal.ErrInvalidArgument, n.FuncName, internal.MsgArgumentCountRange, "floor", 1, 2, len(args))
 		}
 
 		// 如果只有一个参数，向下取整到整数
@@ -136,53 +156,40 @@ func (n *FunctionNode) Eval(ctx context.Context, vars map[string]decimal.Decimal
 			// 如果有两个参数，第二个参数指定小数位数
 			places := args[1]
 			if !places.Equal(places.Floor()) || places.LessThan(decimal.Zero) {
-				return decimal.Zero, &internal.ParseError{
-					Pos:     n.Pos,
-					Message: "小数位数必须是非负整数",
-					Cause:   internal.ErrInvalidArgument,
-				}
+				return decimal.Zero, internal.NewParseError(n.Pos, internal.ErrInvalidArgument, n.FuncName, internal.MsgInvalidDecimalPlaces)
 			}
 
 			result = math_func.FloorToPlaces(args[0], int32(places.IntPart()))
 		}
 	case "pow":
 		if len(args) != 2 {
-			return decimal.Zero, &internal.ParseError{
-				Pos:     n.Pos,
-				Message: fmt.Sprintf("pow 函数需要正好 2 个参数，实际收到 %d 个", len(args)),
-				Cause:   internal.ErrInvalidArgument,
-			}
+			return decimal.Zero, internal.NewParseError(n.Pos, internal.ErrInvalidArgument, n.FuncName, internal.MsgArgumentCountExact, "pow", 2, len(args))
 		}
 		base := args[0]
 		exponent := args[1]
 
-		// 处理负指数
-		if exponent.LessThan(decimal.Zero) {
-			// 对于负指数，计算 1/(base^|exponent|)
-			exponent = exponent.Abs()
-			base = decimal.New(1, 0).Div(base)
+		// 对于非整数指数，返回错误
+		if !exponent.Equal(exponent.Floor()) {
+			return decimal.Zero, internal.NewParseError(n.Pos, internal.ErrInvalidArgument, n.FuncName, internal.MsgNonIntegerExponent)
+		}
+		// 检查指数大小
+		if err := math_utils.CheckExponent(exponent, config); err != nil {
+			return decimal.Zero, math_utils.LimitError(err, n.Pos, n.FuncName)
+		}
+		// 零的负数次幂相当于除以零
+		if base.IsZero() && exponent.IsNegative() {
+			return decimal.Zero, internal.NewParseError(n.Pos, internal.ErrDivisionByZero, n.FuncName, internal.MsgDivisorZero)
 		}
 
-		// 处理整数指数
-		if exponent.Equal(exponent.Floor()) {
-			exp := exponent.IntPart()
-			// 使用优化的幂运算
-			result = math_func.FastPow(base, exp)
-		} else {
-			// 对于非整数指数，返回错误
-			return decimal.Zero, &internal.ParseError{
-				Pos:     n.Pos,
-				Message: "目前不支持非整数指数",
-				Cause:   internal.ErrInvalidArgument,
-			}
+		// 使用位运算计算幂，负指数计算 1/(base^|exponent|)，每次乘法后检查资源限制
+		pow, err := math_func.GuardedPow(base, exponent.IntPart(), math_utils.Guard(ctx, config))
+		if err != nil {
+			return decimal.Zero, math_utils.LimitError(err, n.Pos, n.FuncName)
 		}
+		result = pow
 	case "min":
 		if len(args) < 1 {
-			return decimal.Zero, &internal.ParseError{
-				Pos:     n.Pos,
-				Message: "min 函数需要至少 1 个参数",
-				Cause:   internal.ErrInvalidArgument,
-			}
+			return decimal.Zero, internal.NewParseError(n.Pos, internal.ErrInvalidArgument, n.FuncName, internal.MsgArgumentCountMin, "min", 1)
 		}
 		result = args[0]
 		for _, arg := range args[1:] {
@@ -192,11 +199,7 @@ func (n *FunctionNode) Eval(ctx context.Context, vars map[string]decimal.Decimal
 		}
 	case "max":
 		if len(args) < 1 {
-			return decimal.Zero, &internal.ParseError{
-				Pos:     n.Pos,
-				Message: "max 函数需要至少 1 个参数",
-				Cause:   internal.ErrInvalidArgument,
-			}
+			return decimal.Zero, internal.NewParseError(n.Pos, internal.ErrInvalidArgument, n.FuncName, internal.MsgArgumentCountMin, "max", 1)
 		}
 		result = args[0]
 		for _, arg := range args[1:] {
@@ -205,11 +208,13 @@ func (n *FunctionNode) Eval(ctx context.Context, vars map[string]decimal.Decimal
 			}
 		}
 	default:
-		return decimal.Zero, &internal.ParseError{
-			Pos:     n.Pos,
-			Message: fmt.Sprintf("不支持的函数: %s", n.FuncName),
-			Cause:   internal.ErrUnsupportedOperator,
-		}
+		return decimal.Zero, internal.NewParseError(n.Pos, internal.ErrUnsupportedOperator, n.FuncName, internal.MsgUnsupportedFunction, n.FuncName).
+			WithSuggestions(internal.Suggest(n.FuncName, FunctionNames()))
+	}
+
+	// 检查结果的位数
+	if err := math_utils.CheckDigits(result, config); err != nil {
+		return decimal.Zero, math_utils.LimitError(err, n.Pos, n.FuncName)
 	}
 
 	// 根据精度控制策略决定是否应用精度控制
@@ -218,3 +223,39 @@ func (n *FunctionNode) Eval(ctx context.Context, vars map[string]decimal.Decimal
 	}
 	return result, nil
 }
+
+// evalCoalesce 返回第一个有值的参数，参数中未定义或为空的变量跳过到下一个参数，
+// 最后一个参数按缺失变量的处理策略计算
+func (n *FunctionNode) evalCoalesce(ctx context.Context, vars map[string]decimal.Decimal, config *math_config.CalcConfig) (decimal.Decimal, error) {
+	if len(n.Args) < 1 {
+		return decimal.Zero, internal.NewParseError(n.Pos, internal.ErrInvalidArgument, n.FuncName, internal.MsgArgumentCountMin, "coalesce", 1)
+	}
+	strict := math_utils.WithStrictVariables(ctx)
+	for _, arg := range n.Args[:len(n.Args)-1] {
+		result, err := arg.Eval(strict, vars, config)
+		if err == nil || !math_utils.IsMissing(err) {
+			return result, err
+		}
+	}
+	return n.Args[len(n.Args)-1].Eval(ctx, vars, config)
+}
+
+// evalIsDefined 参数中的变量都有值时返回 1，有未定义或为空的变量时返回 0
+func (n *FunctionNode) evalIsDefined(ctx context.Context, vars map[string]decimal.Decimal, config *math_config.CalcConfig) (decimal.Decimal, error) {
+	if len(n.Args) != 1 {
+		return decimal.Zero, internal.NewParseError(n.Pos, internal.ErrInvalidArgument, n.FuncName, internal.MsgArgumentCountExact, "isdefined", 1, len(n.Args))
+	}
+	_, err := n.Args[0].Eval(math_utils.WithStrictVariables(ctx), vars, config)
+	switch {
+	case err == nil:
+		return decimal.NewFromInt(1), nil
+	case math_utils.IsMissing(err):
+		return decimal.Zero, nil
+	}
+	return decimal.Zero, err
+}
+
+// String 返回 FunctionNode 的表达式字符串
+func (n *FunctionNode) String() string {
+	return Format(n)
+}
@@ -2,11 +2,13 @@ package math_node
 
 import (
 	"context"
+	"errors"
 	"testing"
 
 	"github.com/shopspring/decimal"
 
 	"github.com/ZHOUXING1997/math_calculation/internal"
+	"github.com/ZHOUXING1997/math_calculation/internal/math_utils"
 	"github.com/ZHOUXING1997/math_calculation/math_config"
 )
 
@@ -342,3 +344,198 @@ func TestFunctionNode_EvalWithArgErrors(t *testing.T) {
 		}
 	})
 }
+
+// 测试 BuiltinFunctions 中的参数数量与 Eval 的检查一致
+func TestBuiltinFunctions(t *testing.T) {
+	ctx := context.Background()
+	config := math_config.NewDefaultCalcConfig()
+
+	args := func(n int) []Node {
+		nodes := make([]Node, n)
+		for i := range nodes {
+			nodes[i] = &NumberNode{Value: decimal.NewFromInt(4)}
+		}
+		return nodes
+	}
+
+	for name, arity := range BuiltinFunctions {
+		t.Run(name, func(t *testing.T) {
+			if arity.Min > 0 {
+				node := &FunctionNode{FuncName: name, Args: args(arity.Min - 1)}
+				if _, err := node.Eval(ctx, nil, config); err == nil {
+					t.Errorf("%s with %d arguments: expected error", name, arity.Min-1)
+				}
+			}
+
+			max := arity.Max
+			if max < 0 {
+				max = arity.Min + 3
+			}
+			for n := arity.Min; n <= max; n++ {
+				node := &FunctionNode{FuncName: name, Args: args(n)}
+				if _, err := node.Eval(ctx, nil, config); err != nil {
+					t.Errorf("%s with %d arguments: unexpected error %v", name, n, err)
+				}
+			}
+
+			if arity.Max >= 0 {
+				node := &FunctionNode{FuncName: name, Args: args(arity.Max + 1)}
+				if _, err := node.Eval(ctx, nil, config); err == nil {
+					t.Errorf("%s with %d arguments: expected error", name, arity.Max+1)
+				}
+			}
+		})
+	}
+}
+
+// 测试不支持的函数的错误中包含相近的函数名
+func TestFunctionNode_EvalSuggestions(t *testing.T) {
+	node := &FunctionNode{FuncName: "sqr", Args: []Node{&NumberNode{Value: decimal.NewFromInt(4)}}}
+	_, err := node.Eval(context.Background(), nil, nil)
+	parseErr, ok := err.(*internal.ParseError)
+	if !ok {
+		t.Fatalf("FunctionNode.Eval() error = %v, want *internal.ParseError", err)
+	}
+	if len(parseErr.Suggestions) != 1 || parseErr.Suggestions[0] != "sqrt" {
+		t.Errorf("FunctionNode.Eval() suggestions = %q, want [sqrt]", parseErr.Suggestions)
+	}
+}
+
+// 测试超过资源限制的情况
+func TestFunctionNode_EvalWithResourceLimits(t *testing.T) {
+	num := func(s string) Node { return &NumberNode{Value: decimal.RequireFromString(s)} }
+
+	tests := []struct {
+		name      string
+		node      *FunctionNode
+		config    func(config *math_config.CalcConfig)
+		wantLimit internal.ResourceLimit
+		wantErr   error
+	}{
+		{
+			name:      "pow指数超过限制",
+			node:      &FunctionNode{FuncName: "pow", Args: []Node{num("10"), num("99999999")}},
+			wantLimit: internal.LimitExponent,
+		},
+		{
+			name:      "pow中间结果位数超过限制",
+			node:      &FunctionNode{FuncName: "pow", Args: []Node{num("99999"), num("9999")}},
+			config:    func(config *math_config.CalcConfig) { config.MaxDigits = 1000 },
+			wantLimit: internal.LimitDigits,
+		},
+		{
+			name:    "pow零的负数次幂",
+			node:    &FunctionNode{FuncName: "pow", Args: []Node{num("0"), num("-2")}},
+			wantErr: internal.ErrDivisionByZero,
+		},
+		{
+			name:      "sqrt迭代次数超过限制",
+			node:      &FunctionNode{FuncName: "sqrt", Args: []Node{num("2e1000")}},
+			config:    func(config *math_config.CalcConfig) { config.MaxIterations = 1 },
+			wantLimit: internal.LimitIterations,
+		},
+		{
+			name:      "运算次数超过限制",
+			node:      &FunctionNode{FuncName: "sqrt", Args: []Node{num("2e1000")}},
+			config:    func(config *math_config.CalcConfig) { config.MaxOperations = 3 },
+			wantLimit: internal.LimitOperations,
+		},
+		{
+			name:      "参数位数超过限制",
+			node:      &FunctionNode{FuncName: "abs", Args: []Node{num("123456789")}},
+			config:    func(config *math_config.CalcConfig) { config.MaxDigits = 5 },
+			wantLimit: internal.LimitDigits,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := math_config.NewDefaultCalcConfig()
+			config.ApplyPrecisionEachStep = false
+			if tt.config != nil {
+				tt.config(config)
+			}
+			ctx := math_utils.WithBudget(context.Background(), config)
+			_, err := tt.node.Eval(ctx, nil, config)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("FunctionNode.Eval() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			var limitErr *internal.ResourceLimitError
+			if !errors.As(err, &limitErr) || limitErr.Limit != tt.wantLimit {
+				t.Errorf("FunctionNode.Eval() error = %v, want %s limit", err, tt.wantLimit)
+			}
+		})
+	}
+}
+
+// 测试 coalesce 和 isdefined 在不同缺失变量策略下的结果
+func TestFunctionNode_EvalMissing(t *testing.T) {
+	ctx := context.Background()
+	vars := map[string]decimal.Decimal{"x": decimal.NewFromInt(10)}
+	x := &VariableNode{VarName: "x"}
+	y := &VariableNode{VarName: "y"}
+	z := &VariableNode{VarName: "z"}
+	five := &NumberNode{Value: decimal.NewFromInt(5)}
+	withPolicy := func(policy math_config.MissingVariablePolicy) *math_config.CalcConfig {
+		config := math_config.NewDefaultCalcConfig()
+		config.MissingVariables = policy
+		return config
+	}
+	withDefault := math_config.NewDefaultCalcConfig()
+	withDefault.VariableDefaults = map[string]decimal.Decimal{"y": decimal.NewFromInt(7)}
+
+	tests := []struct {
+		name    string
+		node    *FunctionNode
+		config  *math_config.CalcConfig
+		want    decimal.Decimal
+		wantErr error
+	}{
+		{name: "coalesce-第一个参数有值", node: &FunctionNode{FuncName: "coalesce", Args: []Node{x, five}}, config: withPolicy(math_config.MissingAsError), want: decimal.NewFromInt(10)},
+		{name: "coalesce-跳过未定义的变量", node: &FunctionNode{FuncName: "coalesce", Args: []Node{y, x}}, config: withPolicy(math_config.MissingAsError), want: decimal.NewFromInt(10)},
+		{name: "coalesce-跳过为空的变量", node: &FunctionNode{FuncName: "coalesce", Args: []Node{y, z, five}}, config: withPolicy(math_config.MissingAsNull), want: decimal.NewFromInt(5)},
+		{name: "coalesce-按0计算时也跳过", node: &FunctionNode{FuncName: "coalesce", Args: []Node{y, five}}, config: withPolicy(math_config.MissingAsZero), want: decimal.NewFromInt(5)},
+		{name: "coalesce-默认值视为有值", node: &FunctionNode{FuncName: "coalesce", Args: []Node{y, five}}, config: withDefault, want: decimal.NewFromInt(7)},
+		{name: "coalesce-最后一个参数按策略计算", node: &FunctionNode{FuncName: "coalesce", Args: []Node{y, z}}, config: withPolicy(math_config.MissingAsZero), want: decimal.Zero},
+		{name: "coalesce-都没有值", node: &FunctionNode{FuncName: "coalesce", Args: []Node{y, z}}, config: withPolicy(math_config.MissingAsError), wantErr: internal.ErrUndefinedVariable},
+		{name: "coalesce-都为空", node: &FunctionNode{FuncName: "coalesce", Args: []Node{y, z}}, config: withPolicy(math_config.MissingAsNull), wantErr: internal.ErrNullResult},
+		{
+			name:    "coalesce-其他错误不跳过",
+			node:    &FunctionNode{FuncName: "coalesce", Args: []Node{&FunctionNode{FuncName: "sqrt", Args: []Node{&NumberNode{Value: decimal.NewFromInt(-1)}}}, five}},
+			config:  withPolicy(math_config.MissingAsError),
+			wantErr: internal.ErrInvalidArgument,
+		},
+		{name: "isdefined-有值", node: &FunctionNode{FuncName: "isdefined", Args: []Node{x}}, config: withPolicy(math_config.MissingAsError), want: decimal.NewFromInt(1)},
+		{name: "isdefined-未定义", node: &FunctionNode{FuncName: "isdefined", Args: []Node{y}}, config: withPolicy(math_config.MissingAsError), want: decimal.Zero},
+		{name: "isdefined-按0计算", node: &FunctionNode{FuncName: "isdefined", Args: []Node{y}}, config: withPolicy(math_config.MissingAsZero), want: decimal.Zero},
+		{name: "isdefined-为空", node: &FunctionNode{FuncName: "isdefined", Args: []Node{y}}, config: withPolicy(math_config.MissingAsNull), want: decimal.Zero},
+		{name: "isdefined-有默认值", node: &FunctionNode{FuncName: "isdefined", Args: []Node{y}}, config: withDefault, want: decimal.NewFromInt(1)},
+		{
+			name:   "isdefined-表达式中有未定义的变量",
+			node:   &FunctionNode{FuncName: "isdefined", Args: []Node{&BinaryOpNode{Left: x, Operator: "+", Right: z}}},
+			config: withPolicy(math_config.MissingAsZero),
+			want:   decimal.Zero,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.node.Eval(ctx, vars, tt.config)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("FunctionNode.Eval() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("FunctionNode.Eval() error = %v", err)
+			}
+			if !got.Equal(tt.want) {
+				t.Errorf("FunctionNode.Eval() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
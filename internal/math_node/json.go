@@ -0,0 +1,141 @@
+package math_node
+
+import (
+	"github.com/shopspring/decimal"
+
+	"github.com/ZHOUXING1997/math_calculation/internal"
+)
+
+// JSON 中的节点类型
+const (
+	KindNumber   = "number"
+	KindVariable = "variable"
+	KindUnary    = "unary"
+	KindBinary   = "binary"
+	KindFunction = "function"
+)
+
+// maxJSONDepth 反序列化时允许的最大嵌套深度，防止恶意输入导致栈溢出
+const maxJSONDepth = 1000
+
+// JSONNode 表达式树节点的 JSON 表示
+// 数字的值以字符串保存，避免 JSON 数字转换为浮点数时丢失精度
+type JSONNode struct {
+	Kind     string      `json:"kind"`               // 节点类型
+	Operator string      `json:"operator,omitempty"` // 一元或二元运算符
+	Value    string      `json:"value,omitempty"`    // 数字的值
+	Name     string      `json:"name,omitempty"`     // 变量名或函数名
+	Path     []string    `json:"path,omitempty"`     // 变量路径的各段，只有一段时省略
+	Pos      int         `json:"pos"`                // 节点在原始表达式中的位置
+	Children []*JSONNode `json:"children,omitempty"` // 操作数或函数参数，按书写顺序排列
+}
+
+// ToJSONNode 将表达式树转换为 JSON 表示
+func ToJSONNode(node Node) (*JSONNode, error) {
+	switch n := node.(type) {
+	case *NumberNode:
+		return &JSONNode{Kind: KindNumber, Value: n.Value.String(), Pos: n.Pos}, nil
+	case *VariableNode:
+		return &JSONNode{Kind: KindVariable, Name: n.VarName, Path: n.Path, Pos: n.Pos}, nil
+	case *UnaryOpNode:
+		operand, err := ToJSONNode(n.Operand)
+		if err != nil {
+			return nil, err
+		}
+		return &JSONNode{Kind: KindUnary, Operator: n.Operator, Pos: n.Pos, Children: []*JSONNode{operand}}, nil
+	case *BinaryOpNode:
+		left, err := ToJSONNode(n.Left)
+		if err != nil {
+			return nil, err
+		}
+		right, err := ToJSONNode(n.Right)
+		if err != nil {
+			return nil, err
+		}
+		return &JSONNode{Kind: KindBinary, Operator: n.Operator, Pos: n.Pos, Children: []*JSONNode{left, right}}, nil
+	case *FunctionNode:
+		children := make([]*JSONNode, 0, len(n.Args))
+		for _, arg := range n.Args {
+			child, err := ToJSONNode(arg)
+			if err != nil {
+				return nil, err
+			}
+			children = append(children, child)
+		}
+		return &JSONNode{Kind: KindFunction, Name: n.FuncName, Pos: n.Pos, Children: children}, nil
+	}
+
+	return nil, internal.NewParseError(0, internal.ErrInvalidExpression, "", internal.MsgUnserializableNode, node)
+}
+
+// FromJSONNode 从 JSON 表示重建表达式树，会校验节点结构但不会重新解析表达式
+func FromJSONNode(j *JSONNode) (Node, error) {
+	return fromJSONNode(j, 0)
+}
+
+// fromJSONNode 递归重建表达式树
+func fromJSONNode(j *JSONNode, depth int) (Node, error) {
+	if j == nil {
+		return nil, invalidJSONNode(0, internal.MsgJSONNodeNil)
+	}
+	if depth > maxJSONDepth {
+		return nil, invalidJSONNode(j.Pos, internal.MsgJSONNodeTooDeep)
+	}
+
+	// 先重建子节点
+	children := make([]Node, 0, len(j.Children))
+	for _, c := range j.Children {
+		child, err := fromJSONNode(c, depth+1)
+		if err != nil {
+			return nil, err
+		}
+		children = append(children, child)
+	}
+
+	switch j.Kind {
+	case KindNumber:
+		if len(children) != 0 {
+			return nil, invalidJSONNode(j.Pos, internal.MsgJSONNumberChildren)
+		}
+		val, err := decimal.NewFromString(j.Value)
+		if err != nil {
+			return nil, internal.NewParseError(j.Pos, err, j.Value, internal.MsgInvalidNumber, j.Value)
+		}
+		return &NumberNode{Value: val, Pos: j.Pos}, nil
+	case KindVariable:
+		if j.Name == "" || len(children) != 0 {
+			return nil, invalidJSONNode(j.Pos, internal.MsgJSONVariableInvalid)
+		}
+		return &VariableNode{VarName: j.Name, Path: j.Path, Pos: j.Pos}, nil
+	case KindUnary:
+		if j.Operator != "+" && j.Operator != "-" {
+			return nil, invalidJSONNode(j.Pos, internal.MsgUnsupportedUnaryOperator, j.Operator)
+		}
+		if len(children) != 1 {
+			return nil, invalidJSONNode(j.Pos, internal.MsgJSONUnaryChildren, len(children))
+		}
+		return &UnaryOpNode{Operator: j.Operator, Operand: children[0], Pos: j.Pos}, nil
+	case KindBinary:
+		switch j.Operator {
+		case "+", "-", "*", "/", "^":
+		default:
+			return nil, invalidJSONNode(j.Pos, internal.MsgUnsupportedOperator, j.Operator)
+		}
+		if len(children) != 2 {
+			return nil, invalidJSONNode(j.Pos, internal.MsgJSONBinaryChildren, len(children))
+		}
+		return &BinaryOpNode{Left: children[0], Operator: j.Operator, Right: children[1], Pos: j.Pos}, nil
+	case KindFunction:
+		if j.Name == "" {
+			return nil, invalidJSONNode(j.Pos, internal.MsgJSONFunctionName)
+		}
+		return &FunctionNode{FuncName: j.Name, Args: children, Pos: j.Pos}, nil
+	}
+
+	return nil, invalidJSONNode(j.Pos, internal.MsgJSONUnknownKind, j.Kind)
+}
+
+// invalidJSONNode 创建无效节点错误
+func invalidJSONNode(pos int, key string, args ...interface{}) error {
+	return internal.NewParseError(pos, internal.ErrInvalidExpression, "", key, args...)
+}
@@ -0,0 +1,105 @@
+package math_node
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestToJSONNode(t *testing.T) {
+	node := &BinaryOpNode{
+		Left:     &NumberNode{Value: decimal.RequireFromString("0.1000000000000000000001"), Pos: 0},
+		Operator: "*",
+		Right: &FunctionNode{FuncName: "max", Pos: 26, Args: []Node{
+			&VariableNode{VarName: "x", Pos: 30},
+			&UnaryOpNode{Operator: "-", Operand: &VariableNode{VarName: "y", Pos: 34}, Pos: 33},
+		}},
+		Pos: 24,
+	}
+
+	j, err := ToJSONNode(node)
+	if err != nil {
+		t.Fatalf("ToJSONNode() error = %v", err)
+	}
+
+	if j.Kind != KindBinary || j.Operator != "*" || j.Pos != 24 || len(j.Children) != 2 {
+		t.Errorf("ToJSONNode() root = %+v", j)
+	}
+	// 数字以字符串保存，不丢失精度
+	if j.Children[0].Value != "0.1000000000000000000001" {
+		t.Errorf("ToJSONNode() number value = %q", j.Children[0].Value)
+	}
+	if fn := j.Children[1]; fn.Kind != KindFunction || fn.Name != "max" || len(fn.Children) != 2 {
+		t.Errorf("ToJSONNode() function = %+v", fn)
+	}
+
+	back, err := FromJSONNode(j)
+	if err != nil {
+		t.Fatalf("FromJSONNode() error = %v", err)
+	}
+	if got, want := Format(back), Format(node); got != want {
+		t.Errorf("FromJSONNode() = %q, want %q", got, want)
+	}
+	if pos := back.(*BinaryOpNode).Right.(*FunctionNode).Args[1].(*UnaryOpNode).Pos; pos != 33 {
+		t.Errorf("FromJSONNode() unary pos = %d, want 33", pos)
+	}
+}
+
+func TestJSONNode_VariablePath(t *testing.T) {
+	node := &VariableNode{VarName: "order.items[0].price", Path: []string{"order", "items", "0", "price"}, Pos: 3}
+
+	j, err := ToJSONNode(node)
+	if err != nil {
+		t.Fatalf("ToJSONNode() error = %v", err)
+	}
+	if j.Name != "order.items[0].price" || len(j.Path) != 4 {
+		t.Errorf("ToJSONNode() = %+v", j)
+	}
+
+	back, err := FromJSONNode(j)
+	if err != nil {
+		t.Fatalf("FromJSONNode() error = %v", err)
+	}
+	v := back.(*VariableNode)
+	if v.VarName != node.VarName || strings.Join(v.Path, "/") != "order/items/0/price" || v.Pos != 3 {
+		t.Errorf("FromJSONNode() = %+v, want %+v", v, node)
+	}
+}
+
+func TestFromJSONNode_Invalid(t *testing.T) {
+	number := &JSONNode{Kind: KindNumber, Value: "1"}
+
+	tests := []struct {
+		name     string
+		node     *JSONNode
+		errorMsg string
+	}{
+		{name: "空节点", node: nil, errorMsg: "节点为空"},
+		{name: "未知类型", node: &JSONNode{Kind: "matrix"}, errorMsg: "未知的节点类型"},
+		{name: "无效数字", node: &JSONNode{Kind: KindNumber, Value: "1.2.3"}, errorMsg: "无效的数字"},
+		{name: "变量缺少名称", node: &JSONNode{Kind: KindVariable}, errorMsg: "变量节点需要名称"},
+		{name: "不支持的运算符", node: &JSONNode{Kind: KindBinary, Operator: "%", Children: []*JSONNode{number, number}}, errorMsg: "不支持的运算符"},
+		{name: "二元缺少子节点", node: &JSONNode{Kind: KindBinary, Operator: "+", Children: []*JSONNode{number}}, errorMsg: "二元运算符需要 2 个子节点"},
+		{name: "一元运算符错误", node: &JSONNode{Kind: KindUnary, Operator: "!", Children: []*JSONNode{number}}, errorMsg: "不支持的一元运算符"},
+		{name: "函数缺少名称", node: &JSONNode{Kind: KindFunction}, errorMsg: "函数节点需要名称"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := FromJSONNode(tt.node)
+			if err == nil || !strings.Contains(err.Error(), tt.errorMsg) {
+				t.Errorf("FromJSONNode() error = %v, want containing %q", err, tt.errorMsg)
+			}
+		})
+	}
+
+	// 嵌套过深
+	deep := number
+	for i := 0; i < maxJSONDepth+1; i++ {
+		deep = &JSONNode{Kind: KindUnary, Operator: "-", Children: []*JSONNode{deep}}
+	}
+	if _, err := FromJSONNode(deep); err == nil || !strings.Contains(err.Error(), "节点嵌套过深") {
+		t.Errorf("FromJSONNode() error = %v, want nesting error", err)
+	}
+}
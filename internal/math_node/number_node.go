@@ -12,6 +12,7 @@ import (
 // NumberNode 数字节点
 type NumberNode struct {
 	Value decimal.Decimal
+	Pos   int // 数字在表达式中的位置，用于错误报告
 }
 
 // Eval 实现 NumberNode 的 Eval 方法
@@ -20,9 +21,19 @@ func (n *NumberNode) Eval(ctx context.Context, vars map[string]decimal.Decimal,
 	if config == nil {
 		config = math_config.NewDefaultCalcConfig()
 	}
+	// 检查数字的位数，防止 1e999999999 这样的字面量在之后的运算中展开
+	// 不能用 n.Value.String() 作为出错片段，它会把数字完整展开
+	if err := math_utils.CheckDigits(n.Value, config); err != nil {
+		return decimal.Zero, math_utils.LimitError(err, n.Pos, "")
+	}
 	// 根据精度控制策略决定是否应用精度控制
 	if config.ApplyPrecisionEachStep {
 		return math_utils.SetPrecision(n.Value, config.Precision, config.PrecisionMode), nil
 	}
 	return n.Value, nil
 }
+
+// String 返回 NumberNode 的表达式字符串
+func (n *NumberNode) String() string {
+	return Format(n)
+}
@@ -2,7 +2,6 @@ package math_node
 
 import (
 	"context"
-	"fmt"
 
 	"github.com/shopspring/decimal"
 
@@ -28,6 +27,10 @@ func (n *UnaryOpNode) Eval(ctx context.Context, vars map[string]decimal.Decimal,
 	if err := math_utils.CheckContext(ctx, config.MaxRecursionDepth); err != nil {
 		return decimal.Zero, err
 	}
+	// 消耗一次运算
+	if err := math_utils.Spend(ctx, 1); err != nil {
+		return decimal.Zero, math_utils.LimitError(err, n.Pos, n.Operator)
+	}
 
 	// 计算操作数
 	val, err := n.Operand.Eval(ctx, vars, config)
@@ -35,6 +38,11 @@ func (n *UnaryOpNode) Eval(ctx context.Context, vars map[string]decimal.Decimal,
 		return decimal.Zero, err
 	}
 
+	return n.apply(val, config)
+}
+
+// apply 对已经计算出的操作数执行运算并应用精度控制
+func (n *UnaryOpNode) apply(val decimal.Decimal, config *math_config.CalcConfig) (decimal.Decimal, error) {
 	// 根据运算符执行相应的运算
 	var result decimal.Decimal
 	switch n.Operator {
@@ -43,11 +51,7 @@ func (n *UnaryOpNode) Eval(ctx context.Context, vars map[string]decimal.Decimal,
 	case "+":
 		result = val
 	default:
-		return decimal.Zero, &internal.ParseError{
-			Pos:     n.Pos,
-			Message: fmt.Sprintf("不支持的一元运算符: %s", n.Operator),
-			Cause:   internal.ErrUnsupportedOperator,
-		}
+		return decimal.Zero, internal.NewParseError(n.Pos, internal.ErrUnsupportedOperator, n.Operator, internal.MsgUnsupportedUnaryOperator, n.Operator)
 	}
 
 	// 根据精度控制策略决定是否应用精度控制
@@ -56,3 +60,8 @@ func (n *UnaryOpNode) Eval(ctx context.Context, vars map[string]decimal.Decimal,
 	}
 	return result, nil
 }
+
+// String 返回 UnaryOpNode 的表达式字符串
+func (n *UnaryOpNode) String() string {
+	return Format(n)
+}
@@ -2,7 +2,6 @@ package math_node
 
 import (
 	"context"
-	"fmt"
 
 	"github.com/shopspring/decimal"
 
@@ -14,7 +13,8 @@ import (
 // VariableNode 变量节点
 type VariableNode struct {
 	VarName string
-	Pos     int // 变量在表达式中的位置，用于错误报告
+	Path    []string // 变量路径的各段，如 item[0].price 为 [item 0 price]，只有一段时为 nil
+	Pos     int      // 变量在表达式中的位置，用于错误报告
 }
 
 // Eval 实现 VariableNode 的 Eval 方法
@@ -23,18 +23,60 @@ func (n *VariableNode) Eval(ctx context.Context, vars map[string]decimal.Decimal
 	if config == nil {
 		config = math_config.NewDefaultCalcConfig()
 	}
-	// 检查变量是否存在
-	if val, ok := vars[n.VarName]; ok {
-		// 根据精度控制策略决定是否应用精度控制
-		if config.ApplyPrecisionEachStep {
-			return math_utils.SetPrecision(val, config.Precision, config.PrecisionMode), nil
+	// 检查变量是否存在，map 中没有时从上下文中的变量提供者获取
+	val, ok := vars[n.VarName]
+	if !ok {
+		var err error
+		val, ok, err = math_utils.LookupVariable(ctx, n.VarName, n.Path)
+		if err != nil {
+			// 调用方取消或超时导致的查询失败按上下文结束处理
+			if ctx.Err() != nil {
+				return decimal.Zero, math_utils.ContextError(ctx)
+			}
+			return decimal.Zero, internal.NewParseError(n.Pos, err, n.VarName, internal.MsgVariableLookup, n.VarName, err)
 		}
-		return val, nil
 	}
-	// 返回变量未定义错误，并包含位置信息
-	return decimal.Zero, &internal.ParseError{
-		Pos:     n.Pos,
-		Message: fmt.Sprintf("未定义的变量: %s", n.VarName),
-		Cause:   internal.ErrUndefinedVariable,
+	// 仍然没有时使用默认值，没有默认值时按缺失变量的处理策略
+	if !ok {
+		val, ok = config.VariableDefaults[n.VarName]
 	}
+	if !ok {
+		switch config.MissingVariables {
+		case math_config.MissingAsNull:
+			return decimal.Zero, internal.NewParseError(n.Pos, internal.ErrNullResult, n.VarName, internal.MsgNullVariable, n.VarName)
+		case math_config.MissingAsZero:
+			// coalesce 和 isdefined 的参数中缺失的变量按未定义处理
+			if !math_utils.StrictVariables(ctx) {
+				val, ok = decimal.Zero, true
+			}
+		}
+	}
+	if ok {
+		return n.value(val, config)
+	}
+	// 返回变量未定义错误，并包含位置信息和相近的变量名
+	names := make([]string, 0, len(vars))
+	for name := range vars {
+		names = append(names, name)
+	}
+	return decimal.Zero, internal.NewParseError(n.Pos, internal.ErrUndefinedVariable, n.VarName, internal.MsgUndefinedVariable, n.VarName).
+		WithSuggestions(internal.Suggest(n.VarName, names))
+}
+
+// value 检查变量值的位数并应用精度控制
+func (n *VariableNode) value(val decimal.Decimal, config *math_config.CalcConfig) (decimal.Decimal, error) {
+	// 检查变量值的位数
+	if err := math_utils.CheckDigits(val, config); err != nil {
+		return decimal.Zero, math_utils.LimitError(err, n.Pos, n.VarName)
+	}
+	// 根据精度控制策略决定是否应用精度控制
+	if config.ApplyPrecisionEachStep {
+		return math_utils.SetPrecision(val, config.Precision, config.PrecisionMode), nil
+	}
+	return val, nil
+}
+
+// String 返回 VariableNode 的表达式字符串
+func (n *VariableNode) String() string {
+	return Format(n)
 }
@@ -2,11 +2,13 @@ package math_node
 
 import (
 	"context"
+	"errors"
 	"testing"
 
 	"github.com/shopspring/decimal"
 
 	"github.com/ZHOUXING1997/math_calculation/internal"
+	"github.com/ZHOUXING1997/math_calculation/internal/math_utils"
 	"github.com/ZHOUXING1997/math_calculation/math_config"
 )
 
@@ -140,3 +142,147 @@ func TestVariableNode_Eval(t *testing.T) {
 		})
 	}
 }
+
+// 测试未定义变量的错误中包含相近的变量名
+func TestVariableNode_EvalSuggestions(t *testing.T) {
+	vars := map[string]decimal.Decimal{
+		"price":    decimal.NewFromInt(10),
+		"quantity": decimal.NewFromInt(2),
+	}
+
+	node := &VariableNode{VarName: "prcie", Pos: 0}
+	_, err := node.Eval(context.Background(), vars, nil)
+	parseErr, ok := err.(*internal.ParseError)
+	if !ok {
+		t.Fatalf("VariableNode.Eval() error = %v, want *internal.ParseError", err)
+	}
+	if len(parseErr.Suggestions) != 1 || parseErr.Suggestions[0] != "price" {
+		t.Errorf("VariableNode.Eval() suggestions = %q, want [price]", parseErr.Suggestions)
+	}
+}
+
+// 测试从变量提供者获取变量
+func TestVariableNode_EvalWithProvider(t *testing.T) {
+	errLookup := errors.New("lookup failed")
+	provider := math_utils.ProviderFunc(func(_ context.Context, name string) (decimal.Decimal, bool, error) {
+		switch name {
+		case "price":
+			return decimal.NewFromInt(5), true, nil
+		case "broken":
+			return decimal.Zero, false, errLookup
+		}
+		return decimal.Zero, false, nil
+	})
+	ctx := math_utils.WithProvider(context.Background(), provider)
+	vars := map[string]decimal.Decimal{"price": decimal.NewFromInt(1)}
+
+	tests := []struct {
+		name    string
+		varName string
+		vars    map[string]decimal.Decimal
+		want    decimal.Decimal
+		wantErr error
+	}{
+		{name: "从提供者获取", varName: "price", want: decimal.NewFromInt(5)},
+		{name: "map优先", varName: "price", vars: vars, want: decimal.NewFromInt(1)},
+		{name: "提供者中不存在", varName: "qty", wantErr: internal.ErrUndefinedVariable},
+		{name: "提供者出错", varName: "broken", wantErr: errLookup},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			node := &VariableNode{VarName: tt.varName, Pos: 2}
+			got, err := node.Eval(ctx, tt.vars, math_config.NewDefaultCalcConfig())
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("VariableNode.Eval() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("VariableNode.Eval() error = %v", err)
+			}
+			if !got.Equal(tt.want) {
+				t.Errorf("VariableNode.Eval() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// 测试按路径从变量提供者获取变量
+func TestVariableNode_EvalPath(t *testing.T) {
+	ctx := math_utils.WithProvider(context.Background(), math_utils.NestedMapProvider{
+		"order": map[string]interface{}{
+			"items": []interface{}{map[string]interface{}{"price": 12.5}},
+		},
+	})
+	vars := map[string]decimal.Decimal{"order.items[0].price": decimal.NewFromInt(3)}
+
+	tests := []struct {
+		name string
+		vars map[string]decimal.Decimal
+		want decimal.Decimal
+	}{
+		{name: "按路径获取", want: decimal.NewFromFloat(12.5)},
+		{name: "map中的完整变量名优先", vars: vars, want: decimal.NewFromInt(3)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			node := &VariableNode{VarName: "order.items[0].price", Path: []string{"order", "items", "0", "price"}}
+			got, err := node.Eval(ctx, tt.vars, math_config.NewDefaultCalcConfig())
+			if err != nil {
+				t.Fatalf("VariableNode.Eval() error = %v", err)
+			}
+			if !got.Equal(tt.want) {
+				t.Errorf("VariableNode.Eval() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	node := &VariableNode{VarName: "order.items[1].price", Path: []string{"order", "items", "1", "price"}}
+	if _, err := node.Eval(ctx, nil, math_config.NewDefaultCalcConfig()); !errors.Is(err, internal.ErrUndefinedVariable) {
+		t.Errorf("VariableNode.Eval() error = %v, want %v", err, internal.ErrUndefinedVariable)
+	}
+}
+
+// 测试缺失变量的默认值和处理策略
+func TestVariableNode_EvalMissing(t *testing.T) {
+	defaults := map[string]decimal.Decimal{"rate": decimal.RequireFromString("0.5")}
+
+	tests := []struct {
+		name     string
+		varName  string
+		policy   math_config.MissingVariablePolicy
+		defaults map[string]decimal.Decimal
+		want     decimal.Decimal
+		wantErr  error
+	}{
+		{name: "默认返回错误", varName: "rate", policy: math_config.MissingAsError, wantErr: internal.ErrUndefinedVariable},
+		{name: "按0计算", varName: "rate", policy: math_config.MissingAsZero, want: decimal.Zero},
+		{name: "按空值计算", varName: "rate", policy: math_config.MissingAsNull, wantErr: internal.ErrNullResult},
+		{name: "默认值优先于策略", varName: "rate", policy: math_config.MissingAsNull, defaults: defaults, want: decimal.RequireFromString("0.5")},
+		{name: "没有默认值时按策略", varName: "qty", policy: math_config.MissingAsZero, defaults: defaults, want: decimal.Zero},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := math_config.NewDefaultCalcConfig()
+			config.MissingVariables = tt.policy
+			config.VariableDefaults = tt.defaults
+			got, err := (&VariableNode{VarName: tt.varName}).Eval(context.Background(), nil, config)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("VariableNode.Eval() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("VariableNode.Eval() error = %v", err)
+			}
+			if !got.Equal(tt.want) {
+				t.Errorf("VariableNode.Eval() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,41 @@
+package math_node
+
+// Children 返回节点的直接子节点，按书写顺序排列
+func Children(node Node) []Node {
+	switch n := node.(type) {
+	case *UnaryOpNode:
+		return []Node{n.Operand}
+	case *BinaryOpNode:
+		return []Node{n.Left, n.Right}
+	case *FunctionNode:
+		return n.Args
+	}
+	return nil
+}
+
+// Walk 以先序方式遍历表达式树，fn 返回 false 时不再访问该节点的子节点
+func Walk(node Node, fn func(Node) bool) {
+	if node == nil || !fn(node) {
+		return
+	}
+	for _, child := range Children(node) {
+		Walk(child, fn)
+	}
+}
+
+// Position 返回节点在原始表达式中的位置
+func Position(node Node) int {
+	switch n := node.(type) {
+	case *NumberNode:
+		return n.Pos
+	case *VariableNode:
+		return n.Pos
+	case *UnaryOpNode:
+		return n.Pos
+	case *BinaryOpNode:
+		return n.Pos
+	case *FunctionNode:
+		return n.Pos
+	}
+	return 0
+}
@@ -0,0 +1,42 @@
+package math_node
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestWalk(t *testing.T) {
+	x := &VariableNode{VarName: "x"}
+	y := &VariableNode{VarName: "y"}
+	one := &NumberNode{Value: decimal.NewFromInt(1)}
+	fn := &FunctionNode{FuncName: "max", Args: []Node{y, one}}
+	root := &BinaryOpNode{Left: &UnaryOpNode{Operator: "-", Operand: x}, Operator: "+", Right: fn}
+
+	var visited []string
+	Walk(root, func(n Node) bool {
+		visited = append(visited, Format(n))
+		return true
+	})
+	want := []string{"-x + max(y, 1)", "-x", "x", "max(y, 1)", "y", "1"}
+	if !reflect.DeepEqual(visited, want) {
+		t.Errorf("Walk() visited %v, want %v", visited, want)
+	}
+
+	// 返回 false 时跳过子节点
+	visited = nil
+	Walk(root, func(n Node) bool {
+		visited = append(visited, Format(n))
+		_, isFunc := n.(*FunctionNode)
+		return !isFunc
+	})
+	want = []string{"-x + max(y, 1)", "-x", "x", "max(y, 1)"}
+	if !reflect.DeepEqual(visited, want) {
+		t.Errorf("Walk() visited %v, want %v", visited, want)
+	}
+
+	if got := Children(one); got != nil {
+		t.Errorf("Children(NumberNode) = %v, want nil", got)
+	}
+}
@@ -0,0 +1,49 @@
+package math_utils
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ZHOUXING1997/math_calculation/internal"
+	"github.com/ZHOUXING1997/math_calculation/math_config"
+)
+
+// callerKey 上下文中调用方上下文的键
+type callerKey struct{}
+
+// EvalContext 从调用方的上下文派生一次计算使用的上下文
+// 附加配置的超时时间、运算次数预算和变量查询缓存，并记录调用方的上下文，用于区分调用方取消和配置的超时
+func EvalContext(parent context.Context, config *math_config.CalcConfig) (context.Context, context.CancelFunc) {
+	ctx, cancel := BatchContext(parent, config)
+	return RowContext(ctx, config), cancel
+}
+
+// BatchContext 从调用方的上下文派生一批计算共用的上下文，只附加配置的超时时间并记录调用方的上下文
+// 每一行计算使用 RowContext 派生的上下文，超时时间对整批计算生效
+func BatchContext(parent context.Context, config *math_config.CalcConfig) (context.Context, context.CancelFunc) {
+	if parent == nil {
+		parent = context.Background()
+	}
+	ctx, cancel := context.WithTimeout(parent, config.Timeout)
+	return context.WithValue(ctx, callerKey{}, parent), cancel
+}
+
+// RowContext 从 BatchContext 返回的上下文派生一行计算使用的上下文，附加运算次数预算和变量查询缓存
+func RowContext(ctx context.Context, config *math_config.CalcConfig) context.Context {
+	return memoize(WithBudget(ctx, config))
+}
+
+// ContextError 返回上下文结束的原因
+// 调用方取消或调用方的截止时间到达时返回同时包装了 ErrCanceled 和 ctx.Err() 的错误，
+// 其他情况（配置的超时时间到达）返回 ErrExecutionTimeout
+func ContextError(ctx context.Context) error {
+	if caller, ok := ctx.Value(callerKey{}).(context.Context); ok && caller.Err() != nil {
+		return Canceled(caller)
+	}
+	return internal.ErrExecutionTimeout
+}
+
+// Canceled 返回调用方的上下文 ctx 结束时的错误，同时包装了 ErrCanceled 和 ctx.Err()
+func Canceled(ctx context.Context) error {
+	return fmt.Errorf("%w: %w", internal.ErrCanceled, ctx.Err())
+}
@@ -0,0 +1,49 @@
+package math_utils
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ZHOUXING1997/math_calculation/internal"
+	"github.com/ZHOUXING1997/math_calculation/math_config"
+)
+
+func TestContextError(t *testing.T) {
+	config := math_config.NewDefaultCalcConfig()
+
+	// 调用方取消
+	parent, cancel := context.WithCancel(context.Background())
+	ctx, cancelEval := EvalContext(parent, config)
+	defer cancelEval()
+	cancel()
+	<-ctx.Done()
+	if err := ContextError(ctx); !errors.Is(err, internal.ErrCanceled) || !errors.Is(err, context.Canceled) {
+		t.Errorf("ContextError() = %v, want %v", err, internal.ErrCanceled)
+	}
+
+	// 配置的超时
+	config.Timeout = time.Millisecond
+	ctx, cancelEval = EvalContext(context.Background(), config)
+	defer cancelEval()
+	<-ctx.Done()
+	if err := ContextError(ctx); err != internal.ErrExecutionTimeout {
+		t.Errorf("ContextError() = %v, want %v", err, internal.ErrExecutionTimeout)
+	}
+
+	// 没有记录调用方上下文时按超时处理
+	plain, cancelPlain := context.WithCancel(context.Background())
+	cancelPlain()
+	if err := ContextError(plain); err != internal.ErrExecutionTimeout {
+		t.Errorf("ContextError() = %v, want %v", err, internal.ErrExecutionTimeout)
+	}
+
+	// 附加运算次数预算
+	config.MaxOperations = 1
+	ctx, cancelEval = EvalContext(nil, config)
+	defer cancelEval()
+	if err := Spend(ctx, 2); err == nil {
+		t.Errorf("Spend() error = nil, want operations limit")
+	}
+}
@@ -0,0 +1,165 @@
+package math_utils
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// IsIdentifierStart 判断字符是否可以作为普通名称的开头（Unicode 字母或下划线）
+func IsIdentifierStart(r rune) bool {
+	return r == '_' || unicode.IsLetter(r)
+}
+
+// IsIdentifierRune 判断字符是否可以出现在普通名称中（Unicode 字母、数字或下划线）
+func IsIdentifierRune(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// IsSegmentStart 判断 input[pos:] 是否是路径中一段的开头：普通名称、反引号或方括号
+func IsSegmentStart(input string, pos int) bool {
+	if pos >= len(input) {
+		return false
+	}
+	if input[pos] == '`' || input[pos] == '[' {
+		return true
+	}
+	r, _ := utf8.DecodeRuneInString(input[pos:])
+	return IsIdentifierStart(r)
+}
+
+// ScanIdentifier 从 input[pos:] 读取一个标识符，返回结束位置和路径的各段
+// 标识符由一段或多段组成，段可以是：
+//   - 普通名称：Unicode 字母或下划线开头，之后是字母、数字或下划线，如 price、单价
+//   - 反引号引用的名称，可以包含空格等任意字符，如 `unit price`
+//   - 方括号引用的名称或下标，如 [单价]、[0]
+//
+// 段之间用点号分隔，方括号段之前的点号可以省略，如 order.total、item[0].price
+// quoted 表示是否使用了引用或下标；引用没有结束或为空时 ok 为 false，end 为出错片段的结束位置
+func ScanIdentifier(input string, pos int) (end int, segments []string, quoted bool, ok bool) {
+	for {
+		segment, next, segmentQuoted, segmentOK := scanSegment(input, pos)
+		if !segmentOK {
+			return next, nil, true, false
+		}
+		segments = append(segments, segment)
+		quoted = quoted || segmentQuoted
+		pos = next
+
+		switch {
+		case pos < len(input) && input[pos] == '[':
+			// 方括号段之前的点号可以省略
+		case pos+1 < len(input) && input[pos] == '.' && IsSegmentStart(input, pos+1):
+			pos++
+		default:
+			return pos, segments, quoted, true
+		}
+	}
+}
+
+// scanSegment 从 input[pos:] 读取路径中的一段
+func scanSegment(input string, pos int) (segment string, end int, quoted bool, ok bool) {
+	if pos >= len(input) {
+		return "", pos, false, false
+	}
+
+	switch input[pos] {
+	case '`', '[':
+		closing := byte('`')
+		if input[pos] == '[' {
+			closing = ']'
+		}
+		i := strings.IndexByte(input[pos+1:], closing)
+		if i < 0 {
+			return "", len(input), true, false
+		}
+		segment = input[pos+1 : pos+1+i]
+		end = pos + i + 2
+		return segment, end, true, segment != ""
+	}
+
+	end = pos
+	for end < len(input) {
+		r, size := utf8.DecodeRuneInString(input[end:])
+		if (end == pos && !IsIdentifierStart(r)) || !IsIdentifierRune(r) {
+			break
+		}
+		end += size
+	}
+	return input[pos:end], end, false, end > pos
+}
+
+// ParseIdentifier 解析完整的标识符，返回变量名和路径
+// 只有一段时路径为 nil；变量名由各段组成，全是数字的段写作下标，如 item[0].price
+func ParseIdentifier(raw string) (name string, path []string, ok bool) {
+	end, segments, _, ok := ScanIdentifier(raw, 0)
+	if !ok || end != len(raw) {
+		return raw, nil, false
+	}
+	if len(segments) == 1 {
+		return segments[0], nil, true
+	}
+	return JoinPath(segments), segments, true
+}
+
+// JoinPath 将路径的各段连接为变量名，全是数字的段写作下标，其他段用点号分隔
+func JoinPath(segments []string) string {
+	var sb strings.Builder
+	for i, segment := range segments {
+		if i > 0 && isIndex(segment) {
+			sb.WriteString("[" + segment + "]")
+			continue
+		}
+		if i > 0 {
+			sb.WriteByte('.')
+		}
+		sb.WriteString(segment)
+	}
+	return sb.String()
+}
+
+// FormatIdentifier 将变量名和路径格式化为可以重新解析的标识符
+// 不是普通名称的段用反引号引用，包含反引号的段用方括号引用
+func FormatIdentifier(name string, path []string) string {
+	if path == nil {
+		path = []string{name}
+	}
+	var sb strings.Builder
+	for i, segment := range path {
+		switch {
+		case i > 0 && isIndex(segment):
+			sb.WriteString("[" + segment + "]")
+			continue
+		case i > 0:
+			sb.WriteByte('.')
+		}
+		switch {
+		case isPlainName(segment):
+			sb.WriteString(segment)
+		case strings.IndexByte(segment, '`') >= 0:
+			sb.WriteString("[" + segment + "]")
+		default:
+			sb.WriteString("`" + segment + "`")
+		}
+	}
+	return sb.String()
+}
+
+// isPlainName 判断字符串是否是不需要引用的普通名称
+func isPlainName(s string) bool {
+	_, end, quoted, ok := scanSegment(s, 0)
+	return ok && !quoted && end == len(s)
+}
+
+// isIndex 判断路径的一段是否是下标
+func isIndex(segment string) bool {
+	if segment == "" {
+		return false
+	}
+	for i := 0; i < len(segment); i++ {
+		if !IsDigit(segment[i]) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,91 @@
+package math_utils
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestScanIdentifier(t *testing.T) {
+	tests := []struct {
+		name         string
+		input        string
+		pos          int
+		wantEnd      int
+		wantSegments []string
+		wantQuoted   bool
+		wantOK       bool
+	}{
+		{name: "普通名称", input: "price * 2", wantEnd: 5, wantSegments: []string{"price"}, wantOK: true},
+		{name: "Unicode名称", input: "单价+1", wantEnd: 6, wantSegments: []string{"单价"}, wantOK: true},
+		{name: "点号分隔的路径", input: "order.total", wantEnd: 11, wantSegments: []string{"order", "total"}, wantOK: true},
+		{name: "下标", input: "items[0].price", wantEnd: 14, wantSegments: []string{"items", "0", "price"}, wantQuoted: true, wantOK: true},
+		{name: "反引号引用", input: "`unit price`*2", wantEnd: 12, wantSegments: []string{"unit price"}, wantQuoted: true, wantOK: true},
+		{name: "方括号引用", input: "[单价]", wantEnd: 8, wantSegments: []string{"单价"}, wantQuoted: true, wantOK: true},
+		{name: "点号后不是名称", input: "a.5", wantEnd: 1, wantSegments: []string{"a"}, wantOK: true},
+		{name: "从中间开始", input: "1+b.c", pos: 2, wantEnd: 5, wantSegments: []string{"b", "c"}, wantOK: true},
+		{name: "没有结束的引用", input: "a.`b c", wantEnd: 6, wantQuoted: true},
+		{name: "空的引用", input: "[]+1", wantEnd: 2, wantQuoted: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			end, segments, quoted, ok := ScanIdentifier(tt.input, tt.pos)
+			if end != tt.wantEnd || !reflect.DeepEqual(segments, tt.wantSegments) || quoted != tt.wantQuoted || ok != tt.wantOK {
+				t.Errorf("ScanIdentifier() = %d, %q, %v, %v, want %d, %q, %v, %v",
+					end, segments, quoted, ok, tt.wantEnd, tt.wantSegments, tt.wantQuoted, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestParseIdentifier(t *testing.T) {
+	tests := []struct {
+		name     string
+		raw      string
+		wantName string
+		wantPath []string
+		wantOK   bool
+	}{
+		{name: "普通名称", raw: "x", wantName: "x", wantOK: true},
+		{name: "引用的单段名称", raw: "`unit price`", wantName: "unit price", wantOK: true},
+		{name: "路径", raw: "order.items[0].price", wantName: "order.items[0].price", wantPath: []string{"order", "items", "0", "price"}, wantOK: true},
+		{name: "引用的路径", raw: "[订单].`unit price`", wantName: "订单.unit price", wantPath: []string{"订单", "unit price"}, wantOK: true},
+		{name: "不是完整的标识符", raw: "a+b", wantName: "a+b"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			name, path, ok := ParseIdentifier(tt.raw)
+			if name != tt.wantName || !reflect.DeepEqual(path, tt.wantPath) || ok != tt.wantOK {
+				t.Errorf("ParseIdentifier() = %q, %q, %v, want %q, %q, %v", name, path, ok, tt.wantName, tt.wantPath, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestFormatIdentifier(t *testing.T) {
+	tests := []struct {
+		name    string
+		varName string
+		path    []string
+		want    string
+	}{
+		{name: "普通名称", varName: "price", want: "price"},
+		{name: "需要引用的名称", varName: "unit price", want: "`unit price`"},
+		{name: "包含反引号的名称", varName: "a`b", want: "[a`b]"},
+		{name: "路径和下标", varName: "order.items[0].unit price", path: []string{"order", "items", "0", "unit price"}, want: "order.items[0].`unit price`"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := FormatIdentifier(tt.varName, tt.path)
+			if got != tt.want {
+				t.Errorf("FormatIdentifier() = %q, want %q", got, tt.want)
+			}
+			// 格式化的结果可以重新解析
+			if name, path, ok := ParseIdentifier(got); !ok || name != tt.varName || !reflect.DeepEqual(path, tt.path) {
+				t.Errorf("ParseIdentifier(%q) = %q, %q, %v", got, name, path, ok)
+			}
+		})
+	}
+}
@@ -0,0 +1,89 @@
+package math_utils
+
+import (
+	"context"
+	"sync/atomic"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/ZHOUXING1997/math_calculation/internal"
+	"github.com/ZHOUXING1997/math_calculation/internal/math_func"
+	"github.com/ZHOUXING1997/math_calculation/math_config"
+)
+
+// budgetKey 上下文中运算次数预算的键
+type budgetKey struct{}
+
+// budget 一次计算的运算次数预算，同一次计算的所有节点共享
+type budget struct {
+	max  int64
+	used int64
+}
+
+// WithBudget 返回带有运算次数预算的上下文，每次计算开始时调用一次
+// config.MaxOperations 为 0 时不限制，直接返回 ctx
+func WithBudget(ctx context.Context, config *math_config.CalcConfig) context.Context {
+	if config == nil || config.MaxOperations <= 0 {
+		return ctx
+	}
+	return context.WithValue(ctx, budgetKey{}, &budget{max: config.MaxOperations})
+}
+
+// Spend 消耗 n 次运算，超过预算时返回 LimitOperations 错误；上下文中没有预算时不限制
+func Spend(ctx context.Context, n int64) error {
+	b, ok := ctx.Value(budgetKey{}).(*budget)
+	if !ok {
+		return nil
+	}
+	if atomic.AddInt64(&b.used, n) > b.max {
+		return &internal.ResourceLimitError{Limit: internal.LimitOperations, Max: b.max}
+	}
+	return nil
+}
+
+// CheckDigits 检查数字的位数是否超过 config.MaxDigits，为 0 时不限制
+func CheckDigits(value decimal.Decimal, config *math_config.CalcConfig) error {
+	if config.MaxDigits > 0 && math_func.Digits(value) > config.MaxDigits {
+		return &internal.ResourceLimitError{Limit: internal.LimitDigits, Max: int64(config.MaxDigits)}
+	}
+	return nil
+}
+
+// CheckExponent 检查幂运算指数的绝对值是否超过 config.MaxExponent，为 0 时不限制
+func CheckExponent(exponent decimal.Decimal, config *math_config.CalcConfig) error {
+	if config.MaxExponent > 0 && exponent.Abs().GreaterThan(decimal.NewFromInt(config.MaxExponent)) {
+		return &internal.ResourceLimitError{Limit: internal.LimitExponent, Max: config.MaxExponent}
+	}
+	return nil
+}
+
+// MaxIterations 返回迭代算法的最大迭代次数，未设置时使用默认值
+func MaxIterations(config *math_config.CalcConfig) int {
+	if config.MaxIterations > 0 {
+		return config.MaxIterations
+	}
+	return math_config.DefaultMaxIterations
+}
+
+// Guard 返回在乘方和迭代计算的每一步检查超时、运算次数和中间结果位数的函数
+func Guard(ctx context.Context, config *math_config.CalcConfig) math_func.Guard {
+	return func(value decimal.Decimal) error {
+		select {
+		case <-ctx.Done():
+			return ContextError(ctx)
+		default:
+		}
+		if err := Spend(ctx, 1); err != nil {
+			return err
+		}
+		return CheckDigits(value, config)
+	}
+}
+
+// LimitError 为资源限制错误补充出错的位置和片段，其他错误原样返回
+func LimitError(err error, pos int, token string) error {
+	if limitErr, ok := err.(*internal.ResourceLimitError); ok {
+		return limitErr.At(pos, token)
+	}
+	return err
+}
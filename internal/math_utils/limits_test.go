@@ -0,0 +1,127 @@
+package math_utils
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/ZHOUXING1997/math_calculation/internal"
+	"github.com/ZHOUXING1997/math_calculation/math_config"
+)
+
+func TestSpend(t *testing.T) {
+	tests := []struct {
+		name          string
+		maxOperations int64
+		spend         int
+		wantErr       bool
+	}{
+		{name: "不限制", maxOperations: 0, spend: 1000},
+		{name: "未超过预算", maxOperations: 10, spend: 10},
+		{name: "超过预算", maxOperations: 10, spend: 11, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := WithBudget(context.Background(), &math_config.CalcConfig{MaxOperations: tt.maxOperations})
+			var err error
+			for i := 0; i < tt.spend && err == nil; i++ {
+				err = Spend(ctx, 1)
+			}
+			if tt.wantErr {
+				var limitErr *internal.ResourceLimitError
+				if !errors.As(err, &limitErr) || limitErr.Limit != internal.LimitOperations || limitErr.Max != tt.maxOperations {
+					t.Errorf("Spend() error = %v, want operations limit %d", err, tt.maxOperations)
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("Spend() error = %v", err)
+			}
+		})
+	}
+}
+
+func TestCheckLimits(t *testing.T) {
+	config := &math_config.CalcConfig{MaxDigits: 5, MaxExponent: 100}
+	tests := []struct {
+		name      string
+		check     func() error
+		wantLimit internal.ResourceLimit
+	}{
+		{name: "位数未超过限制", check: func() error { return CheckDigits(decimal.NewFromInt(12345), config) }},
+		{name: "位数超过限制", check: func() error { return CheckDigits(decimal.NewFromInt(123456), config) }, wantLimit: internal.LimitDigits},
+		{name: "小数位数超过限制", check: func() error { return CheckDigits(decimal.RequireFromString("0.000001"), config) }, wantLimit: internal.LimitDigits},
+		{name: "指数未超过限制", check: func() error { return CheckExponent(decimal.NewFromInt(-100), config) }},
+		{name: "指数超过限制", check: func() error { return CheckExponent(decimal.NewFromInt(101), config) }, wantLimit: internal.LimitExponent},
+		{name: "负指数超过限制", check: func() error { return CheckExponent(decimal.NewFromInt(-101), config) }, wantLimit: internal.LimitExponent},
+		{name: "不限制位数", check: func() error { return CheckDigits(decimal.New(1, 100000), &math_config.CalcConfig{}) }},
+		{name: "不限制指数", check: func() error { return CheckExponent(decimal.New(1, 10), &math_config.CalcConfig{}) }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.check()
+			if tt.wantLimit == "" {
+				if err != nil {
+					t.Errorf("error = %v, want nil", err)
+				}
+				return
+			}
+			var limitErr *internal.ResourceLimitError
+			if !errors.As(err, &limitErr) || limitErr.Limit != tt.wantLimit {
+				t.Errorf("error = %v, want %s limit", err, tt.wantLimit)
+			}
+			if !errors.Is(err, internal.ErrResourceLimit) {
+				t.Errorf("errors.Is(err, ErrResourceLimit) = false")
+			}
+		})
+	}
+}
+
+func TestGuard(t *testing.T) {
+	config := &math_config.CalcConfig{MaxDigits: 5, MaxOperations: 2}
+
+	// 超过位数限制
+	guard := Guard(WithBudget(context.Background(), config), config)
+	var limitErr *internal.ResourceLimitError
+	if err := guard(decimal.NewFromInt(123456)); !errors.As(err, &limitErr) || limitErr.Limit != internal.LimitDigits {
+		t.Errorf("Guard() error = %v, want digits limit", err)
+	}
+
+	// 超过运算次数
+	guard = Guard(WithBudget(context.Background(), config), config)
+	_ = guard(decimal.NewFromInt(1))
+	_ = guard(decimal.NewFromInt(1))
+	if err := guard(decimal.NewFromInt(1)); !errors.As(err, &limitErr) || limitErr.Limit != internal.LimitOperations {
+		t.Errorf("Guard() error = %v, want operations limit", err)
+	}
+
+	// 上下文已取消
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := Guard(ctx, config)(decimal.NewFromInt(1)); !errors.Is(err, internal.ErrExecutionTimeout) {
+		t.Errorf("Guard() error = %v, want %v", err, internal.ErrExecutionTimeout)
+	}
+}
+
+func TestLimitError(t *testing.T) {
+	err := LimitError(&internal.ResourceLimitError{Limit: internal.LimitExponent, Max: 10}, 3, "^")
+	var parseErr *internal.ParseError
+	if !errors.As(err, &parseErr) {
+		t.Fatalf("LimitError() = %T, want *ParseError", err)
+	}
+	if parseErr.Pos != 3 || parseErr.Token != "^" {
+		t.Errorf("LimitError() Pos = %d, Token = %q", parseErr.Pos, parseErr.Token)
+	}
+	if !errors.Is(err, internal.ErrResourceLimit) {
+		t.Errorf("errors.Is(err, ErrResourceLimit) = false")
+	}
+
+	other := errors.New("other")
+	if got := LimitError(other, 3, "^"); got != other {
+		t.Errorf("LimitError() = %v, want %v", got, other)
+	}
+}
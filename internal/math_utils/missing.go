@@ -0,0 +1,44 @@
+package math_utils
+
+import (
+	"context"
+	"errors"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/ZHOUXING1997/math_calculation/internal"
+)
+
+// strictKey 上下文中严格变量检查的键
+type strictKey struct{}
+
+// WithStrictVariables 返回缺失的变量不按 0 计算的上下文，用于 coalesce 和 isdefined 判断变量是否有值
+// 变量的默认值仍然有效
+func WithStrictVariables(ctx context.Context) context.Context {
+	if StrictVariables(ctx) {
+		return ctx
+	}
+	return context.WithValue(ctx, strictKey{}, true)
+}
+
+// StrictVariables 判断上下文是否要求严格变量检查
+func StrictVariables(ctx context.Context) bool {
+	strict, _ := ctx.Value(strictKey{}).(bool)
+	return strict
+}
+
+// IsMissing 判断错误是否由变量未定义或值为空引起
+func IsMissing(err error) bool {
+	return errors.Is(err, internal.ErrUndefinedVariable) || errors.Is(err, internal.ErrNullResult)
+}
+
+// Nullable 将计算结果转换为可为空的值，结果为空（ErrNullResult）时返回无效值和 nil 错误
+func Nullable(result decimal.Decimal, err error) (decimal.NullDecimal, error) {
+	if err != nil {
+		if errors.Is(err, internal.ErrNullResult) {
+			return decimal.NullDecimal{}, nil
+		}
+		return decimal.NullDecimal{}, err
+	}
+	return decimal.NewNullDecimal(result), nil
+}
@@ -0,0 +1,73 @@
+package math_utils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/ZHOUXING1997/math_calculation/internal"
+)
+
+func TestStrictVariables(t *testing.T) {
+	ctx := context.Background()
+	if StrictVariables(ctx) {
+		t.Errorf("StrictVariables(Background) = true, want false")
+	}
+	strict := WithStrictVariables(ctx)
+	if !StrictVariables(strict) {
+		t.Errorf("StrictVariables(WithStrictVariables) = false, want true")
+	}
+	if WithStrictVariables(strict) != strict {
+		t.Errorf("WithStrictVariables() should reuse a strict context")
+	}
+}
+
+func TestIsMissing(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{name: "未定义的变量", err: internal.NewParseError(0, internal.ErrUndefinedVariable, "x", internal.MsgUndefinedVariable, "x"), want: true},
+		{name: "为空的变量", err: fmt.Errorf("wrapped: %w", internal.ErrNullResult), want: true},
+		{name: "其他错误", err: internal.ErrDivisionByZero},
+		{name: "没有错误"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsMissing(tt.err); got != tt.want {
+				t.Errorf("IsMissing() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNullable(t *testing.T) {
+	tests := []struct {
+		name      string
+		result    decimal.Decimal
+		err       error
+		want      decimal.NullDecimal
+		wantError error
+	}{
+		{name: "有值", result: decimal.NewFromInt(3), want: decimal.NewNullDecimal(decimal.NewFromInt(3))},
+		{name: "结果为空", err: internal.NewParseError(0, internal.ErrNullResult, "x", internal.MsgNullVariable, "x")},
+		{name: "其他错误", err: internal.ErrDivisionByZero, wantError: internal.ErrDivisionByZero},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Nullable(tt.result, tt.err)
+			if !errors.Is(err, tt.wantError) || (tt.wantError == nil && err != nil) {
+				t.Fatalf("Nullable() error = %v, want %v", err, tt.wantError)
+			}
+			if got.Valid != tt.want.Valid || !got.Decimal.Equal(tt.want.Decimal) {
+				t.Errorf("Nullable() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
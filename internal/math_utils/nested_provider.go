@@ -0,0 +1,104 @@
+package math_utils
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/ZHOUXING1997/math_calculation/internal"
+)
+
+// NestedMapProvider 从嵌套的 map 和切片中按路径获取变量，如 order.items[0].price
+// 中间节点可以是 map[string]interface{}、map[string]decimal.Decimal、[]interface{} 或 []decimal.Decimal，
+// 叶子节点可以是 decimal.Decimal、整数、浮点数、数字字符串或 json.Number，可以直接使用 json.Unmarshal 得到的数据
+type NestedMapProvider map[string]interface{}
+
+// Lookup 实现 VariableProvider 接口，按只有一段的路径获取
+func (m NestedMapProvider) Lookup(ctx context.Context, name string) (decimal.Decimal, bool, error) {
+	return m.LookupPath(ctx, []string{name})
+}
+
+// LookupPath 实现 PathProvider 接口，路径不存在时返回 false，叶子节点不是数字时返回错误
+func (m NestedMapProvider) LookupPath(_ context.Context, path []string) (decimal.Decimal, bool, error) {
+	var current interface{} = map[string]interface{}(m)
+	for _, segment := range path {
+		var ok bool
+		switch node := current.(type) {
+		case map[string]interface{}:
+			current, ok = node[segment]
+		case NestedMapProvider:
+			current, ok = node[segment]
+		case map[string]decimal.Decimal:
+			current, ok = node[segment]
+		case []interface{}:
+			var i int
+			if i, ok = index(segment, len(node)); ok {
+				current = node[i]
+			}
+		case []decimal.Decimal:
+			var i int
+			if i, ok = index(segment, len(node)); ok {
+				current = node[i]
+			}
+		}
+		if !ok {
+			return decimal.Zero, false, nil
+		}
+	}
+	return toDecimal(current, path)
+}
+
+// index 将路径的一段解析为切片下标
+func index(segment string, length int) (int, bool) {
+	i, err := strconv.Atoi(segment)
+	if err != nil || i < 0 || i >= length {
+		return 0, false
+	}
+	return i, true
+}
+
+// toDecimal 将叶子节点的值转换为 decimal，值为 nil 时返回 false
+func toDecimal(value interface{}, path []string) (decimal.Decimal, bool, error) {
+	switch v := value.(type) {
+	case nil:
+		return decimal.Zero, false, nil
+	case decimal.Decimal:
+		return v, true, nil
+	case *decimal.Decimal:
+		if v == nil {
+			return decimal.Zero, false, nil
+		}
+		return *v, true, nil
+	case int:
+		return decimal.NewFromInt(int64(v)), true, nil
+	case int32:
+		return decimal.NewFromInt32(v), true, nil
+	case int64:
+		return decimal.NewFromInt(v), true, nil
+	case uint32:
+		return decimal.NewFromInt(int64(v)), true, nil
+	case float32:
+		if math.IsNaN(float64(v)) || math.IsInf(float64(v), 0) {
+			break
+		}
+		return decimal.NewFromFloat32(v), true, nil
+	case float64:
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			break
+		}
+		return decimal.NewFromFloat(v), true, nil
+	case json.Number:
+		if d, err := decimal.NewFromString(v.String()); err == nil {
+			return d, true, nil
+		}
+	case string:
+		if d, err := decimal.NewFromString(strings.TrimSpace(v)); err == nil {
+			return d, true, nil
+		}
+	}
+	return decimal.Zero, false, internal.NewParseError(0, internal.ErrInvalidArgument, "", internal.MsgPathNotNumber, JoinPath(path), value)
+}
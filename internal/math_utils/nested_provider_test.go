@@ -0,0 +1,70 @@
+package math_utils
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/ZHOUXING1997/math_calculation/internal"
+)
+
+func TestNestedMapProvider(t *testing.T) {
+	var data map[string]interface{}
+	decoder := json.NewDecoder(strings.NewReader(`{"order": {"items": [{"price": 12.5, "qty": "3"}], "discount": null, "note": "vip"}}`))
+	decoder.UseNumber()
+	if err := decoder.Decode(&data); err != nil {
+		t.Fatal(err)
+	}
+	provider := NestedMapProvider(data)
+	provider["单价"] = decimal.NewFromInt(7)
+	provider["rates"] = map[string]decimal.Decimal{"tax": decimal.RequireFromString("0.13")}
+	provider["levels"] = []decimal.Decimal{decimal.NewFromInt(1), decimal.NewFromInt(2)}
+	provider["ratio"] = 0.5
+
+	tests := []struct {
+		name      string
+		path      []string
+		want      decimal.Decimal
+		wantFound bool
+		wantErr   error
+	}{
+		{name: "单段路径", path: []string{"单价"}, want: decimal.NewFromInt(7), wantFound: true},
+		{name: "浮点数", path: []string{"ratio"}, want: decimal.NewFromFloat(0.5), wantFound: true},
+		{name: "json.Number", path: []string{"order", "items", "0", "price"}, want: decimal.NewFromFloat(12.5), wantFound: true},
+		{name: "数字字符串", path: []string{"order", "items", "0", "qty"}, want: decimal.NewFromInt(3), wantFound: true},
+		{name: "decimal map", path: []string{"rates", "tax"}, want: decimal.RequireFromString("0.13"), wantFound: true},
+		{name: "decimal 切片", path: []string{"levels", "1"}, want: decimal.NewFromInt(2), wantFound: true},
+		{name: "下标越界", path: []string{"order", "items", "1", "price"}},
+		{name: "下标不是数字", path: []string{"order", "items", "x"}},
+		{name: "键不存在", path: []string{"order", "total"}},
+		{name: "值为null", path: []string{"order", "discount"}},
+		{name: "路径经过数字", path: []string{"ratio", "x"}},
+		{name: "值不是数字", path: []string{"order", "note"}, wantErr: internal.ErrInvalidArgument},
+		{name: "值是对象", path: []string{"order"}, wantErr: internal.ErrInvalidArgument},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, found, err := provider.LookupPath(context.Background(), tt.path)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("NestedMapProvider.LookupPath() error = %v, want %v", err, tt.wantErr)
+			}
+			if found != tt.wantFound || !got.Equal(tt.want) {
+				t.Errorf("NestedMapProvider.LookupPath() = %v, %v, want %v, %v", got, found, tt.want, tt.wantFound)
+			}
+		})
+	}
+
+	if got, found, err := provider.Lookup(context.Background(), "单价"); !found || err != nil || !got.Equal(decimal.NewFromInt(7)) {
+		t.Errorf("NestedMapProvider.Lookup() = %v, %v, %v", got, found, err)
+	}
+	// 值不是数字的错误可以按语言输出
+	_, _, err := provider.LookupPath(context.Background(), []string{"order", "note"})
+	if got := internal.Localize(err, internal.LocaleEn).Error(); got != "position 0: order.note has value vip, which is not a number: invalid argument" {
+		t.Errorf("NestedMapProvider.LookupPath() error = %q", got)
+	}
+}
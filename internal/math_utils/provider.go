@@ -0,0 +1,125 @@
+package math_utils
+
+import (
+	"context"
+	"strings"
+	"sync"
+
+	"github.com/shopspring/decimal"
+)
+
+// VariableProvider 变量提供者，计算时按名称延迟获取变量的值
+// 可以从结构体、数据库或缓存中取值，只有表达式实际用到的变量才会被查询
+type VariableProvider interface {
+	// Lookup 返回变量的值，变量不存在时返回 false
+	Lookup(ctx context.Context, name string) (decimal.Decimal, bool, error)
+}
+
+// PathProvider 可以按路径获取变量的变量提供者，如从嵌套的 map 中获取 order.items[0].price
+// 变量有多段路径时优先调用 LookupPath，否则调用 Lookup
+type PathProvider interface {
+	VariableProvider
+	// LookupPath 按路径的各段返回变量的值，变量不存在时返回 false
+	LookupPath(ctx context.Context, path []string) (decimal.Decimal, bool, error)
+}
+
+// MapProvider 使用 map 提供变量
+type MapProvider map[string]decimal.Decimal
+
+// Lookup 实现 VariableProvider 接口
+func (m MapProvider) Lookup(_ context.Context, name string) (decimal.Decimal, bool, error) {
+	val, ok := m[name]
+	return val, ok, nil
+}
+
+// ProviderFunc 将函数适配为 VariableProvider
+type ProviderFunc func(ctx context.Context, name string) (decimal.Decimal, bool, error)
+
+// Lookup 实现 VariableProvider 接口
+func (f ProviderFunc) Lookup(ctx context.Context, name string) (decimal.Decimal, bool, error) {
+	return f(ctx, name)
+}
+
+// providerKey 上下文中变量提供者的键
+type providerKey struct{}
+
+// memoKey 上下文中当前这次计算的缓存变量提供者的键
+type memoKey struct{}
+
+// lookupResult 一次查询的结果
+type lookupResult struct {
+	value decimal.Decimal
+	found bool
+}
+
+// memoProvider 在一次计算内缓存查询结果的变量提供者，同一个变量只查询一次
+type memoProvider struct {
+	provider VariableProvider
+	mutex    sync.Mutex
+	results  map[string]lookupResult
+}
+
+// lookup 查询变量并缓存结果，查询出错时不缓存
+func (m *memoProvider) lookup(ctx context.Context, name string, path []string) (decimal.Decimal, bool, error) {
+	key := name
+	if path != nil {
+		// 以 \x00 开头，与只有一段的变量名区分
+		key = "\x00" + strings.Join(path, "\x00")
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if result, ok := m.results[key]; ok {
+		return result.value, result.found, nil
+	}
+	val, ok, err := lookup(ctx, m.provider, name, path)
+	if err != nil {
+		return decimal.Zero, false, err
+	}
+	m.results[key] = lookupResult{value: val, found: ok}
+	return val, ok, nil
+}
+
+// lookup 从变量提供者获取变量，有多段路径且提供者支持路径时按路径获取
+func lookup(ctx context.Context, provider VariableProvider, name string, path []string) (decimal.Decimal, bool, error) {
+	if pathProvider, ok := provider.(PathProvider); ok && path != nil {
+		return pathProvider.LookupPath(ctx, path)
+	}
+	return provider.Lookup(ctx, name)
+}
+
+// WithProvider 返回带有变量提供者的上下文，provider 为 nil 时直接返回 ctx
+// 之后由 EvalContext 开始的每次计算都会在计算内缓存查询结果
+func WithProvider(ctx context.Context, provider VariableProvider) context.Context {
+	if provider == nil {
+		return ctx
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return context.WithValue(ctx, providerKey{}, provider)
+}
+
+// memoize 为上下文中的变量提供者创建这次计算使用的缓存
+func memoize(ctx context.Context) context.Context {
+	provider, ok := ctx.Value(providerKey{}).(VariableProvider)
+	if !ok {
+		return ctx
+	}
+	return context.WithValue(ctx, memoKey{}, &memoProvider{
+		provider: provider,
+		results:  make(map[string]lookupResult),
+	})
+}
+
+// LookupVariable 从上下文中的变量提供者获取变量的值，path 为变量路径的各段，只有一段时为 nil
+// 上下文中没有变量提供者时返回 false
+func LookupVariable(ctx context.Context, name string, path []string) (decimal.Decimal, bool, error) {
+	if memo, ok := ctx.Value(memoKey{}).(*memoProvider); ok {
+		return memo.lookup(ctx, name, path)
+	}
+	if provider, ok := ctx.Value(providerKey{}).(VariableProvider); ok {
+		return lookup(ctx, provider, name, path)
+	}
+	return decimal.Zero, false, nil
+}
@@ -0,0 +1,115 @@
+package math_utils
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/ZHOUXING1997/math_calculation/math_config"
+)
+
+func TestLookupVariable(t *testing.T) {
+	errLookup := errors.New("lookup failed")
+	calls := map[string]int{}
+	provider := ProviderFunc(func(_ context.Context, name string) (decimal.Decimal, bool, error) {
+		calls[name]++
+		switch name {
+		case "broken":
+			return decimal.Zero, false, errLookup
+		case "missing":
+			return decimal.Zero, false, nil
+		}
+		return decimal.NewFromInt(int64(len(name))), true, nil
+	})
+
+	ctx, cancel := EvalContext(WithProvider(context.Background(), provider), math_config.NewDefaultCalcConfig())
+	defer cancel()
+
+	tests := []struct {
+		name      string
+		variable  string
+		want      decimal.Decimal
+		wantFound bool
+		wantErr   error
+	}{
+		{name: "找到变量", variable: "abc", want: decimal.NewFromInt(3), wantFound: true},
+		{name: "再次查询使用缓存", variable: "abc", want: decimal.NewFromInt(3), wantFound: true},
+		{name: "变量不存在", variable: "missing"},
+		{name: "再次查询不存在的变量", variable: "missing"},
+		{name: "查询出错", variable: "broken", wantErr: errLookup},
+		{name: "出错不缓存", variable: "broken", wantErr: errLookup},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, found, err := LookupVariable(ctx, tt.variable, nil)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("LookupVariable() error = %v, want %v", err, tt.wantErr)
+			}
+			if found != tt.wantFound || !got.Equal(tt.want) {
+				t.Errorf("LookupVariable() = %v, %v, want %v, %v", got, found, tt.want, tt.wantFound)
+			}
+		})
+	}
+
+	if calls["abc"] != 1 || calls["missing"] != 1 || calls["broken"] != 2 {
+		t.Errorf("provider calls = %v, want abc:1 missing:1 broken:2", calls)
+	}
+
+	// 新的一次计算重新查询
+	ctx, cancel = EvalContext(WithProvider(context.Background(), provider), math_config.NewDefaultCalcConfig())
+	defer cancel()
+	_, _, _ = LookupVariable(ctx, "abc", nil)
+	if calls["abc"] != 2 {
+		t.Errorf("provider calls for abc = %d, want 2", calls["abc"])
+	}
+
+	// 没有变量提供者
+	if _, found, err := LookupVariable(context.Background(), "abc", nil); found || err != nil {
+		t.Errorf("LookupVariable() = %v, %v, want false, nil", found, err)
+	}
+}
+
+func TestMapProvider(t *testing.T) {
+	provider := MapProvider{"x": decimal.NewFromInt(1)}
+	if got, found, err := provider.Lookup(context.Background(), "x"); !found || err != nil || !got.Equal(decimal.NewFromInt(1)) {
+		t.Errorf("MapProvider.Lookup(x) = %v, %v, %v", got, found, err)
+	}
+	if _, found, _ := provider.Lookup(context.Background(), "y"); found {
+		t.Errorf("MapProvider.Lookup(y) found = true, want false")
+	}
+}
+
+func TestLookupVariable_Path(t *testing.T) {
+	calls := 0
+	provider := NestedMapProvider{"a": map[string]interface{}{"b": 2}, "a.b": 1}
+	counting := ProviderFunc(func(ctx context.Context, name string) (decimal.Decimal, bool, error) {
+		calls++
+		return provider.Lookup(ctx, name)
+	})
+
+	ctx, cancel := EvalContext(WithProvider(context.Background(), provider), math_config.NewDefaultCalcConfig())
+	defer cancel()
+
+	// 支持路径的提供者按路径获取，路径与同名的单段变量分别缓存
+	if got, _, _ := LookupVariable(ctx, "a.b", []string{"a", "b"}); !got.Equal(decimal.NewFromInt(2)) {
+		t.Errorf("LookupVariable(path) = %v, want 2", got)
+	}
+	if got, _, _ := LookupVariable(ctx, "a.b", nil); !got.Equal(decimal.NewFromInt(1)) {
+		t.Errorf("LookupVariable(name) = %v, want 1", got)
+	}
+
+	// 不支持路径的提供者按完整变量名获取
+	ctx, cancel = EvalContext(WithProvider(context.Background(), counting), math_config.NewDefaultCalcConfig())
+	defer cancel()
+	for i := 0; i < 2; i++ {
+		if got, _, _ := LookupVariable(ctx, "a.b", []string{"a", "b"}); !got.Equal(decimal.NewFromInt(1)) {
+			t.Errorf("LookupVariable() = %v, want 1", got)
+		}
+	}
+	if calls != 1 {
+		t.Errorf("provider calls = %d, want 1", calls)
+	}
+}
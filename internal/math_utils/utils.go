@@ -67,7 +67,7 @@ func CheckContext(ctx context.Context, maxDepth int) error {
 	// 检查上下文是否已取消
 	select {
 	case <-ctx.Done():
-		return internal.ErrExecutionTimeout
+		return ContextError(ctx)
 	default:
 	}
 
@@ -111,6 +111,11 @@ func IsAlpha(c byte) bool {
 	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
 }
 
+// IsSpace 判断字符是否是空白字符（空格、制表符、换行符、垂直制表符、换页符），与 strings.TrimSpace 处理的 ASCII 空白一致
+func IsSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\v' || c == '\f'
+}
+
 // IsDigit 判断字符是否是数字
 func IsDigit(c byte) bool {
 	return c >= '0' && c <= '9'
@@ -383,3 +383,26 @@ func TestIsDigit(t *testing.T) {
 		})
 	}
 }
+
+func TestIsSpace(t *testing.T) {
+	tests := []struct {
+		name string
+		c    byte
+		want bool
+	}{
+		{"空格", ' ', true},
+		{"制表符", '\t', true},
+		{"换行符", '\n', true},
+		{"回车符", '\r', true},
+		{"字母", 'a', false},
+		{"数字", '0', false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsSpace(tt.c); got != tt.want {
+				t.Errorf("IsSpace() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,395 @@
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// 内置的错误消息语言
+const (
+	LocaleZh = "zh" // 中文，默认语言
+	LocaleEn = "en" // 英文
+)
+
+// 消息键，格式为 "错误码.具体原因"；错误码本身也是消息键，对应错误原因的描述
+const (
+	MsgPosition   = "position"   // 错误消息的整体格式，参数为位置和消息
+	MsgDiagnostic = "diagnostic" // 诊断信息的标题，参数为行号、列号和消息
+	MsgSuggestion = "suggestion" // 诊断信息中的修改建议，参数为用逗号分隔的相近名称
+
+	MsgEmptyExpression           = "invalid_expression.empty"
+	MsgExpressionTooLong         = "invalid_expression.too_long"
+	MsgExpressionTooComplex      = "invalid_expression.too_complex"
+	MsgUnexpectedToken           = "invalid_expression.unexpected_token"
+	MsgUnexpectedEnd             = "invalid_expression.unexpected_end"
+	MsgInvalidNumber             = "invalid_expression.invalid_number"
+	MsgMissingRightParen         = "invalid_expression.missing_right_paren"
+	MsgFunctionMissingLeftParen  = "invalid_expression.function_missing_left_paren"
+	MsgFunctionMissingRightParen = "invalid_expression.function_missing_right_paren"
+	MsgInvalidJSON               = "invalid_expression.invalid_json"
+	MsgUnsupportedJSONVersion    = "invalid_expression.unsupported_json_version"
+	MsgUnserializableNode        = "invalid_expression.unserializable_node"
+	MsgJSONNodeNil               = "invalid_expression.json_node_nil"
+	MsgJSONNodeTooDeep           = "invalid_expression.json_node_too_deep"
+	MsgJSONNumberChildren        = "invalid_expression.json_number_children"
+	MsgJSONVariableInvalid       = "invalid_expression.json_variable_invalid"
+	MsgJSONUnaryChildren         = "invalid_expression.json_unary_children"
+	MsgJSONBinaryChildren        = "invalid_expression.json_binary_children"
+	MsgJSONFunctionName          = "invalid_expression.json_function_name"
+	MsgJSONUnknownKind           = "invalid_expression.json_unknown_kind"
+
+	MsgUndefinedVariable        = "undefined_variable.name"
+	MsgVariableLookup           = "variable_lookup.failed"
+	MsgNullVariable             = "null_result.variable"
+	MsgCircularReference        = "circular_reference.path"
+	MsgFormulaNotRegistered     = "formula_not_registered.name"
+	MsgDivisorZero              = "division_by_zero.divisor"
+	MsgUnsupportedUnaryOperator = "unsupported_operator.unary"
+	MsgUnsupportedOperator      = "unsupported_operator.binary"
+	MsgUnsupportedFunction      = "unsupported_operator.function"
+
+	MsgArgumentCountExact   = "invalid_argument.count_exact"
+	MsgArgumentCountRange   = "invalid_argument.count_range"
+	MsgArgumentCountMin     = "invalid_argument.count_min"
+	MsgNegativeSqrt         = "invalid_argument.negative_sqrt"
+	MsgInvalidDecimalPlaces = "invalid_argument.decimal_places"
+	MsgNonIntegerExponent   = "invalid_argument.non_integer_exponent"
+	MsgColumnLength         = "invalid_argument.column_length"
+	MsgEmptyFormulaName     = "invalid_argument.empty_formula_name"
+	MsgNameIsInput          = "invalid_argument.name_is_input"
+	MsgNameIsFormula        = "invalid_argument.name_is_formula"
+	MsgBindNotStruct        = "invalid_argument.bind_not_struct"
+	MsgNotFiniteNumber      = "invalid_argument.not_finite_number"
+	MsgInvalidNumberString  = "invalid_argument.invalid_number_string"
+	MsgPathNotNumber        = "invalid_argument.path_not_number"
+	MsgUnmarshalCompiled    = "invalid_argument.unmarshal_compiled"
+
+	MsgDeriveUnaryOperator = "not_differentiable.unary"
+	MsgDeriveNodeType      = "not_differentiable.node_type"
+	MsgDeriveOperator      = "not_differentiable.operator"
+	MsgDeriveExponent      = "not_differentiable.exponent"
+	MsgDeriveFunction      = "not_differentiable.function"
+
+	MsgExpressionLength       = "validation_failed.expression_length"
+	MsgNestedParentheses      = "validation_failed.nested_parentheses"
+	MsgUnbalancedParentheses  = "validation_failed.unbalanced_parentheses"
+	MsgFunctionNotAllowed     = "validation_failed.function_not_allowed"
+	MsgFunctionDisallowed     = "validation_failed.function_disallowed"
+	MsgFunctionArguments      = "validation_failed.function_arguments"
+	MsgVariablesNotAllowed    = "validation_failed.variables_not_allowed"
+	MsgVariableNameLength     = "validation_failed.variable_name_length"
+	MsgVariableNotAllowed     = "validation_failed.variable_not_allowed"
+	MsgRequiredVariable       = "validation_failed.required_variable"
+	MsgInvalidVariablePattern = "validation_failed.invalid_variable_pattern"
+	MsgNumberLength           = "validation_failed.number_length"
+	MsgOperatorCount          = "validation_failed.operator_count"
+	MsgNodeCount              = "validation_failed.node_count"
+
+	MsgExponentLimit  = "resource_limit.exponent"
+	MsgDigitLimit     = "resource_limit.digits"
+	MsgIterationLimit = "resource_limit.iterations"
+	MsgOperationLimit = "resource_limit.operations"
+
+	MsgPoolClosed = "canceled.pool_closed"
+
+	MsgPanic = "internal.panic"
+)
+
+// zhMessages 中文消息
+var zhMessages = map[string]string{
+	MsgPosition:   "位置 %d: %s",
+	MsgDiagnostic: "第 %d 行，第 %d 列: %s",
+	MsgSuggestion: "你是不是想输入: %s",
+
+	string(CodeDivisionByZero):       "除以零错误",
+	string(CodeUndefinedVariable):    "未定义的变量",
+	string(CodeUnsupportedOperator):  "不支持的运算符",
+	string(CodeInvalidExpression):    "无效的表达式",
+	string(CodeInvalidArgument):      "无效的参数",
+	string(CodeMaxRecursionDepth):    "超过最大递归深度",
+	string(CodeExecutionTimeout):     "执行超时",
+	string(CodeNotDifferentiable):    "无法求导",
+	string(CodeValidationFailed):     "表达式验证失败",
+	string(CodeInternal):             "内部错误",
+	string(CodeResourceLimit):        "超过资源限制",
+	string(CodeCanceled):             "计算被取消",
+	string(CodeNullResult):           "结果为空",
+	string(CodeCircularReference):    "公式存在循环引用",
+	string(CodeFormulaNotRegistered): "公式未注册",
+
+	MsgEmptyExpression:           "空表达式",
+	MsgExpressionTooLong:         "表达式过长",
+	MsgExpressionTooComplex:      "表达式复杂度过高",
+	MsgUnexpectedToken:           "意外的标记: %s",
+	MsgUnexpectedEnd:             "表达式意外结束",
+	MsgInvalidNumber:             "无效的数字: %s",
+	MsgMissingRightParen:         "缺少右括号",
+	MsgFunctionMissingLeftParen:  "函数 %s 后缺少左括号",
+	MsgFunctionMissingRightParen: "函数 %s 缺少右括号",
+	MsgInvalidJSON:               "无效的表达式 JSON",
+	MsgUnsupportedJSONVersion:    "不支持的表达式 JSON 版本: %d",
+	MsgUnserializableNode:        "无法序列化的节点类型: %T",
+	MsgJSONNodeNil:               "节点为空",
+	MsgJSONNodeTooDeep:           "节点嵌套过深",
+	MsgJSONNumberChildren:        "数字节点不能有子节点",
+	MsgJSONVariableInvalid:       "变量节点需要名称且不能有子节点",
+	MsgJSONUnaryChildren:         "一元运算符需要 1 个子节点，实际 %d 个",
+	MsgJSONBinaryChildren:        "二元运算符需要 2 个子节点，实际 %d 个",
+	MsgJSONFunctionName:          "函数节点需要名称",
+	MsgJSONUnknownKind:           "未知的节点类型: %s",
+
+	MsgUndefinedVariable:        "未定义的变量: %s",
+	MsgVariableLookup:           "获取变量 %s 的值失败: %v",
+	MsgNullVariable:             "变量 %s 的值为空",
+	MsgCircularReference:        "公式存在循环引用: %s",
+	MsgFormulaNotRegistered:     "公式 %s 未注册",
+	MsgDivisorZero:              "除数不能为零",
+	MsgUnsupportedUnaryOperator: "不支持的一元运算符: %s",
+	MsgUnsupportedOperator:      "不支持的运算符: %s",
+	MsgUnsupportedFunction:      "不支持的函数: %s",
+
+	MsgArgumentCountExact:   "%s 函数需要正好 %d 个参数，实际收到 %d 个",
+	MsgArgumentCountRange:   "%s 函数需要 %d 或 %d 个参数，实际收到 %d 个",
+	MsgArgumentCountMin:     "%s 函数需要至少 %d 个参数",
+	MsgNegativeSqrt:         "不能计算负数的平方根: %s",
+	MsgInvalidDecimalPlaces: "小数位数必须是非负整数",
+	MsgNonIntegerExponent:   "目前不支持非整数指数",
+	MsgColumnLength:         "列 %s 有 %d 行，其他列有 %d 行",
+	MsgEmptyFormulaName:     "公式名不能为空",
+	MsgNameIsInput:          "%s 已经是输入变量",
+	MsgNameIsFormula:        "%s 已经是公式",
+	MsgBindNotStruct:        "只能绑定结构体或结构体指针，得到 %T",
+	MsgNotFiniteNumber:      "%v 不是有限的数字",
+	MsgInvalidNumberString:  "%q 不是有效的数字",
+	MsgPathNotNumber:        "%s 的值 %v 不是数字",
+	MsgUnmarshalCompiled:    "只能反序列化到新的预编译表达式",
+
+	MsgDeriveUnaryOperator: "不支持对一元运算符 %s 求导",
+	MsgDeriveNodeType:      "不支持的节点类型",
+	MsgDeriveOperator:      "不支持对运算符 %s 求导",
+	MsgDeriveExponent:      "指数依赖变量 %s 的幂运算无法求导",
+	MsgDeriveFunction:      "函数 %s 无法对变量 %s 求导",
+
+	MsgExpressionLength:       "表达式长度超过限制 (%d > %d)",
+	MsgNestedParentheses:      "嵌套括号数超过限制 (%d > %d)",
+	MsgUnbalancedParentheses:  "括号不匹配",
+	MsgFunctionNotAllowed:     "函数 %s 不在允许列表中",
+	MsgFunctionDisallowed:     "函数 %s 在禁止列表中",
+	MsgFunctionArguments:      "函数 %s 的参数数量超过限制 (%d > %d)",
+	MsgVariablesNotAllowed:    "表达式中不允许使用变量",
+	MsgVariableNameLength:     "变量名 %s 长度超过限制 (%d > %d)",
+	MsgVariableNotAllowed:     "变量 %s 不在允许列表中",
+	MsgRequiredVariable:       "缺少必需的变量 %s",
+	MsgInvalidVariablePattern: "无效的变量名模式 %s: %s",
+	MsgNumberLength:           "数字 %s 长度超过限制 (%d > %d)",
+	MsgOperatorCount:          "运算符数量超过限制 (%d > %d)",
+	MsgNodeCount:              "表达式节点数超过限制 (%d > %d)",
+
+	MsgExponentLimit:  "指数的绝对值超过限制 %d",
+	MsgDigitLimit:     "中间结果的位数超过限制 %d",
+	MsgIterationLimit: "迭代次数超过限制 %d",
+	MsgOperationLimit: "运算次数超过限制 %d",
+
+	MsgPoolClosed: "工作池已关闭",
+
+	MsgPanic: "计算表达式时发生异常: %v",
+}
+
+// enMessages 英文消息
+var enMessages = map[string]string{
+	MsgPosition:   "position %d: %s",
+	MsgDiagnostic: "line %d, column %d: %s",
+	MsgSuggestion: "did you mean: %s",
+
+	string(CodeDivisionByZero):       "division by zero",
+	string(CodeUndefinedVariable):    "undefined variable",
+	string(CodeUnsupportedOperator):  "unsupported operator",
+	string(CodeInvalidExpression):    "invalid expression",
+	string(CodeInvalidArgument):      "invalid argument",
+	string(CodeMaxRecursionDepth):    "maximum recursion depth exceeded",
+	string(CodeExecutionTimeout):     "execution timeout",
+	string(CodeNotDifferentiable):    "not differentiable",
+	string(CodeValidationFailed):     "expression validation failed",
+	string(CodeInternal):             "internal error",
+	string(CodeResourceLimit):        "resource limit exceeded",
+	string(CodeCanceled):             "calculation canceled",
+	string(CodeNullResult):           "result is null",
+	string(CodeCircularReference):    "circular reference between formulas",
+	string(CodeFormulaNotRegistered): "formula is not registered",
+
+	MsgEmptyExpression:           "empty expression",
+	MsgExpressionTooLong:         "expression is too long",
+	MsgExpressionTooComplex:      "expression is too complex",
+	MsgUnexpectedToken:           "unexpected token: %s",
+	MsgUnexpectedEnd:             "unexpected end of expression",
+	MsgInvalidNumber:             "invalid number: %s",
+	MsgMissingRightParen:         "missing closing parenthesis",
+	MsgFunctionMissingLeftParen:  "missing opening parenthesis after function %s",
+	MsgFunctionMissingRightParen: "missing closing parenthesis for function %s",
+	MsgInvalidJSON:               "invalid expression JSON",
+	MsgUnsupportedJSONVersion:    "unsupported expression JSON version: %d",
+	MsgUnserializableNode:        "cannot serialize node of type %T",
+	MsgJSONNodeNil:               "node is null",
+	MsgJSONNodeTooDeep:           "nodes are nested too deeply",
+	MsgJSONNumberChildren:        "number node cannot have children",
+	MsgJSONVariableInvalid:       "variable node requires a name and cannot have children",
+	MsgJSONUnaryChildren:         "unary operator requires 1 child, got %d",
+	MsgJSONBinaryChildren:        "binary operator requires 2 children, got %d",
+	MsgJSONFunctionName:          "function node requires a name",
+	MsgJSONUnknownKind:           "unknown node kind: %s",
+
+	MsgUndefinedVariable:        "undefined variable: %s",
+	MsgVariableLookup:           "failed to look up variable %s: %v",
+	MsgNullVariable:             "variable %s is null",
+	MsgCircularReference:        "circular reference between formulas: %s",
+	MsgFormulaNotRegistered:     "formula %s is not registered",
+	MsgDivisorZero:              "divisor cannot be zero",
+	MsgUnsupportedUnaryOperator: "unsupported unary operator: %s",
+	MsgUnsupportedOperator:      "unsupported operator: %s",
+	MsgUnsupportedFunction:      "unsupported function: %s",
+
+	MsgArgumentCountExact:   "function %s requires exactly %d argument(s), got %d",
+	MsgArgumentCountRange:   "function %s requires %d or %d arguments, got %d",
+	MsgArgumentCountMin:     "function %s requires at least %d argument(s)",
+	MsgNegativeSqrt:         "cannot take the square root of a negative number: %s",
+	MsgInvalidDecimalPlaces: "decimal places must be a non-negative integer",
+	MsgNonIntegerExponent:   "non-integer exponents are not supported",
+	MsgColumnLength:         "column %s has %d rows, other columns have %d rows",
+	MsgEmptyFormulaName:     "formula name cannot be empty",
+	MsgNameIsInput:          "%s is already an input",
+	MsgNameIsFormula:        "%s is already a formula",
+	MsgBindNotStruct:        "can only bind a struct or a pointer to a struct, got %T",
+	MsgNotFiniteNumber:      "%v is not a finite number",
+	MsgInvalidNumberString:  "%q is not a valid number",
+	MsgPathNotNumber:        "%s has value %v, which is not a number",
+	MsgUnmarshalCompiled:    "can only unmarshal into a new compiled expression",
+
+	MsgDeriveUnaryOperator: "cannot differentiate unary operator %s",
+	MsgDeriveNodeType:      "unsupported node type",
+	MsgDeriveOperator:      "cannot differentiate operator %s",
+	MsgDeriveExponent:      "cannot differentiate a power whose exponent depends on %s",
+	MsgDeriveFunction:      "cannot differentiate function %s with respect to %s",
+
+	MsgExpressionLength:       "expression length exceeds the limit (%d > %d)",
+	MsgNestedParentheses:      "nested parentheses exceed the limit (%d > %d)",
+	MsgUnbalancedParentheses:  "unbalanced parentheses",
+	MsgFunctionNotAllowed:     "function %s is not in the allowed list",
+	MsgFunctionDisallowed:     "function %s is disallowed",
+	MsgFunctionArguments:      "function %s has too many arguments (%d > %d)",
+	MsgVariablesNotAllowed:    "variables are not allowed in the expression",
+	MsgVariableNameLength:     "variable name %s exceeds the length limit (%d > %d)",
+	MsgVariableNotAllowed:     "variable %s is not in the allowed list",
+	MsgRequiredVariable:       "required variable %s is not referenced",
+	MsgInvalidVariablePattern: "invalid variable pattern %s: %s",
+	MsgNumberLength:           "number %s exceeds the length limit (%d > %d)",
+	MsgOperatorCount:          "too many operators (%d > %d)",
+	MsgNodeCount:              "too many expression nodes (%d > %d)",
+
+	MsgExponentLimit:  "exponent magnitude exceeds the limit %d",
+	MsgDigitLimit:     "intermediate result exceeds the digit limit %d",
+	MsgIterationLimit: "iteration count exceeds the limit %d",
+	MsgOperationLimit: "operation count exceeds the limit %d",
+
+	MsgPoolClosed: "worker pool is closed",
+
+	MsgPanic: "panic while evaluating expression: %v",
+}
+
+// Translator 自定义翻译函数，返回 false 时使用已注册的消息
+type Translator func(locale, key string, args ...interface{}) (string, bool)
+
+var (
+	catalogMutex sync.RWMutex
+	catalogs     = map[string]map[string]string{
+		LocaleZh: zhMessages,
+		LocaleEn: enMessages,
+	}
+	translator Translator
+)
+
+// RegisterMessages 注册某种语言的消息，已存在的消息键会被覆盖
+// 消息值是 fmt 格式字符串，参数与内置中文消息相同
+func RegisterMessages(locale string, messages map[string]string) {
+	catalogMutex.Lock()
+	defer catalogMutex.Unlock()
+
+	catalog := make(map[string]string, len(catalogs[locale])+len(messages))
+	for k, v := range catalogs[locale] {
+		catalog[k] = v
+	}
+	for k, v := range messages {
+		catalog[k] = v
+	}
+	catalogs[locale] = catalog
+}
+
+// SetTranslator 设置自定义翻译函数，传入 nil 取消
+func SetTranslator(t Translator) {
+	catalogMutex.Lock()
+	defer catalogMutex.Unlock()
+	translator = t
+}
+
+// Translate 按语言格式化消息，locale 为空时使用中文
+// 找不到消息时依次回退到去掉地区后缀的语言（如 en-US 回退到 en）、英文和中文
+func Translate(locale, key string, args ...interface{}) string {
+	if locale == "" {
+		locale = LocaleZh
+	}
+
+	catalogMutex.RLock()
+	t := translator
+	catalogMutex.RUnlock()
+	if t != nil {
+		if message, ok := t(locale, key, args...); ok {
+			return message
+		}
+	}
+
+	catalogMutex.RLock()
+	defer catalogMutex.RUnlock()
+	for _, l := range fallbackLocales(locale) {
+		if format, ok := catalogs[l][key]; ok {
+			return fmt.Sprintf(format, args...)
+		}
+	}
+	return key
+}
+
+// fallbackLocales 返回查找消息时依次尝试的语言
+func fallbackLocales(locale string) []string {
+	locales := []string{locale}
+	if i := strings.IndexAny(locale, "-_"); i > 0 {
+		locales = append(locales, locale[:i])
+	}
+	return append(locales, LocaleEn, LocaleZh)
+}
+
+// localizer 可以按语言输出消息的错误
+type localizer interface {
+	Localize(locale string) error
+}
+
+// Localize 返回按指定语言输出消息的错误副本，不影响 errors.Is 和 errors.As 的判断
+// 不支持本地化的错误原样返回
+func Localize(err error, locale string) error {
+	if err == nil || locale == "" {
+		return err
+	}
+	if l, ok := err.(localizer); ok {
+		return l.Localize(locale)
+	}
+	return err
+}
+
+// causeMessage 返回原始错误的消息，预定义错误及包装了预定义错误的错误按语言翻译
+func causeMessage(cause error, locale string) string {
+	for _, ec := range errorCodes {
+		if errors.Is(cause, ec.err) {
+			return Translate(locale, string(ec.code))
+		}
+	}
+	return cause.Error()
+}
@@ -0,0 +1,94 @@
+package internal
+
+import (
+	"errors"
+	"testing"
+)
+
+// TestCatalogsComplete 确保每种内置语言的消息键一致
+func TestCatalogsComplete(t *testing.T) {
+	for key := range zhMessages {
+		if _, ok := enMessages[key]; !ok {
+			t.Errorf("enMessages missing key %q", key)
+		}
+	}
+	for key := range enMessages {
+		if _, ok := zhMessages[key]; !ok {
+			t.Errorf("zhMessages missing key %q", key)
+		}
+	}
+	for _, ec := range errorCodes {
+		if _, ok := zhMessages[string(ec.code)]; !ok {
+			t.Errorf("zhMessages missing error code %q", ec.code)
+		}
+	}
+}
+
+func TestTranslate(t *testing.T) {
+	RegisterMessages("ja", map[string]string{MsgUndefinedVariable: "未定義の変数: %s"})
+	defer func() {
+		catalogMutex.Lock()
+		delete(catalogs, "ja")
+		catalogMutex.Unlock()
+	}()
+
+	tests := []struct {
+		name   string
+		locale string
+		key    string
+		args   []interface{}
+		want   string
+	}{
+		{name: "默认中文", locale: "", key: MsgUndefinedVariable, args: []interface{}{"x"}, want: "未定义的变量: x"},
+		{name: "英文", locale: LocaleEn, key: MsgUndefinedVariable, args: []interface{}{"x"}, want: "undefined variable: x"},
+		{name: "地区后缀回退", locale: "en-US", key: MsgDivisorZero, want: "divisor cannot be zero"},
+		{name: "注册的语言", locale: "ja", key: MsgUndefinedVariable, args: []interface{}{"x"}, want: "未定義の変数: x"},
+		{name: "缺少的消息回退到英文", locale: "ja", key: MsgDivisorZero, want: "divisor cannot be zero"},
+		{name: "未知的消息键", locale: LocaleEn, key: "unknown.key", want: "unknown.key"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Translate(tt.locale, tt.key, tt.args...); got != tt.want {
+				t.Errorf("Translate() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSetTranslator(t *testing.T) {
+	SetTranslator(func(locale, key string, args ...interface{}) (string, bool) {
+		if locale == "fr" && key == MsgDivisorZero {
+			return "le diviseur ne peut pas être zéro", true
+		}
+		return "", false
+	})
+	defer SetTranslator(nil)
+
+	if got := Translate("fr", MsgDivisorZero); got != "le diviseur ne peut pas être zéro" {
+		t.Errorf("Translate() = %q", got)
+	}
+	if got := Translate("fr", MsgEmptyExpression); got != "empty expression" {
+		t.Errorf("Translate() = %q", got)
+	}
+}
+
+func TestLocalize(t *testing.T) {
+	err := error(NewParseError(4, ErrUndefinedVariable, "price", MsgUndefinedVariable, "price"))
+
+	localized := Localize(err, LocaleEn)
+	if got, want := localized.Error(), "position 4: undefined variable: price: undefined variable"; got != want {
+		t.Errorf("Localize().Error() = %q, want %q", got, want)
+	}
+	if got, want := err.Error(), "位置 4: 未定义的变量: price: 未定义的变量"; got != want {
+		t.Errorf("original Error() = %q, want %q", got, want)
+	}
+	if !errors.Is(localized, ErrUndefinedVariable) {
+		t.Errorf("errors.Is(localized, ErrUndefinedVariable) = false")
+	}
+
+	// 不支持本地化的错误原样返回
+	if got := Localize(ErrDivisionByZero, LocaleEn); got != ErrDivisionByZero {
+		t.Errorf("Localize() = %v, want %v", got, ErrDivisionByZero)
+	}
+}
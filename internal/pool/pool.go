@@ -0,0 +1,184 @@
+// Package pool 提供可以复用的计算工作池，按完成顺序流式返回每个任务的结果
+package pool
+
+import (
+	"context"
+	"runtime"
+	"sync"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/ZHOUXING1997/math_calculation/internal"
+	"github.com/ZHOUXING1997/math_calculation/internal/math_utils"
+)
+
+// Task 一组任务中第 index 个任务的计算函数，ctx 结束时应尽快返回
+type Task func(ctx context.Context, index int) (decimal.Decimal, error)
+
+// Result 一个任务的结果，Index 为任务在这组任务中的位置
+type Result struct {
+	Index int
+	Value decimal.Decimal
+	Err   error
+}
+
+// Options 一组任务的运行选项
+type Options struct {
+	FailFast bool   // 有任务出错时取消其余任务，尚未开始的任务返回 ErrCanceled；为 false 时出错的任务不影响其他任务
+	Locale   string // 任务 panic 时错误消息使用的语言
+}
+
+// Pool 固定数量 worker 的工作池，可以被多组任务同时复用，不再使用时调用 Close 释放 worker
+type Pool struct {
+	workers int
+	tasks   chan func()
+	wg      sync.WaitGroup
+	mutex   sync.RWMutex
+	closed  bool
+}
+
+// New 创建有 workers 个 worker 的工作池，workers 小于等于 0 时使用 runtime.GOMAXPROCS(0)
+func New(workers int) *Pool {
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	p := &Pool{
+		workers: workers,
+		tasks:   make(chan func()),
+	}
+	p.wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go func() {
+			defer p.wg.Done()
+			for task := range p.tasks {
+				task()
+			}
+		}()
+	}
+	return p
+}
+
+// Workers 返回 worker 的数量
+func (p *Pool) Workers() int {
+	return p.workers
+}
+
+// Close 关闭工作池，等待已经开始的任务完成，之后提交的任务返回 ErrCanceled，可以重复调用
+func (p *Pool) Close() {
+	p.mutex.Lock()
+	if !p.closed {
+		p.closed = true
+		close(p.tasks)
+	}
+	p.mutex.Unlock()
+	p.wg.Wait()
+}
+
+// submit 将任务交给空闲的 worker，工作池已关闭或 ctx 结束时返回错误
+func (p *Pool) submit(ctx context.Context, task func()) error {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+	if p.closed {
+		return internal.NewParseError(0, internal.ErrCanceled, "", internal.MsgPoolClosed)
+	}
+	select {
+	case p.tasks <- task:
+		return nil
+	case <-ctx.Done():
+		return math_utils.Canceled(ctx)
+	}
+}
+
+// Stream 运行 n 个任务，按完成顺序从返回的通道输出每个任务的结果，所有任务都有结果后关闭通道
+// 通道有 n 个缓冲，读取结果较慢时不会阻塞 worker；ctx 结束后尚未开始的任务返回 ErrCanceled
+func (p *Pool) Stream(ctx context.Context, n int, task Task, options Options) <-chan Result {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	results := make(chan Result, n)
+	ctx, cancel := context.WithCancel(ctx)
+
+	go func() {
+		defer cancel()
+		wg := sync.WaitGroup{}
+		for i := 0; i < n; i++ {
+			// ctx 结束时不再提交新的任务
+			if ctx.Err() != nil {
+				results <- Result{Index: i, Err: math_utils.Canceled(ctx)}
+				continue
+			}
+			index := i
+			wg.Add(1)
+			err := p.submit(ctx, func() {
+				defer wg.Done()
+				result := run(ctx, index, task, options.Locale)
+				if result.Err != nil && options.FailFast {
+					cancel()
+				}
+				results <- result
+			})
+			if err != nil {
+				wg.Done()
+				results <- Result{Index: i, Err: internal.Localize(err, options.Locale)}
+			}
+		}
+		wg.Wait()
+		close(results)
+	}()
+	return results
+}
+
+// run 运行一个任务，捕获任务中的 panic
+func run(ctx context.Context, index int, task Task, locale string) (result Result) {
+	result.Index = index
+	defer func() {
+		if r := recover(); r != nil {
+			result.Value = decimal.Zero
+			result.Err = internal.Localize(internal.NewParseError(0, internal.ErrInternal, "", internal.MsgPanic, r), locale)
+		}
+	}()
+	result.Value, result.Err = task(ctx, index)
+	if result.Err != nil {
+		result.Value = decimal.Zero
+	}
+	return result
+}
+
+// Run 运行 n 个任务，等待全部完成后按任务的顺序返回结果和错误
+func (p *Pool) Run(ctx context.Context, n int, task Task, options Options) ([]decimal.Decimal, []error) {
+	return Collect(p.Stream(ctx, n, task, options), n)
+}
+
+// Stream 使用有 workers 个 worker 的临时工作池运行 n 个任务，所有任务完成后关闭工作池
+// workers 小于等于 0 时使用 runtime.GOMAXPROCS(0)，超过 n 时只创建 n 个 worker
+func Stream(ctx context.Context, workers int, n int, task Task, options Options) <-chan Result {
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	if workers > n {
+		workers = n
+	}
+	if workers <= 0 {
+		workers = 1
+	}
+	p := New(workers)
+	results := make(chan Result, n)
+	go func() {
+		defer close(results)
+		for result := range p.Stream(ctx, n, task, options) {
+			results <- result
+		}
+		p.Close()
+	}()
+	return results
+}
+
+// Collect 读取通道中的 n 个结果，按任务的顺序返回结果和错误
+func Collect(results <-chan Result, n int) ([]decimal.Decimal, []error) {
+	values := make([]decimal.Decimal, n)
+	errs := make([]error, n)
+	for result := range results {
+		values[result.Index], errs[result.Index] = result.Value, result.Err
+	}
+	return values, errs
+}
@@ -0,0 +1,218 @@
+package pool
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/ZHOUXING1997/math_calculation/internal"
+)
+
+// square 返回 index 的平方
+func square(_ context.Context, index int) (decimal.Decimal, error) {
+	return decimal.NewFromInt(int64(index * index)), nil
+}
+
+func TestPool_Run(t *testing.T) {
+	errOdd := errors.New("奇数")
+	tests := []struct {
+		name    string
+		n       int
+		task    Task
+		options Options
+		check   func(t *testing.T, values []decimal.Decimal, errs []error)
+	}{
+		{
+			name: "按任务顺序返回结果",
+			n:    50,
+			task: square,
+			check: func(t *testing.T, values []decimal.Decimal, errs []error) {
+				for i := range values {
+					if errs[i] != nil || !values[i].Equal(decimal.NewFromInt(int64(i*i))) {
+						t.Errorf("task %d = %v, %v, want %d", i, values[i], errs[i], i*i)
+					}
+				}
+			},
+		},
+		{
+			name: "出错的任务不影响其他任务",
+			n:    10,
+			task: func(ctx context.Context, index int) (decimal.Decimal, error) {
+				if index%2 == 1 {
+					return decimal.NewFromInt(1), errOdd
+				}
+				return square(ctx, index)
+			},
+			check: func(t *testing.T, values []decimal.Decimal, errs []error) {
+				for i := range values {
+					if i%2 == 1 {
+						if !errors.Is(errs[i], errOdd) || !values[i].IsZero() {
+							t.Errorf("task %d = %v, %v, want error %v", i, values[i], errs[i], errOdd)
+						}
+					} else if errs[i] != nil {
+						t.Errorf("task %d error = %v", i, errs[i])
+					}
+				}
+			},
+		},
+		{
+			name: "捕获panic",
+			n:    2,
+			task: func(ctx context.Context, index int) (decimal.Decimal, error) {
+				if index == 1 {
+					panic("boom")
+				}
+				return square(ctx, index)
+			},
+			options: Options{Locale: "en"},
+			check: func(t *testing.T, values []decimal.Decimal, errs []error) {
+				if errs[0] != nil || !errors.Is(errs[1], internal.ErrInternal) {
+					t.Errorf("errs = %v, want [nil ErrInternal]", errs)
+				}
+			},
+		},
+		{
+			name: "没有任务",
+			n:    0,
+			task: square,
+			check: func(t *testing.T, values []decimal.Decimal, errs []error) {
+				if len(values) != 0 || len(errs) != 0 {
+					t.Errorf("Run() = %v, %v, want empty", values, errs)
+				}
+			},
+		},
+	}
+
+	p := New(4)
+	defer p.Close()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			values, errs := p.Run(context.Background(), tt.n, tt.task, tt.options)
+			tt.check(t, values, errs)
+		})
+	}
+}
+
+func TestPool_Concurrency(t *testing.T) {
+	p := New(3)
+	defer p.Close()
+	if p.Workers() != 3 {
+		t.Fatalf("Workers() = %d, want 3", p.Workers())
+	}
+
+	var running, peak int32
+	task := func(ctx context.Context, index int) (decimal.Decimal, error) {
+		n := atomic.AddInt32(&running, 1)
+		for {
+			old := atomic.LoadInt32(&peak)
+			if n <= old || atomic.CompareAndSwapInt32(&peak, old, n) {
+				break
+			}
+		}
+		defer atomic.AddInt32(&running, -1)
+		return square(ctx, index)
+	}
+
+	// 多组任务同时复用同一个工作池
+	wg := sync.WaitGroup{}
+	for g := 0; g < 4; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, errs := p.Run(context.Background(), 100, task, Options{})
+			for i, err := range errs {
+				if err != nil {
+					t.Errorf("task %d error = %v", i, err)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	if peak > 3 {
+		t.Errorf("peak concurrency = %d, want <= 3", peak)
+	}
+}
+
+func TestPool_Stream(t *testing.T) {
+	p := New(2)
+	defer p.Close()
+
+	// 任务 0 等待任务 1 完成，结果按完成顺序输出
+	done := make(chan struct{})
+	results := p.Stream(context.Background(), 2, func(ctx context.Context, index int) (decimal.Decimal, error) {
+		if index == 0 {
+			<-done
+		} else {
+			defer close(done)
+		}
+		return square(ctx, index)
+	}, Options{})
+
+	var order []int
+	for result := range results {
+		order = append(order, result.Index)
+	}
+	if len(order) != 2 || order[0] != 1 || order[1] != 0 {
+		t.Errorf("result order = %v, want [1 0]", order)
+	}
+}
+
+func TestPool_FailFast(t *testing.T) {
+	p := New(2)
+	defer p.Close()
+
+	errFirst := errors.New("第一个任务出错")
+	_, errs := p.Run(context.Background(), 20, func(ctx context.Context, index int) (decimal.Decimal, error) {
+		if index == 0 {
+			return decimal.Zero, errFirst
+		}
+		// 其余任务等待取消
+		<-ctx.Done()
+		return decimal.Zero, ctx.Err()
+	}, Options{FailFast: true})
+
+	if !errors.Is(errs[0], errFirst) {
+		t.Errorf("task 0 error = %v, want %v", errs[0], errFirst)
+	}
+	for i := 1; i < len(errs); i++ {
+		if !errors.Is(errs[i], context.Canceled) {
+			t.Errorf("task %d error = %v, want %v", i, errs[i], context.Canceled)
+		}
+	}
+}
+
+func TestPool_Canceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	p := New(2)
+	_, errs := p.Run(ctx, 5, square, Options{})
+	for i, err := range errs {
+		if !errors.Is(err, internal.ErrCanceled) || !errors.Is(err, context.Canceled) {
+			t.Errorf("task %d error = %v, want %v", i, err, internal.ErrCanceled)
+		}
+	}
+
+	// 关闭后提交的任务
+	p.Close()
+	p.Close()
+	_, errs = p.Run(context.Background(), 2, square, Options{Locale: internal.LocaleEn})
+	for i, err := range errs {
+		if !errors.Is(err, internal.ErrCanceled) || err.Error() != "position 0: worker pool is closed: calculation canceled" {
+			t.Errorf("task %d error = %v, want %v", i, err, internal.ErrCanceled)
+		}
+	}
+}
+
+func TestStream(t *testing.T) {
+	values, errs := Collect(Stream(context.Background(), 0, 30, square, Options{}), 30)
+	for i := range values {
+		if errs[i] != nil || !values[i].Equal(decimal.NewFromInt(int64(i*i))) {
+			t.Errorf("task %d = %v, %v, want %d", i, values[i], errs[i], i*i)
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package render
+
+import (
+	"strings"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/ZHOUXING1997/math_calculation/internal/math_node"
+	"github.com/ZHOUXING1997/math_calculation/internal/math_utils"
+)
+
+// LaTeX 将表达式树渲染为 LaTeX 公式（不含 $ 等数学环境定界符）
+// 除法渲染为 \frac，幂渲染为上标，sqrt、abs、ceil、floor 使用对应的数学符号
+func LaTeX(node math_node.Node, options Options) string {
+	w := &walker{backend: latexBackend{}, options: options}
+	out, _ := w.render(node)
+	return out
+}
+
+// latexBackend LaTeX 输出
+type latexBackend struct{}
+
+// latexEscaper 转义数学环境中 \mathrm 和 \operatorname 内的 LaTeX 特殊字符
+// 数学环境中不能使用 \^ 等文本重音命令，反斜杠、^ 和 ~ 使用 \text 中的文本符号（\text 和 \operatorname 都需要 amsmath）
+var latexEscaper = strings.NewReplacer(
+	`\`, `\text{\textbackslash}`,
+	`_`, `\_`,
+	`{`, `\{`,
+	`}`, `\}`,
+	`$`, `\$`,
+	`%`, `\%`,
+	`&`, `\&`,
+	`#`, `\#`,
+	`^`, `\text{\textasciicircum}`,
+	`~`, `\text{\textasciitilde}`,
+	` `, `\ `,
+)
+
+func (latexBackend) number(value decimal.Decimal) string {
+	return value.String()
+}
+
+func (latexBackend) variable(name string) string {
+	// 单个字母按数学变量排版，其他名称使用正体
+	if len(name) == 1 && math_utils.IsAlpha(name[0]) {
+		return name
+	}
+	return `\mathrm{` + latexEscaper.Replace(name) + `}`
+}
+
+func (latexBackend) paren(content string) string {
+	return `\left(` + content + `\right)`
+}
+
+func (latexBackend) binary(operator, left, right string) string {
+	if operator == "*" {
+		operator = `\cdot`
+	}
+	return left + " " + operator + " " + right
+}
+
+func (latexBackend) fraction(numerator, denominator string) string {
+	return `\frac{` + numerator + `}{` + denominator + `}`
+}
+
+func (latexBackend) power(base, exponent string) string {
+	return "{" + base + "}^{" + exponent + "}"
+}
+
+func (latexBackend) negate(operand string) string {
+	return "-" + operand
+}
+
+func (latexBackend) function(name string, args []string) string {
+	switch {
+	case name == "sqrt" && len(args) == 1:
+		return `\sqrt{` + args[0] + `}`
+	case name == "abs" && len(args) == 1:
+		return `\left|` + args[0] + `\right|`
+	case name == "ceil" && len(args) == 1:
+		return `\left\lceil ` + args[0] + ` \right\rceil`
+	case name == "floor" && len(args) == 1:
+		return `\left\lfloor ` + args[0] + ` \right\rfloor`
+	case name == "min" || name == "max":
+		return `\` + name + `\left(` + strings.Join(args, ", ") + `\right)`
+	}
+	return `\operatorname{` + latexEscaper.Replace(name) + `}\left(` + strings.Join(args, ", ") + `\right)`
+}
@@ -0,0 +1,74 @@
+package render_test
+
+import (
+	"testing"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/ZHOUXING1997/math_calculation/internal/croe"
+	"github.com/ZHOUXING1997/math_calculation/internal/math_node"
+	"github.com/ZHOUXING1997/math_calculation/internal/render"
+	"github.com/ZHOUXING1997/math_calculation/math_config"
+)
+
+// parse 解析测试表达式
+func parse(t *testing.T, expression string) math_node.Node {
+	t.Helper()
+	node, err := croe.NewParser(nil, math_config.NewDefaultCalcConfig()).Parse(expression)
+	if err != nil {
+		t.Fatalf("Parse(%q) error = %v", expression, err)
+	}
+	return node
+}
+
+func TestLaTeX(t *testing.T) {
+	tests := []struct {
+		name       string
+		expression string
+		options    render.Options
+		want       string
+	}{
+		{name: "加减", expression: "a + b - c", want: `a + b - c`},
+		{name: "减法右侧括号", expression: "a - (b - c)", want: `a - \left(b - c\right)`},
+		{name: "乘法", expression: "(a + b) * c", want: `\left(a + b\right) \cdot c`},
+		{name: "分数", expression: "(a + b) / (c * 2)", want: `\frac{a + b}{c \cdot 2}`},
+		{name: "上标", expression: "(x + 1) ^ 2", want: `{\left(x + 1\right)}^{2}`},
+		{name: "负数底数", expression: "-2 ^ 2", want: `{\left(-2\right)}^{2}`},
+		{name: "pow函数", expression: "pow(x, n + 1)", want: `{x}^{n + 1}`},
+		{name: "平方根", expression: "sqrt(x / 2)", want: `\sqrt{\frac{x}{2}}`},
+		{name: "向上取整", expression: "ceil(x)", want: `\left\lceil x \right\rceil`},
+		{name: "向下取整", expression: "floor(x)", want: `\left\lfloor x \right\rfloor`},
+		{name: "绝对值", expression: "abs(x)", want: `\left|x\right|`},
+		{name: "最值", expression: "max(a, b)", want: `\max\left(a, b\right)`},
+		{name: "其他函数", expression: "round(x, 2)", want: `\operatorname{round}\left(x, 2\right)`},
+		{name: "多字母变量", expression: "unit_price * qty", want: `\mathrm{unit\_price} \cdot \mathrm{qty}`},
+		{name: "单个下划线变量", expression: "_ * 2", want: `\mathrm{\_} \cdot 2`},
+		{name: "引用的变量名中的特殊字符", expression: "`%` + `a b` + [x^y~\\z]", want: `\mathrm{\%} + \mathrm{a\ b} + \mathrm{x\text{\textasciicircum}y\text{\textasciitilde}\text{\textbackslash}z}`},
+		{name: "一元负号", expression: "-(a + b)", want: `-\left(a + b\right)`},
+		{name: "乘负数", expression: "a * -2", want: `a \cdot \left(-2\right)`},
+		{
+			name:       "替换变量值",
+			expression: "qty * price * (1 - discount)",
+			options: render.Options{Variables: map[string]decimal.Decimal{
+				"qty":      decimal.NewFromInt(3),
+				"price":    decimal.NewFromFloat(19.9),
+				"discount": decimal.NewFromFloat(0.1),
+			}},
+			want: `3 \cdot 19.9 \cdot \left(1 - 0.1\right)`,
+		},
+		{
+			name:       "替换负数变量值",
+			expression: "a - b",
+			options:    render.Options{Variables: map[string]decimal.Decimal{"b": decimal.NewFromInt(-5)}},
+			want:       `a - \left(-5\right)`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := render.LaTeX(parse(t, tt.expression), tt.options); got != tt.want {
+				t.Errorf("LaTeX(%q) = %s, want %s", tt.expression, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,82 @@
+package render
+
+import (
+	"html"
+	"strings"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/ZHOUXING1997/math_calculation/internal/math_node"
+)
+
+// MathML 将表达式树渲染为 MathML 表示层标记，包含 <math> 根元素
+// 除法渲染为 <mfrac>，幂渲染为 <msup>，sqrt 渲染为 <msqrt>
+func MathML(node math_node.Node, options Options) string {
+	w := &walker{backend: mathmlBackend{}, options: options}
+	out, _ := w.render(node)
+	return `<math xmlns="http://www.w3.org/1998/Math/MathML">` + out + `</math>`
+}
+
+// mathmlBackend MathML 输出
+type mathmlBackend struct{}
+
+func (mathmlBackend) number(value decimal.Decimal) string {
+	return "<mn>" + value.String() + "</mn>"
+}
+
+func (mathmlBackend) variable(name string) string {
+	return "<mi>" + html.EscapeString(name) + "</mi>"
+}
+
+func (mathmlBackend) paren(content string) string {
+	return "<mrow><mo>(</mo>" + content + "<mo>)</mo></mrow>"
+}
+
+func (mathmlBackend) binary(operator, left, right string) string {
+	switch operator {
+	case "*":
+		operator = "⋅"
+	case "-":
+		operator = "−"
+	}
+	return "<mrow>" + left + "<mo>" + operator + "</mo>" + right + "</mrow>"
+}
+
+func (mathmlBackend) fraction(numerator, denominator string) string {
+	// 每个输出片段都是单个元素，可以直接作为 <mfrac>、<msup> 的参数
+	return "<mfrac>" + numerator + denominator + "</mfrac>"
+}
+
+func (mathmlBackend) power(base, exponent string) string {
+	return "<msup>" + base + exponent + "</msup>"
+}
+
+func (mathmlBackend) negate(operand string) string {
+	return "<mrow><mo>−</mo>" + operand + "</mrow>"
+}
+
+func (mathmlBackend) function(name string, args []string) string {
+	switch {
+	case name == "sqrt" && len(args) == 1:
+		return "<msqrt>" + args[0] + "</msqrt>"
+	case name == "abs" && len(args) == 1:
+		return "<mrow><mo>|</mo>" + args[0] + "<mo>|</mo></mrow>"
+	case name == "ceil" && len(args) == 1:
+		return "<mrow><mo>⌈</mo>" + args[0] + "<mo>⌉</mo></mrow>"
+	case name == "floor" && len(args) == 1:
+		return "<mrow><mo>⌊</mo>" + args[0] + "<mo>⌋</mo></mrow>"
+	}
+
+	var sb strings.Builder
+	sb.WriteString("<mrow><mi>")
+	sb.WriteString(html.EscapeString(name))
+	sb.WriteString("</mi><mo>&#x2061;</mo><mrow><mo>(</mo>")
+	for i, arg := range args {
+		if i > 0 {
+			sb.WriteString("<mo>,</mo>")
+		}
+		sb.WriteString(arg)
+	}
+	sb.WriteString("<mo>)</mo></mrow></mrow>")
+	return sb.String()
+}
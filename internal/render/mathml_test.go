@@ -0,0 +1,58 @@
+package render_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/ZHOUXING1997/math_calculation/internal/render"
+)
+
+func TestMathML(t *testing.T) {
+	const prefix = `<math xmlns="http://www.w3.org/1998/Math/MathML">`
+	const suffix = `</math>`
+
+	tests := []struct {
+		name       string
+		expression string
+		options    render.Options
+		want       string
+	}{
+		{name: "数字", expression: "3.5", want: `<mn>3.5</mn>`},
+		{name: "变量转义", expression: "a_b", want: `<mi>a_b</mi>`},
+		{name: "加法", expression: "a + 1", want: `<mrow><mi>a</mi><mo>+</mo><mn>1</mn></mrow>`},
+		{name: "乘法", expression: "a * b", want: `<mrow><mi>a</mi><mo>⋅</mo><mi>b</mi></mrow>`},
+		{name: "分数", expression: "a / b", want: `<mfrac><mi>a</mi><mi>b</mi></mfrac>`},
+		{
+			name:       "上标",
+			expression: "(a - b) ^ 2",
+			want:       `<msup><mrow><mo>(</mo><mrow><mi>a</mi><mo>−</mo><mi>b</mi></mrow><mo>)</mo></mrow><mn>2</mn></msup>`,
+		},
+		{name: "平方根", expression: "sqrt(x)", want: `<msqrt><mi>x</mi></msqrt>`},
+		{name: "向上取整", expression: "ceil(x)", want: `<mrow><mo>⌈</mo><mi>x</mi><mo>⌉</mo></mrow>`},
+		{
+			name:       "其他函数",
+			expression: "min(a, 2)",
+			want:       `<mrow><mi>min</mi><mo>&#x2061;</mo><mrow><mo>(</mo><mi>a</mi><mo>,</mo><mn>2</mn><mo>)</mo></mrow></mrow>`,
+		},
+		{
+			name:       "替换变量值",
+			expression: "fee * rate",
+			options:    render.Options{Variables: map[string]decimal.Decimal{"rate": decimal.NewFromFloat(0.06)}},
+			want:       `<mrow><mi>fee</mi><mo>⋅</mo><mn>0.06</mn></mrow>`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := render.MathML(parse(t, tt.expression), tt.options)
+			if !strings.HasPrefix(got, prefix) || !strings.HasSuffix(got, suffix) {
+				t.Fatalf("MathML(%q) = %s, missing <math> root", tt.expression, got)
+			}
+			if inner := strings.TrimSuffix(strings.TrimPrefix(got, prefix), suffix); inner != tt.want {
+				t.Errorf("MathML(%q) = %s, want %s", tt.expression, inner, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,113 @@
+package render
+
+import (
+	"github.com/shopspring/decimal"
+
+	"github.com/ZHOUXING1997/math_calculation/internal/math_node"
+)
+
+// 数学排版中的优先级，数值越大绑定越紧
+// 分数线本身就起到分组作用，所以除法按原子处理
+const (
+	precAdditive       = 1 // + -
+	precMultiplicative = 2 // *
+	precPower          = 3 // ^ 和 pow
+	precUnary          = 4 // 一元负号和负数
+	precAtom           = 5 // 数字、变量、函数、分数
+)
+
+// Options 渲染选项
+type Options struct {
+	Variables map[string]decimal.Decimal // 非空时用变量值替换公式中对应的变量名
+}
+
+// backend 输出格式的具体实现
+type backend interface {
+	number(value decimal.Decimal) string
+	variable(name string) string
+	paren(content string) string
+	binary(operator, left, right string) string
+	fraction(numerator, denominator string) string
+	power(base, exponent string) string
+	negate(operand string) string
+	function(name string, args []string) string
+}
+
+// walker 遍历表达式树，根据优先级决定括号，再交给 backend 输出
+type walker struct {
+	backend backend
+	options Options
+}
+
+// render 渲染节点，返回输出文本和节点的排版优先级
+func (w *walker) render(node math_node.Node) (string, int) {
+	switch n := node.(type) {
+	case *math_node.NumberNode:
+		return w.number(n.Value)
+	case *math_node.VariableNode:
+		if value, ok := w.options.Variables[n.VarName]; ok {
+			return w.number(value)
+		}
+		return w.backend.variable(n.VarName), precAtom
+	case *math_node.UnaryOpNode:
+		operand, prec := w.render(n.Operand)
+		if n.Operator == "+" {
+			return operand, prec
+		}
+		// -(a + b) 和 -(-x) 需要括号，-x·y、-x² 按惯例不需要
+		if prec < precMultiplicative || prec == precUnary {
+			operand = w.backend.paren(operand)
+		}
+		return w.backend.negate(operand), precUnary
+	case *math_node.BinaryOpNode:
+		return w.binary(n.Operator, n.Left, n.Right)
+	case *math_node.FunctionNode:
+		if n.FuncName == "pow" && len(n.Args) == 2 {
+			return w.binary("^", n.Args[0], n.Args[1])
+		}
+		args := make([]string, len(n.Args))
+		for i, arg := range n.Args {
+			args[i], _ = w.render(arg)
+		}
+		return w.backend.function(n.FuncName, args), precAtom
+	}
+	return "", precAtom
+}
+
+// number 渲染数字，负数按一元负号处理优先级
+func (w *walker) number(value decimal.Decimal) (string, int) {
+	if value.IsNegative() {
+		return w.backend.negate(w.backend.number(value.Neg())), precUnary
+	}
+	return w.backend.number(value), precAtom
+}
+
+// binary 渲染二元运算
+func (w *walker) binary(operator string, leftNode, rightNode math_node.Node) (string, int) {
+	left, lp := w.render(leftNode)
+	right, rp := w.render(rightNode)
+
+	switch operator {
+	case "/":
+		return w.backend.fraction(left, right), precAtom
+	case "^":
+		// 底数不是原子时加括号，指数位于上标中不需要括号
+		if lp < precAtom {
+			left = w.backend.paren(left)
+		}
+		return w.backend.power(left, right), precPower
+	}
+
+	prec := precAdditive
+	if operator == "*" {
+		prec = precMultiplicative
+	}
+	if lp < prec {
+		left = w.backend.paren(left)
+	}
+	// 右侧的负数和一元负号加括号，减法右侧同级运算也需要括号
+	if rp < prec || rp == precUnary || (rp == prec && operator == "-") {
+		right = w.backend.paren(right)
+	}
+	return w.backend.binary(operator, left, right), prec
+}
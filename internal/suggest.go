@@ -0,0 +1,99 @@
+package internal
+
+import (
+	"sort"
+	"strings"
+)
+
+// maxSuggestions 最多返回的建议数量
+const maxSuggestions = 3
+
+// minSuggestLength 给出建议的最短名称长度，更短的名称与大量候选只差一个字符，建议没有参考价值
+const minSuggestLength = 3
+
+// Suggest 从 candidates 中找出与 name 相近的名称，用于“你是不是想输入”提示
+// 按编辑距离（不区分大小写，按字符计算）从小到大排序，距离相同时按名称排序，最多返回 3 个；
+// 允许的最大距离为名称长度的三分之一，至少为 1；名称少于 3 个字符或没有相近的名称时返回 nil
+func Suggest(name string, candidates []string) []string {
+	target := []rune(strings.ToLower(name))
+	if len(target) < minSuggestLength || len(candidates) == 0 {
+		return nil
+	}
+
+	limit := len(target) / 3
+	if limit < 1 {
+		limit = 1
+	}
+
+	type suggestion struct {
+		name     string
+		distance int
+	}
+	var found []suggestion
+	seen := make(map[string]bool, len(candidates))
+	for _, candidate := range candidates {
+		if candidate == name || seen[candidate] {
+			continue
+		}
+		seen[candidate] = true
+		if d := editDistance(target, []rune(strings.ToLower(candidate))); d <= limit {
+			found = append(found, suggestion{candidate, d})
+		}
+	}
+	if len(found) == 0 {
+		return nil
+	}
+
+	sort.Slice(found, func(i, j int) bool {
+		if found[i].distance != found[j].distance {
+			return found[i].distance < found[j].distance
+		}
+		return found[i].name < found[j].name
+	})
+	if len(found) > maxSuggestions {
+		found = found[:maxSuggestions]
+	}
+
+	names := make([]string, len(found))
+	for i, s := range found {
+		names[i] = s.name
+	}
+	return names
+}
+
+// editDistance 计算两个字符序列的编辑距离，相邻字符交换算一次编辑
+func editDistance(a, b []rune) int {
+	prev2 := make([]int, len(b)+1)
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = minInt(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+			if i > 1 && j > 1 && a[i-1] == b[j-2] && a[i-2] == b[j-1] {
+				curr[j] = minInt(curr[j], prev2[j-2]+1)
+			}
+		}
+		prev2, prev, curr = prev, curr, prev2
+	}
+	return prev[len(b)]
+}
+
+// minInt 返回最小值
+func minInt(values ...int) int {
+	m := values[0]
+	for _, v := range values[1:] {
+		if v < m {
+			m = v
+		}
+	}
+	return m
+}
@@ -0,0 +1,112 @@
+package internal
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSuggest(t *testing.T) {
+	functions := []string{"abs", "ceil", "floor", "max", "min", "pow", "round", "sqrt"}
+
+	tests := []struct {
+		name       string
+		input      string
+		candidates []string
+		want       []string
+	}{
+		{
+			name:       "缺少字符",
+			input:      "sqr",
+			candidates: functions,
+			want:       []string{"sqrt"},
+		},
+		{
+			name:       "相邻字符交换",
+			input:      "prcie",
+			candidates: []string{"price", "prize", "quantity"},
+			want:       []string{"price"},
+		},
+		{
+			name:       "不区分大小写",
+			input:      "SQRT",
+			candidates: functions,
+			want:       []string{"sqrt"},
+		},
+		{
+			name:       "按距离和名称排序",
+			input:      "mix",
+			candidates: functions,
+			want:       []string{"max", "min"},
+		},
+		{
+			name:       "最多返回三个",
+			input:      "abc",
+			candidates: []string{"abf", "abe", "abd", "xbc", "abcd"},
+			want:       []string{"abcd", "abd", "abe"},
+		},
+		{
+			name:       "没有相近的名称",
+			input:      "log",
+			candidates: functions,
+			want:       nil,
+		},
+		{
+			name:       "中文变量名",
+			input:      "总单介",
+			candidates: []string{"总单价", "总数量"},
+			want:       []string{"总单价"},
+		},
+		{
+			name:       "跳过自身和重复的候选",
+			input:      "rate",
+			candidates: []string{"rate", "rates", "rates"},
+			want:       []string{"rates"},
+		},
+		{
+			name:       "单字符名称不给建议",
+			input:      "x",
+			candidates: []string{"a", "b", "y", "z"},
+			want:       nil,
+		},
+		{
+			name:       "两个字符的名称不给建议",
+			input:      "mi",
+			candidates: functions,
+			want:       nil,
+		},
+		{
+			name:       "空名称",
+			input:      "",
+			candidates: functions,
+			want:       nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Suggest(tt.input, tt.candidates); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Suggest(%q) = %q, want %q", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEditDistance(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"", "", 0},
+		{"abc", "", 3},
+		{"kitten", "sitting", 3},
+		{"prcie", "price", 1},
+		{"sqrt", "sqrt", 0},
+		{"单介", "单价", 1},
+	}
+
+	for _, tt := range tests {
+		if got := editDistance([]rune(tt.a), []rune(tt.b)); got != tt.want {
+			t.Errorf("editDistance(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
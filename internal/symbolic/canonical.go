@@ -0,0 +1,475 @@
+package symbolic
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/ZHOUXING1997/math_calculation/internal/math_func"
+	"github.com/ZHOUXING1997/math_calculation/internal/math_node"
+)
+
+// term 单项式：系数乘以若干因子的整数次幂
+type term struct {
+	coef    decimal.Decimal
+	factors []factor
+}
+
+// factor 单项式中的因子，base 是已经规范化的不可再分解的表达式
+type factor struct {
+	base math_node.Node
+	key  string // base 的规范字符串，用于排序和合并
+	exp  int64
+}
+
+// NormalizeOptions 规范化选项
+type NormalizeOptions struct {
+	// AssumeDefined 假定所有变量在求值时都有值，此时 x - x、0 * x、x ^ 0 等可以消去变量
+	// 默认为 false：变量可能未定义，消去变量会让求值出错的表达式得到不会出错的规范形式
+	AssumeDefined bool
+}
+
+// normalizer 按规范化选项规范化表达式树
+type normalizer struct {
+	options NormalizeOptions
+}
+
+// Normalize 将表达式树转换为规范形式：展开单项式与多项式的乘积、合并同类项、
+// 按确定的顺序排列加法和乘法的操作数，以及 min/max 等交换函数的参数
+// 规范形式在求值时出错当且仅当原表达式出错：分母中可能为零的因子不与分子约分，
+// 乘以零、零次幂和相互抵消的同类项也不消去可能出错的表达式，如 x / x、0 * (1 / 0)、sqrt(-1) * 0、x - x。
+// 函数调用和变量都视为可能出错（变量可能未定义），可以通过 NormalizeWithOptions 假定变量都有值。
+// 在每一步应用精度控制时计算结果可能与原表达式存在舍入差异
+func Normalize(node math_node.Node) math_node.Node {
+	return NormalizeWithOptions(node, NormalizeOptions{})
+}
+
+// NormalizeWithOptions 使用指定选项将表达式树转换为规范形式
+func NormalizeWithOptions(node math_node.Node, options NormalizeOptions) math_node.Node {
+	n := &normalizer{options: options}
+	return n.normalize(node)
+}
+
+// Canonical 返回表达式树的规范字符串，代数上等价的常见写法会得到相同的字符串
+// 例如 a+b 与 b + a、x*2 与 2*x、x+x 与 2*x
+func Canonical(node math_node.Node) string {
+	return math_node.Format(Normalize(node))
+}
+
+// CanonicalWithOptions 使用指定选项返回表达式树的规范字符串
+func CanonicalWithOptions(node math_node.Node, options NormalizeOptions) string {
+	return math_node.Format(NormalizeWithOptions(node, options))
+}
+
+// normalize 将节点转换为规范形式
+func (n *normalizer) normalize(node math_node.Node) math_node.Node {
+	return buildSum(n.normalizeSum(node))
+}
+
+// normalizeSum 将节点分解为合并后的单项式列表，空列表表示 0
+func (n *normalizer) normalizeSum(node math_node.Node) []term {
+	return n.combine(n.expand(node))
+}
+
+// expand 将节点分解为未合并的单项式列表
+func (n *normalizer) expand(node math_node.Node) []term {
+	switch v := node.(type) {
+	case *math_node.NumberNode:
+		if v.Value.IsZero() {
+			return nil
+		}
+		return []term{{coef: v.Value}}
+	case *math_node.VariableNode:
+		return []term{opaqueTerm(v)}
+	case *math_node.UnaryOpNode:
+		switch v.Operator {
+		case "-":
+			return negateTerms(n.expand(v.Operand))
+		case "+":
+			return n.expand(v.Operand)
+		}
+		return []term{opaqueTerm(&math_node.UnaryOpNode{Operator: v.Operator, Operand: n.normalize(v.Operand)})}
+	case *math_node.BinaryOpNode:
+		return n.expandBinary(v)
+	case *math_node.FunctionNode:
+		return n.expandFunction(v)
+	}
+	return []term{opaqueTerm(node)}
+}
+
+// expandBinary 分解二元运算
+func (n *normalizer) expandBinary(v *math_node.BinaryOpNode) []term {
+	switch v.Operator {
+	case "+":
+		return append(n.expand(v.Left), n.expand(v.Right)...)
+	case "-":
+		return append(n.expand(v.Left), negateTerms(n.expand(v.Right))...)
+	case "*":
+		return n.multiplySums(n.normalizeSum(v.Left), n.normalizeSum(v.Right))
+	case "/":
+		left, right := n.normalizeSum(v.Left), n.normalizeSum(v.Right)
+		if len(right) == 0 {
+			// 保留除以零，让求值阶段报告错误
+			return []term{opaqueTerm(binary("/", buildSum(left), number(decimal.Zero)))}
+		}
+		divisor := right[0]
+		if len(right) > 1 {
+			divisor = opaqueTerm(buildSum(right))
+		}
+		result := make([]term, 0, len(left))
+		for _, t := range left {
+			result = append(result, divideTerm(t, divisor))
+		}
+		return result
+	case "^":
+		return n.expandPower(n.normalizeSum(v.Left), n.normalize(v.Right))
+	}
+	return []term{opaqueTerm(binary(v.Operator, n.normalize(v.Left), n.normalize(v.Right)))}
+}
+
+// expandPower 分解幂运算，只处理较小的整数指数
+func (n *normalizer) expandPower(base []term, exp math_node.Node) []term {
+	e, ok := numberValue(exp)
+	if !ok || !e.Equal(e.Floor()) || e.Abs().GreaterThan(decimal.NewFromInt(maxFoldExponent)) {
+		return []term{opaqueTerm(binary("^", buildSum(base), exp))}
+	}
+
+	power := e.IntPart()
+	if power == 0 {
+		if n.mayFail(base) {
+			return []term{opaqueTerm(binary("^", buildSum(base), exp))}
+		}
+		return []term{{coef: decimal.NewFromInt(1)}}
+	}
+	if len(base) == 0 {
+		if power > 0 {
+			return nil
+		}
+		return []term{opaqueTerm(binary("^", number(decimal.Zero), exp))}
+	}
+	t := base[0]
+	if len(base) > 1 {
+		// 多项式的幂不展开，作为一个整体因子
+		t = opaqueTerm(buildSum(base))
+	}
+	// 负指数会把分母中可能为零的因子移到分子，不再报告除以零
+	if power < 0 && n.mayFail([]term{{factors: t.factors}}) {
+		return []term{opaqueTerm(binary("^", buildSum(base), exp))}
+	}
+	if result, ok := powerTerm(t, power); ok {
+		return []term{result}
+	}
+	return []term{opaqueTerm(binary("^", buildSum(base), exp))}
+}
+
+// expandFunction 分解函数调用：参数规范化后尝试常量折叠，否则作为整体因子
+func (n *normalizer) expandFunction(f *math_node.FunctionNode) []term {
+	args := make([]math_node.Node, len(f.Args))
+	for i, arg := range f.Args {
+		args[i] = n.normalize(arg)
+	}
+
+	// min 和 max 与参数顺序无关
+	if f.FuncName == "min" || f.FuncName == "max" {
+		sort.SliceStable(args, func(i, j int) bool {
+			return math_node.Format(args[i]) < math_node.Format(args[j])
+		})
+	}
+
+	fn := &math_node.FunctionNode{FuncName: f.FuncName, Args: args, Pos: f.Pos}
+	if folded, ok := foldFunction(fn); ok {
+		return n.expand(folded)
+	}
+	return []term{opaqueTerm(fn)}
+}
+
+// multiplySums 计算两个多项式的乘积；只有一边是单项式时才展开，避免项数膨胀
+func (n *normalizer) multiplySums(left, right []term) []term {
+	if len(left) == 0 || len(right) == 0 {
+		// 乘以零时保留可能出错的另一个操作数，让求值阶段报告错误，零统一放在前面
+		if other := append(left, right...); n.mayFail(other) {
+			return []term{opaqueTerm(binary("*", number(decimal.Zero), buildSum(other)))}
+		}
+		return nil
+	}
+	if len(left) > 1 && len(right) > 1 {
+		return []term{multiplyTerms(opaqueTerm(buildSum(left)), opaqueTerm(buildSum(right)))}
+	}
+
+	result := make([]term, 0, len(left)*len(right))
+	for _, l := range left {
+		for _, r := range right {
+			result = append(result, multiplyTerms(l, r))
+		}
+	}
+	return n.combine(result)
+}
+
+// multiplyTerms 计算两个单项式的乘积，相同底数的指数相加
+// 分母中可能为零的因子单独合并，只在分母的次数更高时与分子中的相同因子约分，如 y / y ^ 2 得到 1 / y，
+// 约分后分母中仍然保留这个因子，求值时是否出错不变
+func multiplyTerms(a, b term) term {
+	exps := make(map[string]int64, len(a.factors)+len(b.factors))
+	bases := make(map[string]factor, len(a.factors)+len(b.factors))
+	for _, f := range append(append([]factor{}, a.factors...), b.factors...) {
+		key := f.key
+		if f.exp < 0 && !isNonZeroNumber(f.base) {
+			key = "/" + key
+		}
+		exps[key] += f.exp
+		bases[key] = f
+	}
+	for key, exp := range exps {
+		if denominator, ok := exps["/"+key]; ok && exp > 0 && exp < -denominator {
+			exps["/"+key] = denominator + exp
+			exps[key] = 0
+		}
+	}
+
+	factors := make([]factor, 0, len(exps))
+	for key, exp := range exps {
+		if exp != 0 {
+			factors = append(factors, factor{base: bases[key].base, key: bases[key].key, exp: exp})
+		}
+	}
+	sortFactors(factors)
+
+	return term{coef: a.coef.Mul(b.coef), factors: factors}
+}
+
+// divideTerm 单项式除法，系数不能整除时把除数的系数保留为分母中的数字因子
+func divideTerm(t, divisor term) term {
+	inverse := term{coef: decimal.NewFromInt(1), factors: make([]factor, 0, len(divisor.factors)+1)}
+	for _, f := range divisor.factors {
+		inverse.factors = append(inverse.factors, factor{base: f.base, key: f.key, exp: -f.exp})
+	}
+
+	if q, ok := foldBinary("/", t.coef, divisor.coef); ok {
+		t.coef = q
+	} else {
+		if divisor.coef.IsNegative() {
+			inverse.coef = inverse.coef.Neg()
+		}
+		abs := number(divisor.coef.Abs())
+		inverse.factors = append(inverse.factors, factor{base: abs, key: math_node.Format(abs), exp: -1})
+	}
+	return multiplyTerms(t, inverse)
+}
+
+// powerTerm 单项式的整数次幂，累计的指数超过 foldLimits.MaxExponent 或系数的位数超过 foldLimits.MaxDigits 时返回 false
+func powerTerm(t term, power int64) (term, bool) {
+	result := term{coef: decimal.NewFromInt(1)}
+	factors := make([]factor, 0, len(t.factors)+1)
+	for _, f := range t.factors {
+		exp, ok := checkedMul(f.exp, power, foldLimits.MaxExponent)
+		if !ok {
+			return term{}, false
+		}
+		factors = append(factors, factor{base: f.base, key: f.key, exp: exp})
+	}
+	if !canFoldPower(t.coef, abs(power)) {
+		return term{}, false
+	}
+
+	coefPow := math_func.FastPow(t.coef, abs(power))
+	if power > 0 || coefPow.Abs().Equal(decimal.NewFromInt(1)) {
+		result.coef = coefPow
+	} else {
+		// 负指数且系数不为 ±1 时，系数作为分母中的数字因子保留
+		if coefPow.IsNegative() {
+			result.coef = result.coef.Neg()
+		}
+		n := number(coefPow.Abs())
+		factors = append(factors, factor{base: n, key: math_node.Format(n), exp: -1})
+	}
+	result.factors = factors
+	sortFactors(result.factors)
+	return result, true
+}
+
+// mayFail 判断单项式列表在求值时是否可能出错：分母中有可能为零的因子，或者因子本身可能出错
+func (n *normalizer) mayFail(terms []term) bool {
+	for _, t := range terms {
+		for _, f := range t.factors {
+			if (f.exp < 0 && !isNonZeroNumber(f.base)) || n.nodeMayFail(f.base) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// nodeMayFail 判断表达式在求值时是否可能出错：除数不是非零数字的除法、指数不是非负整数的幂运算、
+// 函数调用（函数可能不存在、参数数量或取值可能无效），以及未假定有值的变量
+func (n *normalizer) nodeMayFail(node math_node.Node) bool {
+	switch v := node.(type) {
+	case *math_node.VariableNode:
+		return !n.options.AssumeDefined
+	case *math_node.FunctionNode:
+		return true
+	case *math_node.BinaryOpNode:
+		switch v.Operator {
+		case "/":
+			if !isNonZeroNumber(v.Right) {
+				return true
+			}
+		case "^":
+			if e, ok := numberValue(v.Right); !ok || e.IsNegative() || !e.Equal(e.Floor()) {
+				return true
+			}
+		}
+		return n.nodeMayFail(v.Left) || n.nodeMayFail(v.Right)
+	case *math_node.UnaryOpNode:
+		return n.nodeMayFail(v.Operand)
+	}
+	return false
+}
+
+// isNonZeroNumber 判断节点是否为不等于零的数字
+func isNonZeroNumber(node math_node.Node) bool {
+	v, ok := numberValue(node)
+	return ok && !v.IsZero()
+}
+
+// opaqueTerm 把不可再分解的表达式包装为系数为 1 的单项式
+func opaqueTerm(node math_node.Node) term {
+	return term{
+		coef:    decimal.NewFromInt(1),
+		factors: []factor{{base: node, key: math_node.Format(node), exp: 1}},
+	}
+}
+
+// negateTerms 对所有单项式取反
+func negateTerms(terms []term) []term {
+	result := make([]term, len(terms))
+	for i, t := range terms {
+		result[i] = term{coef: t.coef.Neg(), factors: t.factors}
+	}
+	return result
+}
+
+// combine 合并同类项，去掉系数为零的项并排序
+// 相互抵消的同类项可能出错时保留为系数为零的项，如 x - x 得到 0 * x
+func (n *normalizer) combine(terms []term) []term {
+	index := make(map[string]int, len(terms))
+	result := make([]term, 0, len(terms))
+	for _, t := range terms {
+		key := monomialKey(t)
+		if i, ok := index[key]; ok {
+			result[i].coef = result[i].coef.Add(t.coef)
+			continue
+		}
+		index[key] = len(result)
+		result = append(result, t)
+	}
+
+	nonZero := result[:0]
+	for _, t := range result {
+		if !t.coef.IsZero() || n.mayFail([]term{t}) {
+			nonZero = append(nonZero, t)
+		}
+	}
+	// 次数高的项在前，同次数按字典序，常数项在最后
+	sort.SliceStable(nonZero, func(i, j int) bool {
+		di, dj := degree(nonZero[i]), degree(nonZero[j])
+		if di != dj {
+			return di > dj
+		}
+		return monomialKey(nonZero[i]) < monomialKey(nonZero[j])
+	})
+	return nonZero
+}
+
+// monomialKey 返回单项式除系数外部分的规范键
+func monomialKey(t term) string {
+	parts := make([]string, len(t.factors))
+	for i, f := range t.factors {
+		parts[i] = f.key + "^" + strconv.FormatInt(f.exp, 10)
+	}
+	return strings.Join(parts, "*")
+}
+
+// degree 返回单项式的总次数
+func degree(t term) int64 {
+	var d int64
+	for _, f := range t.factors {
+		d += f.exp
+	}
+	return d
+}
+
+// sortFactors 按规范键排序因子，同一底数在分子和分母中各有一个因子时分子在前
+func sortFactors(factors []factor) {
+	sort.SliceStable(factors, func(i, j int) bool {
+		if factors[i].key != factors[j].key {
+			return factors[i].key < factors[j].key
+		}
+		return factors[i].exp > factors[j].exp
+	})
+}
+
+// buildSum 把单项式列表重建为表达式树
+func buildSum(terms []term) math_node.Node {
+	if len(terms) == 0 {
+		return number(decimal.Zero)
+	}
+
+	result := buildTerm(terms[0])
+	for _, t := range terms[1:] {
+		if t.coef.IsNegative() {
+			result = binary("-", result, buildTerm(term{coef: t.coef.Neg(), factors: t.factors}))
+		} else {
+			result = binary("+", result, buildTerm(t))
+		}
+	}
+	return result
+}
+
+// buildTerm 把单项式重建为表达式树：系数 * 分子因子 / 分母因子
+func buildTerm(t term) math_node.Node {
+	var numerator, denominator []factor
+	for _, f := range t.factors {
+		if f.exp > 0 {
+			numerator = append(numerator, f)
+		} else {
+			denominator = append(denominator, factor{base: f.base, key: f.key, exp: -f.exp})
+		}
+	}
+
+	one := decimal.NewFromInt(1)
+	var result math_node.Node
+	switch {
+	case len(numerator) == 0:
+		result = number(t.coef)
+	case t.coef.Equal(one):
+		result = buildProduct(numerator)
+	case t.coef.Equal(one.Neg()):
+		result = negate(buildProduct(numerator))
+	default:
+		result = binary("*", number(t.coef), buildProduct(numerator))
+	}
+
+	if len(denominator) > 0 {
+		result = binary("/", result, buildProduct(denominator))
+	}
+	return result
+}
+
+// buildProduct 把因子列表重建为乘积
+func buildProduct(factors []factor) math_node.Node {
+	var result math_node.Node
+	for _, f := range factors {
+		var node math_node.Node = f.base
+		if f.exp != 1 {
+			node = binary("^", f.base, number(decimal.NewFromInt(f.exp)))
+		}
+		if result == nil {
+			result = node
+		} else {
+			result = binary("*", result, node)
+		}
+	}
+	return result
+}
@@ -0,0 +1,163 @@
+package symbolic_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/ZHOUXING1997/math_calculation/internal/math_node"
+	"github.com/ZHOUXING1997/math_calculation/internal/symbolic"
+	"github.com/ZHOUXING1997/math_calculation/math_config"
+)
+
+func TestCanonical(t *testing.T) {
+	tests := []struct {
+		name       string
+		expression string
+		want       string
+	}{
+		{name: "交换加法", expression: "b + a", want: "a + b"},
+		{name: "交换乘法", expression: "x * 2", want: "2 * x"},
+		{name: "合并同类项", expression: "x + x", want: "2 * x"},
+		{name: "合并同类幂", expression: "x * x * y", want: "x ^ 2 * y"},
+		{name: "抵消保留可能未定义的变量", expression: "x * y - y * x", want: "0 * (x * y)"},
+		{name: "数字抵消", expression: "2 - 1 - 1", want: "0"},
+		{name: "展开单项式乘法", expression: "2 * (x + y) - y", want: "2 * x + y"},
+		{name: "常数项在最后", expression: "1 + x", want: "x + 1"},
+		{name: "高次项在前", expression: "x + x ^ 2", want: "x ^ 2 + x"},
+		{name: "多项式乘积不展开", expression: "(a + b) * (b + a)", want: "(a + b) ^ 2"},
+		{name: "不精确除法", expression: "x / 3 + x / 3", want: "2 * x / 3"},
+		{name: "精确除法", expression: "x / 2 + x / 2", want: "x"},
+		{name: "变量除法不约分", expression: "a * b / a", want: "a * b / a"},
+		{name: "相同变量相除不约分", expression: "x / x", want: "x / x"},
+		{name: "数字除数约分", expression: "2 * x / 2", want: "x"},
+		{name: "分母合并", expression: "x / y / y", want: "x / y ^ 2"},
+		{name: "分母次数更高时约分", expression: "y / y ^ 2", want: "1 / y"},
+		{name: "分子次数更高时不约分", expression: "y ^ 2 / y", want: "y ^ 2 / y"},
+		{name: "乘以零保留除以零", expression: "0 * (1 / 0)", want: "0 * (1 / 0)"},
+		{name: "乘以零保留变量除法", expression: "(1 / x) * 0", want: "0 * (1 / x)"},
+		{name: "乘以零保留变量", expression: "0 * x", want: "0 * x"},
+		{name: "乘以零消去数字", expression: "0 * (2 + 3)", want: "0"},
+		{name: "乘以零保留无效的函数参数", expression: "sqrt(-1) * 0", want: "0 * sqrt(-1)"},
+		{name: "乘以零保留未知函数", expression: "sqr(x) * 0", want: "0 * sqr(x)"},
+		{name: "乘以零保留参数数量错误", expression: "sqrt(1, 2) * 0", want: "0 * sqrt(1, 2)"},
+		{name: "零次幂保留变量", expression: "x ^ 0", want: "x ^ 0"},
+		{name: "零次幂保留除法", expression: "(1 / x) ^ 0", want: "(1 / x) ^ 0"},
+		{name: "负指数保留分母", expression: "(x / y) ^ -1", want: "(x / y) ^ -1"},
+		{name: "负系数", expression: "0 - 2 * x", want: "-2 * x"},
+		{name: "减法输出", expression: "a - 3 * b", want: "a - 3 * b"},
+		{name: "交换函数参数", expression: "min(b, a)", want: "min(a, b)"},
+		{name: "函数参数规范化", expression: "round(y + x, 2)", want: "round(x + y, 2)"},
+		{name: "函数常量折叠", expression: "x + abs(-2)", want: "x + 2"},
+		{name: "非整数指数", expression: "x ^ y", want: "x ^ y"},
+		{name: "除以零保留", expression: "x / 0", want: "x / 0"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := symbolic.Canonical(parse(t, tt.expression)); got != tt.want {
+				t.Errorf("Canonical(%q) = %q, want %q", tt.expression, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCanonicalWithOptions(t *testing.T) {
+	options := symbolic.NormalizeOptions{AssumeDefined: true}
+	tests := []struct {
+		name       string
+		expression string
+		want       string
+	}{
+		{name: "抵消", expression: "x * y - y * x", want: "0"},
+		{name: "乘以零", expression: "0 * x", want: "0"},
+		{name: "零次幂", expression: "x ^ 0", want: "1"},
+		{name: "仍然保留函数调用", expression: "sqrt(x) * 0", want: "0 * sqrt(x)"},
+		{name: "仍然保留除法", expression: "0 * (1 / x)", want: "0 * (1 / x)"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := symbolic.CanonicalWithOptions(parse(t, tt.expression), options); got != tt.want {
+				t.Errorf("CanonicalWithOptions(%q) = %q, want %q", tt.expression, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCanonical_FailsLikeOriginal(t *testing.T) {
+	// 规范形式在求值时出错当且仅当原表达式出错，x 有值，y 未定义
+	expressions := []string{
+		"sqrt(-1) * 0", "sqr(x) * 0", "sqrt(1, 2) * 0", "x * 0", "y * 0", "y - y",
+		"y ^ 0", "x ^ 0", "(1 / (x - 2)) ^ 0", "0 * (1 / 0)", "x / x", "coalesce(y, 1) * 0",
+		"y / y ^ 2", "(x - 2) / (x - 2) ^ 2", "x ^ 2 / x",
+	}
+	vars := map[string]decimal.Decimal{"x": decimal.NewFromInt(2)}
+	config := math_config.NewDefaultCalcConfig()
+
+	for _, expression := range expressions {
+		t.Run(expression, func(t *testing.T) {
+			node := parse(t, expression)
+			_, want := node.Eval(context.Background(), vars, config)
+			normalized := symbolic.Normalize(node)
+			if _, got := normalized.Eval(context.Background(), vars, config); (got != nil) != (want != nil) {
+				t.Errorf("Normalize(%q) = %q evaluates with error %v, original error %v", expression, math_node.Format(normalized), got, want)
+			}
+		})
+	}
+}
+
+func TestCanonical_Equivalent(t *testing.T) {
+	// 常见的等价写法应该得到相同的规范字符串
+	groups := [][]string{
+		{"a+b", "b + a", "(b) + (a)"},
+		{"qty * unit_price * (1 - discount)", "(1 - discount) * unit_price * qty", "qty*unit_price - qty*unit_price*discount"},
+		{"2*x + 3*y", "y + x + 2*y + x"},
+	}
+
+	for _, group := range groups {
+		want := symbolic.Canonical(parse(t, group[0]))
+		for _, expression := range group[1:] {
+			if got := symbolic.Canonical(parse(t, expression)); got != want {
+				t.Errorf("Canonical(%q) = %q, want %q (same as %q)", expression, got, want, group[0])
+			}
+		}
+	}
+}
+
+func TestCanonical_NestedPower(t *testing.T) {
+	tests := []struct {
+		name       string
+		expression string
+		maxLength  int
+	}{
+		{name: "两层幂折叠", expression: "(9 ^ 64) ^ 64", maxLength: 4000},
+		{name: "三层幂不再折叠", expression: "((9 ^ 64) ^ 64) ^ 64", maxLength: 4000},
+		{name: "四层幂不再折叠", expression: "(((9 ^ 64) ^ 64) ^ 64) ^ 64", maxLength: 4000},
+		{name: "变量的累计指数", expression: "((((x ^ 64) ^ 64) ^ 64) ^ 64) ^ 64", maxLength: 100},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start := time.Now()
+			canonical := symbolic.Canonical(parse(t, tt.expression))
+			simplified := math_node.Format(symbolic.Simplify(parse(t, tt.expression)))
+			if elapsed := time.Since(start); elapsed > time.Second {
+				t.Errorf("Canonical(%q) and Simplify() took %v", tt.expression, elapsed)
+			}
+			if len(canonical) > tt.maxLength || len(simplified) > tt.maxLength {
+				t.Errorf("Canonical(%q) has %d characters and Simplify() has %d, want <= %d", tt.expression, len(canonical), len(simplified), tt.maxLength)
+			}
+		})
+	}
+
+	// 累计指数超过上限时不同的表达式得到不同的规范形式
+	a := symbolic.Canonical(parse(t, "((((x ^ 64) ^ 64) ^ 64) ^ 64) ^ 64"))
+	b := symbolic.Canonical(parse(t, "((((x ^ 64) ^ 64) ^ 64) ^ 64) ^ 63"))
+	if a == b || !strings.Contains(a, "^") {
+		t.Errorf("Canonical() = %q and %q, want distinct keys", a, b)
+	}
+}
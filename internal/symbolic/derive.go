@@ -0,0 +1,161 @@
+package symbolic
+
+import (
+	"github.com/shopspring/decimal"
+
+	"github.com/ZHOUXING1997/math_calculation/internal"
+	"github.com/ZHOUXING1997/math_calculation/internal/math_node"
+)
+
+// Derive 对表达式树关于变量 name 求导，返回化简后的导数表达式树
+// 支持 + - * / ^ 运算符以及 sqrt、abs（分段）、pow 函数；
+// 不依赖 name 的子树导数为 0，其余无法求导的情况返回 internal.ErrNotDifferentiable
+// 导数先消除求导产生的 0 和 1，再合并同类项并转换为规范形式，如 x * x * x 的导数为 3 * x ^ 2
+func Derive(node math_node.Node, name string) (math_node.Node, error) {
+	d, err := derive(node, name)
+	if err != nil {
+		return nil, err
+	}
+	// 乘积法则产生的 0 * y 等项已经由 Simplify 消去，导数中的变量与原表达式中的变量相同，假定都有值
+	return NormalizeWithOptions(Simplify(d), NormalizeOptions{AssumeDefined: true}), nil
+}
+
+// derive 递归求导，不做化简
+func derive(node math_node.Node, name string) (math_node.Node, error) {
+	// 不依赖求导变量的子树是常数
+	if !DependsOn(node, name) {
+		return number(decimal.Zero), nil
+	}
+
+	switch n := node.(type) {
+	case *math_node.VariableNode:
+		// 依赖检查已经保证变量名相同
+		return number(decimal.NewFromInt(1)), nil
+	case *math_node.UnaryOpNode:
+		d, err := derive(n.Operand, name)
+		if err != nil {
+			return nil, err
+		}
+		switch n.Operator {
+		case "-":
+			return &math_node.UnaryOpNode{Operator: "-", Operand: d}, nil
+		case "+":
+			return d, nil
+		}
+		return nil, notDifferentiable(n.Pos, internal.MsgDeriveUnaryOperator, n.Operator)
+	case *math_node.BinaryOpNode:
+		return deriveBinary(n, name)
+	case *math_node.FunctionNode:
+		return deriveFunction(n, name)
+	}
+
+	return nil, notDifferentiable(0, internal.MsgDeriveNodeType)
+}
+
+// deriveBinary 二元运算符求导
+func deriveBinary(n *math_node.BinaryOpNode, name string) (math_node.Node, error) {
+	if n.Operator == "^" {
+		return derivePower(n.Left, n.Right, name, n.Pos, func(base, exp math_node.Node) math_node.Node {
+			return binary("^", base, exp)
+		})
+	}
+
+	dl, err := derive(n.Left, name)
+	if err != nil {
+		return nil, err
+	}
+	dr, err := derive(n.Right, name)
+	if err != nil {
+		return nil, err
+	}
+
+	switch n.Operator {
+	case "+", "-":
+		return binary(n.Operator, dl, dr), nil
+	case "*":
+		// (uv)' = u'v + uv'
+		return binary("+", binary("*", dl, n.Right), binary("*", n.Left, dr)), nil
+	case "/":
+		// (u/v)' = (u'v - uv') / v^2
+		numerator := binary("-", binary("*", dl, n.Right), binary("*", n.Left, dr))
+		return binary("/", numerator, binary("^", n.Right, number(decimal.NewFromInt(2)))), nil
+	}
+
+	return nil, notDifferentiable(n.Pos, internal.MsgDeriveOperator, n.Operator)
+}
+
+// derivePower 幂运算求导，仅支持指数不依赖求导变量的情况：(u^c)' = c * u^(c-1) * u'
+// pow 用于构造 u^(c-1)，使 ^ 与 pow() 各自保持原来的写法
+func derivePower(base, exp math_node.Node, name string, pos int, pow func(base, exp math_node.Node) math_node.Node) (math_node.Node, error) {
+	if DependsOn(exp, name) {
+		// 指数依赖变量时需要对数函数，目前不支持
+		return nil, notDifferentiable(pos, internal.MsgDeriveExponent, name)
+	}
+
+	db, err := derive(base, name)
+	if err != nil {
+		return nil, err
+	}
+
+	reduced := pow(base, binary("-", exp, number(decimal.NewFromInt(1))))
+	return binary("*", binary("*", exp, reduced), db), nil
+}
+
+// deriveFunction 函数求导
+func deriveFunction(n *math_node.FunctionNode, name string) (math_node.Node, error) {
+	switch n.FuncName {
+	case "sqrt":
+		if len(n.Args) != 1 {
+			break
+		}
+		// sqrt(u)' = u' / (2 * sqrt(u))
+		du, err := derive(n.Args[0], name)
+		if err != nil {
+			return nil, err
+		}
+		return binary("/", du, binary("*", number(decimal.NewFromInt(2)), n)), nil
+	case "abs":
+		if len(n.Args) != 1 {
+			break
+		}
+		// abs(u)' = u' * u / abs(u)，在 u = 0 处无定义，求值时会得到除以零错误
+		du, err := derive(n.Args[0], name)
+		if err != nil {
+			return nil, err
+		}
+		return binary("*", du, binary("/", n.Args[0], n)), nil
+	case "pow":
+		if len(n.Args) != 2 {
+			break
+		}
+		return derivePower(n.Args[0], n.Args[1], name, n.Pos, func(base, exp math_node.Node) math_node.Node {
+			return &math_node.FunctionNode{FuncName: "pow", Args: []math_node.Node{base, exp}}
+		})
+	}
+
+	return nil, notDifferentiable(n.Pos, internal.MsgDeriveFunction, n.FuncName, name)
+}
+
+// DependsOn 判断表达式树是否引用了变量 name
+func DependsOn(node math_node.Node, name string) bool {
+	switch n := node.(type) {
+	case *math_node.VariableNode:
+		return n.VarName == name
+	case *math_node.UnaryOpNode:
+		return DependsOn(n.Operand, name)
+	case *math_node.BinaryOpNode:
+		return DependsOn(n.Left, name) || DependsOn(n.Right, name)
+	case *math_node.FunctionNode:
+		for _, arg := range n.Args {
+			if DependsOn(arg, name) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// notDifferentiable 创建无法求导错误
+func notDifferentiable(pos int, key string, args ...interface{}) error {
+	return internal.NewParseError(pos, internal.ErrNotDifferentiable, "", key, args...)
+}
@@ -0,0 +1,106 @@
+package symbolic_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/ZHOUXING1997/math_calculation/internal"
+	"github.com/ZHOUXING1997/math_calculation/internal/croe"
+	"github.com/ZHOUXING1997/math_calculation/internal/math_node"
+	"github.com/ZHOUXING1997/math_calculation/internal/symbolic"
+	"github.com/ZHOUXING1997/math_calculation/math_config"
+)
+
+// parse 解析测试表达式
+func parse(t *testing.T, expression string) math_node.Node {
+	t.Helper()
+	node, err := croe.NewParser(nil, math_config.NewDefaultCalcConfig()).Parse(expression)
+	if err != nil {
+		t.Fatalf("Parse(%q) error = %v", expression, err)
+	}
+	return node
+}
+
+func TestDerive(t *testing.T) {
+	tests := []struct {
+		name       string
+		expression string
+		variable   string
+		want       string
+	}{
+		{name: "常数", expression: "5", variable: "x", want: "0"},
+		{name: "自身", expression: "x", variable: "x", want: "1"},
+		{name: "其他变量", expression: "y", variable: "x", want: "0"},
+		{name: "加法", expression: "x + y", variable: "x", want: "1"},
+		{name: "减法", expression: "y - x", variable: "x", want: "-1"},
+		{name: "一元负号", expression: "-x", variable: "x", want: "-1"},
+		{name: "乘法", expression: "x * y", variable: "x", want: "y"},
+		{name: "乘积法则", expression: "x * x", variable: "x", want: "2 * x"},
+		{name: "乘积法则合并同类项", expression: "x * x * x", variable: "x", want: "3 * x ^ 2"},
+		{name: "除法", expression: "1 / x", variable: "x", want: "-1 / x ^ 2"},
+		{name: "商法则", expression: "x / y", variable: "x", want: "1 / y"},
+		{name: "商法则约分", expression: "x / y ^ 2", variable: "x", want: "1 / y ^ 2"},
+		{name: "幂运算", expression: "x ^ 3", variable: "x", want: "3 * x ^ 2"},
+		{name: "平方", expression: "x ^ 2", variable: "x", want: "2 * x"},
+		{name: "pow函数", expression: "pow(x, 3)", variable: "x", want: "3 * pow(x, 2)"},
+		{name: "pow函数一次幂", expression: "pow(x, 2)", variable: "x", want: "2 * x"},
+		{name: "平方根", expression: "sqrt(x)", variable: "x", want: "0.5 / sqrt(x)"},
+		{name: "绝对值", expression: "abs(x)", variable: "x", want: "x / abs(x)"},
+		{name: "定价公式", expression: "price * (1 - discount)", variable: "discount", want: "-price"},
+		{name: "不依赖变量的函数", expression: "round(y, 2) * x", variable: "x", want: "round(y, 2)"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := symbolic.Derive(parse(t, tt.expression), tt.variable)
+			if err != nil {
+				t.Fatalf("Derive() error = %v", err)
+			}
+			if s := math_node.Format(got); s != tt.want {
+				t.Errorf("Derive(%q, %q) = %q, want %q", tt.expression, tt.variable, s, tt.want)
+			}
+		})
+	}
+}
+
+func TestDerive_Evaluate(t *testing.T) {
+	// d/dx (x^3 + 2*x) 在 x = 2 处等于 3*4 + 2 = 14
+	d, err := symbolic.Derive(parse(t, "x ^ 3 + 2 * x"), "x")
+	if err != nil {
+		t.Fatalf("Derive() error = %v", err)
+	}
+
+	vars := map[string]decimal.Decimal{"x": decimal.NewFromInt(2)}
+	got, err := d.Eval(context.Background(), vars, math_config.NewDefaultCalcConfig())
+	if err != nil {
+		t.Fatalf("Eval() error = %v", err)
+	}
+	if !got.Equal(decimal.NewFromInt(14)) {
+		t.Errorf("Eval() = %v, want 14", got)
+	}
+}
+
+func TestDerive_NotDifferentiable(t *testing.T) {
+	tests := []struct {
+		name       string
+		expression string
+	}{
+		{name: "指数依赖变量", expression: "2 ^ x"},
+		{name: "pow指数依赖变量", expression: "pow(2, x)"},
+		{name: "取整函数", expression: "floor(x)"},
+		{name: "最值函数", expression: "max(x, 1)"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := symbolic.Derive(parse(t, tt.expression), "x")
+			var parseErr *internal.ParseError
+			if !errors.As(err, &parseErr) || parseErr.Cause != internal.ErrNotDifferentiable {
+				t.Errorf("Derive() error = %v, want ErrNotDifferentiable", err)
+			}
+		})
+	}
+}
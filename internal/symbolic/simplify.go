@@ -0,0 +1,272 @@
+package symbolic
+
+import (
+	"context"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/ZHOUXING1997/math_calculation/internal/math_func"
+	"github.com/ZHOUXING1997/math_calculation/internal/math_node"
+	"github.com/ZHOUXING1997/math_calculation/math_config"
+)
+
+// maxFoldExponent 常量折叠时允许的最大整数指数，避免化简阶段产生巨大的数字
+const maxFoldExponent = 64
+
+// foldLimits 折叠嵌套的幂运算时累计指数和结果位数的上限，取默认配置的 MaxExponent 和 MaxDigits
+// 每一层的指数都不超过 maxFoldExponent 时，嵌套的幂运算仍然会让指数和位数成倍增长，超过上限时保留幂运算不折叠
+var foldLimits = math_config.NewDefaultCalcConfig()
+
+// canFoldPower 判断 base 的 power 次幂的位数是否不超过 foldLimits.MaxDigits
+func canFoldPower(base decimal.Decimal, power int64) bool {
+	digits := int64(len(base.Coefficient().String())) + abs(int64(base.Exponent()))
+	_, ok := checkedMul(digits, power, int64(foldLimits.MaxDigits))
+	return ok
+}
+
+// checkedMul 返回 a * b，乘积的绝对值超过 limit 时返回 false，不会溢出
+func checkedMul(a, b, limit int64) (int64, bool) {
+	if a == 0 || b == 0 {
+		return 0, true
+	}
+	if abs(a) > limit/abs(b) {
+		return 0, false
+	}
+	return a * b, true
+}
+
+// abs 返回整数的绝对值
+func abs(n int64) int64 {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+// Simplify 对表达式树进行代数化简，返回新的表达式树，不会修改输入的节点
+// 化简只做精确的变换：常量折叠（结果不精确时保留原式）和 0、1 等单位元的消除
+func Simplify(node math_node.Node) math_node.Node {
+	switch n := node.(type) {
+	case *math_node.BinaryOpNode:
+		return simplifyBinary(n.Operator, Simplify(n.Left), Simplify(n.Right))
+	case *math_node.UnaryOpNode:
+		operand := Simplify(n.Operand)
+		if n.Operator == "-" {
+			return negate(operand)
+		}
+		if n.Operator == "+" {
+			return operand
+		}
+		return &math_node.UnaryOpNode{Operator: n.Operator, Operand: operand, Pos: n.Pos}
+	case *math_node.FunctionNode:
+		args := make([]math_node.Node, len(n.Args))
+		for i, arg := range n.Args {
+			args[i] = Simplify(arg)
+		}
+		fn := &math_node.FunctionNode{FuncName: n.FuncName, Args: args, Pos: n.Pos}
+		if folded, ok := foldFunction(fn); ok {
+			return folded
+		}
+		// pow(x, 1) 与 x ^ 1 相同地化简
+		if fn.FuncName == "pow" && len(args) == 2 && isNumber(args[1], 1) {
+			return args[0]
+		}
+		return fn
+	default:
+		// 数字和变量节点是叶子节点，直接复用
+		return node
+	}
+}
+
+// simplifyBinary 化简已化简过操作数的二元运算
+func simplifyBinary(operator string, left, right math_node.Node) math_node.Node {
+	// 两个操作数都是数字时尝试常量折叠
+	if l, ok := numberValue(left); ok {
+		if r, ok := numberValue(right); ok {
+			if folded, ok := foldBinary(operator, l, r); ok {
+				return number(folded)
+			}
+		}
+	}
+
+	switch operator {
+	case "+":
+		if isNumber(left, 0) {
+			return right
+		}
+		if isNumber(right, 0) {
+			return left
+		}
+		// x + -y 改写为 x - y
+		if inner, ok := negated(right); ok {
+			return simplifyBinary("-", left, inner)
+		}
+	case "-":
+		if isNumber(right, 0) {
+			return left
+		}
+		if isNumber(left, 0) {
+			return negate(right)
+		}
+		// x - -y 改写为 x + y
+		if inner, ok := negated(right); ok {
+			return simplifyBinary("+", left, inner)
+		}
+	case "*":
+		if isNumber(left, 0) || isNumber(right, 0) {
+			return number(decimal.Zero)
+		}
+		if isNumber(left, 1) {
+			return right
+		}
+		if isNumber(right, 1) {
+			return left
+		}
+		if isNumber(left, -1) {
+			return negate(right)
+		}
+		if isNumber(right, -1) {
+			return negate(left)
+		}
+	case "/":
+		if isNumber(right, 1) {
+			return left
+		}
+		if isNumber(right, -1) {
+			return negate(left)
+		}
+	case "^":
+		if isNumber(right, 0) {
+			return number(decimal.NewFromInt(1))
+		}
+		if isNumber(right, 1) {
+			return left
+		}
+		if isNumber(left, 1) {
+			return number(decimal.NewFromInt(1))
+		}
+	}
+
+	return binary(operator, left, right)
+}
+
+// foldBinary 对两个数字进行精确的二元运算，结果不精确时返回 false
+func foldBinary(operator string, l, r decimal.Decimal) (decimal.Decimal, bool) {
+	switch operator {
+	case "+":
+		return l.Add(r), true
+	case "-":
+		return l.Sub(r), true
+	case "*":
+		return l.Mul(r), true
+	case "/":
+		if r.IsZero() {
+			return decimal.Zero, false
+		}
+		q := l.Div(r)
+		if !q.Mul(r).Equal(l) {
+			return decimal.Zero, false
+		}
+		return q, true
+	case "^":
+		// 只折叠较小的非负整数指数，与求值器的整数幂语义保持一致
+		if !r.Equal(r.Floor()) || r.IsNegative() || r.GreaterThan(decimal.NewFromInt(maxFoldExponent)) || !canFoldPower(l, r.IntPart()) {
+			return decimal.Zero, false
+		}
+		return math_func.FastPow(l, r.IntPart()), true
+	}
+	return decimal.Zero, false
+}
+
+// foldFunction 对参数全部为数字的函数调用进行精确的常量折叠
+func foldFunction(n *math_node.FunctionNode) (math_node.Node, bool) {
+	for _, arg := range n.Args {
+		if _, ok := numberValue(arg); !ok {
+			return nil, false
+		}
+	}
+
+	switch n.FuncName {
+	case "pow":
+		// 负指数需要做除法，结果不一定精确
+		if len(n.Args) != 2 {
+			return nil, false
+		}
+		base, _ := numberValue(n.Args[0])
+		exp, _ := numberValue(n.Args[1])
+		if !exp.Equal(exp.Floor()) || exp.IsNegative() || exp.GreaterThan(decimal.NewFromInt(maxFoldExponent)) || !canFoldPower(base, exp.IntPart()) {
+			return nil, false
+		}
+	case "sqrt", "abs", "round", "ceil", "floor", "min", "max", "coalesce", "isdefined":
+	default:
+		return nil, false
+	}
+
+	// 使用不在每一步控制精度的配置求值，保证折叠结果不丢失精度
+	config := math_config.NewDefaultCalcConfig()
+	config.ApplyPrecisionEachStep = false
+	val, err := n.Eval(context.Background(), nil, config)
+	if err != nil {
+		return nil, false
+	}
+
+	// 平方根只在结果精确时折叠
+	if n.FuncName == "sqrt" {
+		arg, _ := numberValue(n.Args[0])
+		if !val.Mul(val).Equal(arg) {
+			return nil, false
+		}
+	}
+
+	return number(val), true
+}
+
+// negate 返回节点的相反数，尽量避免产生多余的一元负号
+func negate(node math_node.Node) math_node.Node {
+	if v, ok := numberValue(node); ok {
+		return number(v.Neg())
+	}
+	if inner, ok := negated(node); ok {
+		return inner
+	}
+	return &math_node.UnaryOpNode{Operator: "-", Operand: node}
+}
+
+// negated 如果节点是负号表达式（一元负号或负数），返回其相反数
+func negated(node math_node.Node) (math_node.Node, bool) {
+	switch n := node.(type) {
+	case *math_node.UnaryOpNode:
+		if n.Operator == "-" {
+			return n.Operand, true
+		}
+	case *math_node.NumberNode:
+		if n.Value.IsNegative() {
+			return number(n.Value.Neg()), true
+		}
+	}
+	return nil, false
+}
+
+// numberValue 如果节点是数字节点，返回其值
+func numberValue(node math_node.Node) (decimal.Decimal, bool) {
+	if n, ok := node.(*math_node.NumberNode); ok {
+		return n.Value, true
+	}
+	return decimal.Zero, false
+}
+
+// isNumber 判断节点是否为指定整数值的数字节点
+func isNumber(node math_node.Node, value int64) bool {
+	v, ok := numberValue(node)
+	return ok && v.Equal(decimal.NewFromInt(value))
+}
+
+// number 创建数字节点
+func number(value decimal.Decimal) math_node.Node {
+	return &math_node.NumberNode{Value: value}
+}
+
+// binary 创建二元运算符节点
+func binary(operator string, left, right math_node.Node) math_node.Node {
+	return &math_node.BinaryOpNode{Left: left, Operator: operator, Right: right}
+}
@@ -0,0 +1,45 @@
+package symbolic_test
+
+import (
+	"testing"
+
+	"github.com/ZHOUXING1997/math_calculation/internal/math_node"
+	"github.com/ZHOUXING1997/math_calculation/internal/symbolic"
+)
+
+func TestSimplify(t *testing.T) {
+	tests := []struct {
+		name       string
+		expression string
+		want       string
+	}{
+		{name: "常量折叠", expression: "2 * 3 + 4", want: "10"},
+		{name: "精确除法折叠", expression: "6 / 4", want: "1.5"},
+		{name: "不精确除法保留", expression: "1 / 3", want: "1 / 3"},
+		{name: "加零", expression: "x + 0", want: "x"},
+		{name: "零减", expression: "0 - x", want: "-x"},
+		{name: "乘一", expression: "1 * x", want: "x"},
+		{name: "乘零", expression: "x * 0", want: "0"},
+		{name: "乘负一", expression: "x * -1", want: "-x"},
+		{name: "除以一", expression: "x / 1", want: "x"},
+		{name: "零次幂", expression: "x ^ 0", want: "1"},
+		{name: "一次幂", expression: "x ^ 1", want: "x"},
+		{name: "pow一次幂", expression: "pow(x, 1)", want: "x"},
+		{name: "双重负号", expression: "-(-x)", want: "x"},
+		{name: "加负数", expression: "x + -2", want: "x - 2"},
+		{name: "减负数", expression: "x - -2", want: "x + 2"},
+		{name: "函数折叠", expression: "max(1, 2) + abs(-3)", want: "5"},
+		{name: "不精确平方根保留", expression: "sqrt(2)", want: "sqrt(2)"},
+		{name: "精确平方根折叠", expression: "sqrt(16)", want: "4"},
+		{name: "含变量的函数", expression: "round(x * 1, 2)", want: "round(x, 2)"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := math_node.Format(symbolic.Simplify(parse(t, tt.expression)))
+			if got != tt.want {
+				t.Errorf("Simplify(%q) = %q, want %q", tt.expression, got, tt.want)
+			}
+		})
+	}
+}
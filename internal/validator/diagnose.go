@@ -0,0 +1,143 @@
+package validator
+
+import (
+	"sort"
+
+	"github.com/ZHOUXING1997/math_calculation/math_config"
+
+	"github.com/ZHOUXING1997/math_calculation/internal"
+	"github.com/ZHOUXING1997/math_calculation/internal/croe"
+	"github.com/ZHOUXING1997/math_calculation/internal/math_node"
+)
+
+// DiagnoseOptions 诊断选项
+type DiagnoseOptions struct {
+	ValidationOptions          // 验证选项
+	Variables         []string // 已声明的变量，为 nil 时不检查未定义的变量
+	Locale            string   // 错误消息语言，空表示中文
+}
+
+// Diagnose 一次性检查表达式中的所有问题，适用于编辑器中的实时检查
+// 包括所有语法错误（跳过多余的运算符，在逗号、右括号处恢复后继续解析）、未知函数、函数参数数量错误、
+// 未声明的变量以及违反验证选项的地方。与计算时相同，检查的是净化后的表达式，错误的位置对应原始表达式。
+// 错误按位置排序，没有问题时返回 nil
+func Diagnose(expression string, options DiagnoseOptions) []error {
+	var errs []error
+
+	// 验证错误，括号不匹配由解析器报告，这里不再重复
+	for _, err := range ValidateAll(expression, options.ValidationOptions) {
+		if validationErr, ok := err.(*ValidationError); ok && validationErr.Key == internal.MsgUnbalancedParentheses {
+			continue
+		}
+		errs = append(errs, err)
+	}
+
+	// 语法错误，解析净化后的表达式，错误位置映射回原始表达式
+	sanitized, offsets := SanitizeWithOffsets(expression)
+	parser := croe.NewParser(nil, &math_config.CalcConfig{})
+	ast, syntaxErrs := parser.ParseAll(sanitized)
+	for _, err := range syntaxErrs {
+		errs = append(errs, internal.Relocate(err, expression, offsets))
+	}
+
+	// 未知函数、参数数量和未声明的变量
+	if ast != nil {
+		for _, err := range checkNames(ast, sanitized, options.Variables) {
+			errs = append(errs, internal.Relocate(err, expression, offsets))
+		}
+	}
+
+	if len(errs) == 0 {
+		return nil
+	}
+
+	sort.SliceStable(errs, func(i, j int) bool {
+		return errorPos(errs[i]) < errorPos(errs[j])
+	})
+	for i, err := range errs {
+		errs[i] = internal.Localize(err, options.Locale)
+	}
+	return errs
+}
+
+// checkNames 检查表达式树中的函数和变量
+func checkNames(ast math_node.Node, expression string, variables []string) []error {
+	var declared map[string]bool
+	if variables != nil {
+		declared = make(map[string]bool, len(variables))
+		for _, name := range variables {
+			declared[name] = true
+		}
+	}
+
+	var errs []error
+	report := func(err *internal.ParseError) {
+		errs = append(errs, internal.Locate(err, expression))
+	}
+
+	optional := optionalVariables(ast)
+	math_node.Walk(ast, func(node math_node.Node) bool {
+		switch n := node.(type) {
+		case *math_node.VariableNode:
+			if declared != nil && !declared[n.VarName] && !optional[n] {
+				report(internal.NewParseError(n.Pos, internal.ErrUndefinedVariable, n.VarName, internal.MsgUndefinedVariable, n.VarName).
+					WithSuggestions(internal.Suggest(n.VarName, variables)))
+			}
+		case *math_node.FunctionNode:
+			arity, ok := math_node.BuiltinFunctions[n.FuncName]
+			switch {
+			case !ok:
+				report(internal.NewParseError(n.Pos, internal.ErrUnsupportedOperator, n.FuncName, internal.MsgUnsupportedFunction, n.FuncName).
+					WithSuggestions(internal.Suggest(n.FuncName, math_node.FunctionNames())))
+			case arity.Max < 0 && len(n.Args) < arity.Min:
+				report(internal.NewParseError(n.Pos, internal.ErrInvalidArgument, n.FuncName, internal.MsgArgumentCountMin, n.FuncName, arity.Min))
+			case arity.Max >= 0 && arity.Min == arity.Max && len(n.Args) != arity.Min:
+				report(internal.NewParseError(n.Pos, internal.ErrInvalidArgument, n.FuncName, internal.MsgArgumentCountExact, n.FuncName, arity.Min, len(n.Args)))
+			case arity.Max >= 0 && (len(n.Args) < arity.Min || len(n.Args) > arity.Max):
+				report(internal.NewParseError(n.Pos, internal.ErrInvalidArgument, n.FuncName, internal.MsgArgumentCountRange, n.FuncName, arity.Min, arity.Max, len(n.Args)))
+			}
+		}
+		return true
+	})
+	return errs
+}
+
+// optionalVariables 返回允许没有值的变量：isdefined 的参数和 coalesce 除最后一个参数外的参数中的变量
+// 计算时这些变量没有值不会出错，与 FunctionNode 的计算规则一致
+func optionalVariables(ast math_node.Node) map[*math_node.VariableNode]bool {
+	optional := make(map[*math_node.VariableNode]bool)
+	math_node.Walk(ast, func(node math_node.Node) bool {
+		n, ok := node.(*math_node.FunctionNode)
+		if !ok {
+			return true
+		}
+		var args []math_node.Node
+		switch {
+		case n.FuncName == "isdefined":
+			args = n.Args
+		case n.FuncName == "coalesce" && len(n.Args) > 0:
+			args = n.Args[:len(n.Args)-1]
+		}
+		for _, arg := range args {
+			math_node.Walk(arg, func(node math_node.Node) bool {
+				if v, ok := node.(*math_node.VariableNode); ok {
+					optional[v] = true
+				}
+				return true
+			})
+		}
+		return true
+	})
+	return optional
+}
+
+// errorPos 返回错误的位置
+func errorPos(err error) int {
+	switch e := err.(type) {
+	case *internal.ParseError:
+		return e.Pos
+	case *ValidationError:
+		return e.Pos
+	}
+	return 0
+}
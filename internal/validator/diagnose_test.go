@@ -0,0 +1,229 @@
+package validator
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/ZHOUXING1997/math_calculation/internal"
+)
+
+func TestDiagnose(t *testing.T) {
+	options := DefaultValidationOptions
+	options.DisallowedFunctions = []string{"pow"}
+
+	tests := []struct {
+		name       string
+		expression string
+		variables  []string
+		wantTokens []string
+		wantCodes  []internal.ErrorCode
+	}{
+		{
+			name:       "没有错误",
+			expression: "max(a, b) * 2",
+			variables:  []string{"a", "b"},
+			wantTokens: nil,
+			wantCodes:  nil,
+		},
+		{
+			name:       "语法错误",
+			expression: "1 + * 2, )",
+			wantTokens: []string{"*", ",", ")"},
+			wantCodes: []internal.ErrorCode{
+				internal.CodeInvalidExpression,
+				internal.CodeInvalidExpression,
+				internal.CodeInvalidExpression,
+			},
+		},
+		{
+			name:       "未知函数和未声明的变量",
+			expression: "foo(x) + bar * y",
+			variables:  []string{"x"},
+			wantTokens: []string{"foo", "bar", "y"},
+			wantCodes: []internal.ErrorCode{
+				internal.CodeUnsupportedOperator,
+				internal.CodeUndefinedVariable,
+				internal.CodeUndefinedVariable,
+			},
+		},
+		{
+			name:       "不检查变量",
+			expression: "a + b",
+			variables:  nil,
+			wantTokens: nil,
+			wantCodes:  nil,
+		},
+		{
+			name:       "参数数量错误",
+			expression: "sqrt(1, 2) + round() + max()",
+			wantTokens: []string{"sqrt", "round", "max"},
+			wantCodes: []internal.ErrorCode{
+				internal.CodeInvalidArgument,
+				internal.CodeInvalidArgument,
+				internal.CodeInvalidArgument,
+			},
+		},
+		{
+			name:       "验证错误与语法错误按位置排序",
+			expression: "pow(a, 2) + (1 +",
+			wantTokens: []string{"pow", "(", ""},
+			wantCodes: []internal.ErrorCode{
+				internal.CodeValidationFailed,
+				internal.CodeInvalidExpression,
+				internal.CodeInvalidExpression,
+			},
+		},
+		{
+			name:       "coalesce 和 isdefined 的参数中允许未声明的变量",
+			expression: "coalesce(y * 2, 1) + isdefined(z) + coalesce(1, w)",
+			variables:  []string{"x"},
+			wantTokens: []string{"w"},
+			wantCodes:  []internal.ErrorCode{internal.CodeUndefinedVariable},
+		},
+		{
+			name:       "单个右括号只报告一次",
+			expression: ")",
+			wantTokens: []string{")"},
+			wantCodes:  []internal.ErrorCode{internal.CodeInvalidExpression},
+		},
+		{
+			name:       "连续的右括号",
+			expression: "))",
+			wantTokens: []string{")", ")"},
+			wantCodes:  []internal.ErrorCode{internal.CodeInvalidExpression, internal.CodeInvalidExpression},
+		},
+		{
+			name:       "连续的逗号",
+			expression: ",,,",
+			wantTokens: []string{",", ",", ","},
+			wantCodes: []internal.ErrorCode{
+				internal.CodeInvalidExpression,
+				internal.CodeInvalidExpression,
+				internal.CodeInvalidExpression,
+			},
+		},
+		{
+			name:       "表达式中间的右括号",
+			expression: "1+)+(",
+			wantTokens: []string{")", "(", ""},
+			wantCodes: []internal.ErrorCode{
+				internal.CodeInvalidExpression,
+				internal.CodeInvalidExpression,
+				internal.CodeInvalidExpression,
+			},
+		},
+		{
+			name:       "函数参数和结尾的多余标记",
+			expression: "max(1,,2)+)",
+			wantTokens: []string{",", ")"},
+			wantCodes:  []internal.ErrorCode{internal.CodeInvalidExpression, internal.CodeInvalidExpression},
+		},
+		{
+			name:       "同一层中的多个语法错误",
+			expression: "1 + * 2 + * 3",
+			wantTokens: []string{"*", "*"},
+			wantCodes:  []internal.ErrorCode{internal.CodeInvalidExpression, internal.CodeInvalidExpression},
+		},
+		{
+			name:       "多余的运算符、空括号和结尾",
+			expression: "1 +* / 2 * (3 + ) + 4 /",
+			wantTokens: []string{"*", ")", ""},
+			wantCodes: []internal.ErrorCode{
+				internal.CodeInvalidExpression,
+				internal.CodeInvalidExpression,
+				internal.CodeInvalidExpression,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			errs := Diagnose(tt.expression, DiagnoseOptions{
+				ValidationOptions: options,
+				Variables:         tt.variables,
+			})
+
+			var tokens []string
+			var codes []internal.ErrorCode
+			for _, err := range errs {
+				switch e := err.(type) {
+				case *internal.ParseError:
+					tokens = append(tokens, e.Token)
+				case *ValidationError:
+					tokens = append(tokens, e.Token)
+				default:
+					t.Fatalf("Diagnose() error = %v, want *ParseError or *ValidationError", err)
+				}
+				codes = append(codes, internal.CodeOf(err))
+			}
+			if !reflect.DeepEqual(tokens, tt.wantTokens) {
+				t.Errorf("Diagnose() tokens = %q, want %q", tokens, tt.wantTokens)
+			}
+			if !reflect.DeepEqual(codes, tt.wantCodes) {
+				t.Errorf("Diagnose() codes = %q, want %q", codes, tt.wantCodes)
+			}
+		})
+	}
+}
+
+func TestDiagnose_Sanitized(t *testing.T) {
+	// 与计算时相同，检查净化后的表达式，位置对应原始表达式
+	tests := []struct {
+		name       string
+		expression string
+		variables  []string
+		wantPos    []int
+	}{
+		{name: "删除无效 UTF-8 字节后没有错误", expression: "1\xff2", wantPos: nil},
+		{name: "首部空白", expression: "  1 + * 2 - / 3", wantPos: []int{6, 12}},
+		{name: "无效 UTF-8 字节之后的错误", expression: "x\xff + * y", variables: []string{}, wantPos: []int{0, 5, 7}},
+		{name: "合并的加减号之后的错误", expression: "1 +-+ * 2", wantPos: []int{6}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var positions []int
+			for _, err := range Diagnose(tt.expression, DiagnoseOptions{ValidationOptions: DefaultValidationOptions, Variables: tt.variables}) {
+				positions = append(positions, errorPos(err))
+			}
+			if !reflect.DeepEqual(positions, tt.wantPos) {
+				t.Errorf("Diagnose(%q) positions = %v, want %v", tt.expression, positions, tt.wantPos)
+			}
+		})
+	}
+}
+
+func TestDiagnose_Locale(t *testing.T) {
+	errs := Diagnose("foo(1) +\n  x", DiagnoseOptions{
+		ValidationOptions: DefaultValidationOptions,
+		Variables:         []string{},
+		Locale:            internal.LocaleEn,
+	})
+	if len(errs) != 2 {
+		t.Fatalf("Diagnose() returned %d errors, want 2: %v", len(errs), errs)
+	}
+
+	var parseErr *internal.ParseError
+	if !errors.As(errs[1], &parseErr) || parseErr.Line != 2 || parseErr.Column != 3 {
+		t.Fatalf("Diagnose() second error = %v, want undefined variable at 2:3", errs[1])
+	}
+	if want := "line 2, column 3: undefined variable: x: undefined variable\n 2 |   x\n   |   ^"; parseErr.Diagnostic() != want {
+		t.Errorf("Diagnostic() = %q, want %q", parseErr.Diagnostic(), want)
+	}
+}
+
+func TestDiagnose_Suggestions(t *testing.T) {
+	errs := Diagnose("sqr(prcie)", DiagnoseOptions{
+		ValidationOptions: DefaultValidationOptions,
+		Variables:         []string{"price", "quantity"},
+	})
+
+	var suggestions [][]string
+	for _, err := range errs {
+		suggestions = append(suggestions, err.(*internal.ParseError).Suggestions)
+	}
+	if want := [][]string{{"sqrt"}, {"price"}}; !reflect.DeepEqual(suggestions, want) {
+		t.Errorf("Diagnose() suggestions = %q, want %q", suggestions, want)
+	}
+}
@@ -1,10 +1,13 @@
 package validator
 
 import (
-	"fmt"
 	"strings"
+	"unicode"
 	"unicode/utf8"
 
+	"github.com/ZHOUXING1997/math_calculation/internal"
+	"github.com/ZHOUXING1997/math_calculation/internal/croe"
+	"github.com/ZHOUXING1997/math_calculation/internal/math_node"
 	"github.com/ZHOUXING1997/math_calculation/internal/math_utils"
 )
 
@@ -18,6 +21,14 @@ type ValidationOptions struct {
 	AllowVariables        bool     // 是否允许变量
 	MaxVariableNameLength int      // 最大变量名长度
 	MaxNumberLength       int      // 最大数字长度
+	MaxOperators          int      // 最大运算符数量，0 表示不限制
+	MaxNodes              int      // 解析树的最大节点数，0 表示不限制
+
+	// 变量声明，以下三项都为空时允许任意变量，否则变量需要满足其中之一
+	AllowedVariables        []string // 允许的变量名
+	AllowedVariablePrefixes []string // 允许的变量名前缀
+	AllowedVariablePatterns []string // 允许的变量名正则表达式，需要匹配整个变量名
+	RequiredVariables       []string // 表达式中必须引用的变量
 }
 
 // DefaultValidationOptions 默认验证选项
@@ -36,296 +47,375 @@ var DefaultValidationOptions = ValidationOptions{
 type ValidationError struct {
 	Message string
 	Pos     int
+	Token   string        // 未通过验证的函数名、变量名或数字，可能为空
+	Key     string        // 消息键，用于翻译错误消息
+	Args    []interface{} // 消息参数
+	Length  int           // 出错片段的字节长度，0 表示只定位到一个位置
+	Line    int           // 行号，从 1 开始，未知时为 0
+	Column  int           // 列号，从 1 开始按字符计数，未知时为 0
+
+	locale string // 输出消息使用的语言，空表示中文
+	source string // 出错的表达式
+}
+
+// newValidationError 按消息键创建验证错误，出错片段为 token
+func newValidationError(pos int, token, key string, args ...interface{}) *ValidationError {
+	return &ValidationError{
+		Message: internal.Translate(internal.LocaleZh, key, args...),
+		Pos:     pos,
+		Token:   token,
+		Key:     key,
+		Args:    args,
+		Length:  len(token),
+	}
 }
 
 func (e *ValidationError) Error() string {
-	return fmt.Sprintf("位置 %d: %s", e.Pos, e.Message)
+	return e.LocalizedError(e.locale)
+}
+
+// LocalizedError 返回指定语言的错误消息
+func (e *ValidationError) LocalizedError(locale string) string {
+	return internal.Translate(locale, internal.MsgPosition, e.Pos, e.detail(locale))
+}
+
+// detail 返回不含位置的错误消息
+func (e *ValidationError) detail(locale string) string {
+	if e.Key != "" {
+		return internal.Translate(locale, e.Key, e.Args...)
+	}
+	return e.Message
+}
+
+// Diagnostic 返回包含行号、列号、出错的表达式行和 ^~~~ 标记的诊断信息
+// 错误未记录表达式时返回 Error()
+func (e *ValidationError) Diagnostic() string {
+	if e.source == "" {
+		return e.Error()
+	}
+	header := internal.Translate(e.locale, internal.MsgDiagnostic, e.Line, e.Column, e.detail(e.locale))
+	return internal.RenderDiagnostic(e.source, e.Pos, e.Length, header)
+}
+
+// Localize 返回按指定语言输出消息的错误副本
+func (e *ValidationError) Localize(locale string) error {
+	localized := *e
+	localized.locale = locale
+	return &localized
+}
+
+// Locate 返回记录了出错表达式的错误副本，并填充行号和列号
+func (e *ValidationError) Locate(expression string) error {
+	if e.source != "" {
+		return e
+	}
+	located := *e
+	located.source = expression
+	located.Line, located.Column = internal.LineColumn(expression, e.Pos)
+	return &located
+}
+
+// Unwrap 返回 internal.ErrValidationFailed，支持 errors.Is 判断
+func (e *ValidationError) Unwrap() error {
+	return internal.ErrValidationFailed
+}
+
+// Code 返回错误码
+func (e *ValidationError) Code() internal.ErrorCode {
+	return internal.CodeValidationFailed
 }
 
-// ValidateExpression 验证表达式
+// reporter 接收验证错误，返回 false 时停止验证
+type reporter func(err *ValidationError) bool
+
+// ValidateExpression 验证表达式，返回第一个验证错误，错误中记录了表达式以便输出诊断信息
 func ValidateExpression(expression string, options ValidationOptions) error {
+	var first error
+	validateExpression(expression, options, func(err *ValidationError) bool {
+		first = internal.Locate(err, expression)
+		return false
+	})
+	return first
+}
+
+// ValidateAll 验证表达式，返回所有验证错误，没有错误时返回 nil
+func ValidateAll(expression string, options ValidationOptions) []error {
+	var errs []error
+	validateExpression(expression, options, func(err *ValidationError) bool {
+		errs = append(errs, internal.Locate(err, expression))
+		return true
+	})
+	return errs
+}
+
+// validateExpression 验证表达式，将错误交给 report，返回 false 表示验证被中止
+// 除表达式长度外，所有检查都基于净化后的表达式，即实际计算的表达式，使用解析器的词法分析器产生的标记和解析树，
+// 保证验证器与解析器的理解一致。错误的位置映射回原始表达式
+func validateExpression(expression string, options ValidationOptions, report reporter) bool {
 	// 检查表达式长度
 	if len(expression) > options.MaxExpressionLength {
-		return &ValidationError{
-			Message: fmt.Sprintf("表达式长度超过限制 (%d > %d)", len(expression), options.MaxExpressionLength),
-			Pos:     0,
+		if !report(newValidationError(0, "", internal.MsgExpressionLength, len(expression), options.MaxExpressionLength)) {
+			return false
 		}
 	}
 
-	// 检查嵌套括号数
-	parenCount := 0
-	maxParenCount := 0
-	for i, c := range expression {
-		if c == '(' {
-			parenCount++
-			if parenCount > maxParenCount {
-				maxParenCount = parenCount
-			}
-			if parenCount > options.MaxNestedParentheses {
-				return &ValidationError{
-					Message: fmt.Sprintf("嵌套括号数超过限制 (%d > %d)", parenCount, options.MaxNestedParentheses),
-					Pos:     i,
-				}
-			}
-		} else if c == ')' {
-			parenCount--
-			if parenCount < 0 {
-				return &ValidationError{
-					Message: "括号不匹配",
-					Pos:     i,
-				}
-			}
-		}
+	sanitized, offsets := SanitizeWithOffsets(expression)
+	relocate := func(err *ValidationError) bool {
+		relocated := *err
+		relocated.Pos, relocated.Length = internal.MapSpan(offsets, err.Pos, err.Length)
+		return report(&relocated)
 	}
 
-	// 检查括号是否匹配
-	if parenCount != 0 {
-		return &ValidationError{
-			Message: "括号不匹配",
-			Pos:     len(expression) - 1,
+	tokens := croe.NewLexer(nil).Lex(sanitized)
+	for _, check := range []func([]croe.Token, ValidationOptions, reporter) bool{
+		validateParentheses,
+		validateFunctions,
+		validateVariableNames,
+		validateNumberLength,
+		validateOperators,
+	} {
+		if !check(tokens, options, relocate) {
+			return false
 		}
 	}
 
-	// 检查函数参数数量和函数名称
-	if err := validateFunctions(expression, options); err != nil {
-		return err
-	}
+	return validateTree(sanitized, options, relocate)
+}
 
-	// 检查变量名称
-	if err := validateVariableNames(expression, options); err != nil {
-		return err
+// validateParentheses 验证括号嵌套层数和括号是否匹配
+func validateParentheses(tokens []croe.Token, options ValidationOptions, report reporter) bool {
+	depth := 0
+	last := 0
+	for _, token := range tokens {
+		last = token.Pos + len(token.Value)
+		switch token.Type {
+		case croe.TokenLParen:
+			depth++
+			// 每次超过限制时只报告一次
+			if depth == options.MaxNestedParentheses+1 {
+				if !report(newValidationError(token.Pos, token.Value, internal.MsgNestedParentheses, depth, options.MaxNestedParentheses)) {
+					return false
+				}
+			}
+		case croe.TokenRParen:
+			depth--
+			if depth < 0 {
+				if !report(newValidationError(token.Pos, token.Value, internal.MsgUnbalancedParentheses)) {
+					return false
+				}
+				depth = 0
+			}
+		}
 	}
 
-	// 检查数字长度
-	if err := validateNumberLength(expression, options); err != nil {
-		return err
+	// 检查括号是否匹配
+	if depth != 0 {
+		return report(newValidationError(last-1, "", internal.MsgUnbalancedParentheses))
 	}
-
-	return nil
+	return true
 }
 
-// validateFunctions 验证函数参数数量和函数名称
-func validateFunctions(expression string, options ValidationOptions) error {
-	// 如果没有函数限制和参数限制，直接返回
-	if len(options.AllowedFunctions) == 0 && len(options.DisallowedFunctions) == 0 && options.MaxFunctionArguments >= 1000 {
-		return nil
+// validateFunctions 验证函数名称和函数参数数量
+func validateFunctions(tokens []croe.Token, options ValidationOptions, report reporter) bool {
+	// 正在统计参数的函数调用
+	type call struct {
+		token croe.Token // 函数名标记
+		depth int        // 函数括号所在的层数
+		args  int        // 已出现的逗号数
+		empty bool       // 括号中是否还没有任何标记
 	}
+	var calls []call
 
-	// 查找所有函数调用
-	i := 0
-	for i < len(expression) {
-		// 跳过非字母和非下划线的字符
-		if i < len(expression) && !math_utils.IsAlpha(expression[i]) && expression[i] != '_' {
-			i++
-			continue
+	// checkArguments 检查结束的函数调用的参数数量
+	checkArguments := func(c call) bool {
+		argCount := c.args + 1
+		if c.empty {
+			argCount = 0
 		}
-
-		// 查找函数名
-		start := i
-		for i < len(expression) && math_utils.IsIdentifierChar(expression[i]) {
-			i++
+		if argCount > options.MaxFunctionArguments {
+			return report(newValidationError(c.token.Pos, c.token.Value, internal.MsgFunctionArguments, c.token.Value, argCount, options.MaxFunctionArguments))
 		}
+		return true
+	}
 
-		// 跳过空白字符
-		tempPos := i
-		for tempPos < len(expression) && expression[tempPos] == ' ' {
-			tempPos++
+	depth := 0
+	for i, token := range tokens {
+		if n := len(calls); n > 0 && token.Type != croe.TokenRParen {
+			calls[n-1].empty = false
 		}
 
-		// 检查是否是函数调用
-		if tempPos < len(expression) && expression[tempPos] == '(' {
-			funcName := expression[start:i]
-			i = tempPos + 1 // 跳过左括号
-
-			// 检查函数名是否在允许列表中
-			if len(options.AllowedFunctions) > 0 {
-				allowed := false
-				for _, allowedFunc := range options.AllowedFunctions {
-					if funcName == allowedFunc {
-						allowed = true
-						break
-					}
-				}
-				if !allowed {
-					return &ValidationError{
-						Message: fmt.Sprintf("函数 %s 不在允许列表中", funcName),
-						Pos:     start,
-					}
-				}
+		switch token.Type {
+		case croe.TokenFunc:
+			if !validateFunctionName(token, options, report) {
+				return false
 			}
-
-			// 检查函数名是否在禁止列表中
-			if len(options.DisallowedFunctions) > 0 {
-				for _, disallowedFunc := range options.DisallowedFunctions {
-					if funcName == disallowedFunc {
-						return &ValidationError{
-							Message: fmt.Sprintf("函数 %s 在禁止列表中", funcName),
-							Pos:     start,
-						}
-					}
-				}
+		case croe.TokenLParen:
+			depth++
+			// 词法分析器把函数名后的左括号作为单独的标记
+			if i > 0 && tokens[i-1].Type == croe.TokenFunc {
+				calls = append(calls, call{token: tokens[i-1], depth: depth, empty: true})
 			}
-
-			// 计算参数数量
-			argCount := 1
-			parenCount := 1
-
-			for i < len(expression) && parenCount > 0 {
-				if expression[i] == '(' {
-					parenCount++
-				} else if expression[i] == ')' {
-					parenCount--
-				} else if expression[i] == ',' && parenCount == 1 {
-					argCount++
-				}
-				i++
+		case croe.TokenComma:
+			if n := len(calls); n > 0 && calls[n-1].depth == depth {
+				calls[n-1].args++
 			}
-
-			// 检查参数数量是否超过限制
-			if argCount > options.MaxFunctionArguments {
-				return &ValidationError{
-					Message: fmt.Sprintf("函数 %s 的参数数量超过限制 (%d > %d)", funcName, argCount, options.MaxFunctionArguments),
-					Pos:     start,
+		case croe.TokenRParen:
+			if n := len(calls); n > 0 && calls[n-1].depth == depth {
+				c := calls[n-1]
+				calls = calls[:n-1]
+				if !checkArguments(c) {
+					return false
 				}
 			}
-		} else {
-			// 不是函数调用，继续处理
-			i++
+			depth--
 		}
 	}
 
-	return nil
-}
-
-// validateVariableNames 验证变量名称
-func validateVariableNames(expression string, options ValidationOptions) error {
-	// 如果不允许变量，检查是否有变量
-	if !options.AllowVariables {
-		// 简单检查是否有可能的变量（字母开头的标识符，不是函数调用）
-		i := 0
-		for i < len(expression) {
-			// 跳过非字母的字符
-			if i < len(expression) && !math_utils.IsAlpha(expression[i]) {
-				i++
-				continue
-			}
+	// 缺少右括号的函数调用，参数一直持续到表达式结尾
+	for i := len(calls) - 1; i >= 0; i-- {
+		if !checkArguments(calls[i]) {
+			return false
+		}
+	}
 
-			// 查找标识符
-			start := i
-			for i < len(expression) && math_utils.IsIdentifierChar(expression[i]) {
-				i++
-			}
+	return true
+}
 
-			// 跳过空白字符
-			tempPos := i
-			for tempPos < len(expression) && expression[tempPos] == ' ' {
-				tempPos++
-			}
+// validateFunctionName 验证函数名是否在允许列表中且不在禁止列表中
+func validateFunctionName(token croe.Token, options ValidationOptions, report reporter) bool {
+	funcName := token.Value
 
-			// 如果不是函数调用，则可能是变量
-			if tempPos >= len(expression) || expression[tempPos] != '(' {
-				return &ValidationError{
-					Message: "表达式中不允许使用变量",
-					Pos:     start,
-				}
-			} else {
-				// 是函数调用，跳过
-				i = tempPos + 1
-			}
+	// 检查函数名是否在允许列表中
+	if len(options.AllowedFunctions) > 0 && !contains(options.AllowedFunctions, funcName) {
+		if !report(newValidationError(token.Pos, funcName, internal.MsgFunctionNotAllowed, funcName)) {
+			return false
 		}
 	}
 
-	// 检查变量名长度
-	i := 0
-	for i < len(expression) {
-		// 跳过非字母的字符
-		if i < len(expression) && !math_utils.IsAlpha(expression[i]) {
-			i++
-			continue
+	// 检查函数名是否在禁止列表中
+	if contains(options.DisallowedFunctions, funcName) {
+		if !report(newValidationError(token.Pos, funcName, internal.MsgFunctionDisallowed, funcName)) {
+			return false
 		}
+	}
 
-		// 查找标识符
-		start := i
-		for i < len(expression) && math_utils.IsIdentifierChar(expression[i]) {
-			i++
+	return true
+}
+
+// validateVariableNames 验证是否允许变量以及变量名长度
+func validateVariableNames(tokens []croe.Token, options ValidationOptions, report reporter) bool {
+	for _, token := range tokens {
+		if token.Type != croe.TokenVariable {
+			continue
 		}
 
-		// 跳过空白字符
-		tempPos := i
-		for tempPos < len(expression) && expression[tempPos] == ' ' {
-			tempPos++
+		// 检查是否允许变量
+		if !options.AllowVariables {
+			if !report(newValidationError(token.Pos, token.Value, internal.MsgVariablesNotAllowed)) {
+				return false
+			}
 		}
 
-		// 如果不是函数调用，则可能是变量
-		if tempPos >= len(expression) || expression[tempPos] != '(' {
-			varName := expression[start:i]
-			if len(varName) > options.MaxVariableNameLength {
-				return &ValidationError{
-					Message: fmt.Sprintf("变量名 %s 长度超过限制 (%d > %d)", varName, len(varName), options.MaxVariableNameLength),
-					Pos:     start,
-				}
+		// 检查变量名长度，按字符计算，引用符号不计入长度
+		name, _, _ := math_utils.ParseIdentifier(token.Value)
+		if length := utf8.RuneCountInString(name); length > options.MaxVariableNameLength {
+			if !report(newValidationError(token.Pos, token.Value, internal.MsgVariableNameLength, name, length, options.MaxVariableNameLength)) {
+				return false
 			}
-			i++
-		} else {
-			// 是函数调用，跳过
-			i = tempPos + 1
 		}
 	}
 
-	return nil
+	return true
 }
 
-// validateNumberLength 验证数字长度
-func validateNumberLength(expression string, options ValidationOptions) error {
-	i := 0
-	for i < len(expression) {
-		// 查找数字
-		if math_utils.IsDigit(expression[i]) || (expression[i] == '-' && i+1 < len(expression) && math_utils.IsDigit(expression[i+1])) {
-			start := i
-			if expression[i] == '-' {
-				i++
+// validateNumberLength 验证数字长度，负号、小数点和指数部分都计入长度
+func validateNumberLength(tokens []croe.Token, options ValidationOptions, report reporter) bool {
+	for _, token := range tokens {
+		if token.Type == croe.TokenNumber && len(token.Value) > options.MaxNumberLength {
+			if !report(newValidationError(token.Pos, token.Value, internal.MsgNumberLength, token.Value, len(token.Value), options.MaxNumberLength)) {
+				return false
 			}
+		}
+	}
 
-			// 整数部分
-			for i < len(expression) && math_utils.IsDigit(expression[i]) {
-				i++
-			}
+	return true
+}
 
-			// 小数部分
-			if i < len(expression) && expression[i] == '.' {
-				i++
-				for i < len(expression) && math_utils.IsDigit(expression[i]) {
-					i++
-				}
-			}
+// validateOperators 验证运算符数量，MaxOperators 为 0 表示不限制
+// 负数字面量中的负号属于数字，不计入运算符；错误位置为第一个超出限制的运算符
+func validateOperators(tokens []croe.Token, options ValidationOptions, report reporter) bool {
+	if options.MaxOperators <= 0 {
+		return true
+	}
 
-			// 检查数字长度
-			numStr := expression[start:i]
-			if len(numStr) > options.MaxNumberLength {
-				return &ValidationError{
-					Message: fmt.Sprintf("数字 %s 长度超过限制 (%d > %d)", numStr, len(numStr), options.MaxNumberLength),
-					Pos:     start,
-				}
+	count := 0
+	var first *croe.Token
+	for i, token := range tokens {
+		switch token.Type {
+		case croe.TokenPlus, croe.TokenMinus, croe.TokenAsterisk, croe.TokenSlash, croe.TokenCaret:
+			count++
+			if count == options.MaxOperators+1 {
+				first = &tokens[i]
 			}
-		} else {
-			i++
 		}
 	}
+	if first != nil {
+		return report(newValidationError(first.Pos, first.Value, internal.MsgOperatorCount, count, options.MaxOperators))
+	}
+	return true
+}
+
+// validateTree 验证需要解析树的规则：节点总数和变量声明
+// 表达式无法解析时跳过这些规则，语法错误由解析器报告
+func validateTree(expression string, options ValidationOptions, report reporter) bool {
+	if options.MaxNodes <= 0 && !options.hasVariableRules() {
+		return true
+	}
+
+	// 使用默认配置解析，解析树会进入缓存，之后编译同一表达式时不需要重新解析
+	ast, err := croe.NewParser(nil, nil).Parse(expression)
+	if err != nil {
+		return true
+	}
+
+	return validateNodes(ast, options, report) && validateVariables(ast, options, report)
+}
+
+// validateNodes 验证解析树的节点总数，MaxNodes 为 0 表示不限制
+func validateNodes(ast math_node.Node, options ValidationOptions, report reporter) bool {
+	if options.MaxNodes <= 0 {
+		return true
+	}
 
-	return nil
+	count := 0
+	math_node.Walk(ast, func(math_node.Node) bool {
+		count++
+		return true
+	})
+	if count > options.MaxNodes {
+		return report(newValidationError(0, "", internal.MsgNodeCount, count, options.MaxNodes))
+	}
+	return true
+}
+
+// contains 判断字符串切片中是否包含指定字符串
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
 }
 
 // ValidateAndSanitizeExpression 验证并净化表达式
 func ValidateAndSanitizeExpression(expression string, options ValidationOptions) (string, error) {
-	// 验证表达式
+	// 验证表达式，验证的对象就是净化后的表达式
 	if err := ValidateExpression(expression, options); err != nil {
 		return "", err
 	}
 
-	// 净化表达式
-	sanitized := sanitizeExpression(expression)
-
-	return sanitized, nil
+	return sanitizeExpression(expression), nil
 }
 
 // SimplifyConsecutiveOperators 简化连续的加减运算符
@@ -390,6 +480,14 @@ func SimplifyConsecutiveOperators(expression string) string {
 
 	i := 0
 	for i < len(expression) {
+		// 变量名原样保留，反引号、方括号引用的名称中可以包含加减号
+		if math_utils.IsSegmentStart(expression, i) {
+			end, _, _, _ := math_utils.ScanIdentifier(expression, i)
+			result.WriteString(expression[i:end])
+			i = end
+			continue
+		}
+
 		// 如果当前字符不是加号或减号，直接添加到结果中
 		if expression[i] != '+' && expression[i] != '-' {
 			result.WriteByte(expression[i])
@@ -460,3 +558,81 @@ func sanitizeExpression(expression string) string {
 
 	return expression
 }
+
+// SanitizeWithOffsets 与 ValidateAndSanitizeExpression 相同地净化表达式，同时返回净化后每个字节在原始表达式中的位置
+// offsets 比净化后的表达式多一项，对应表达式的结尾，可以传给 internal.Relocate 将错误位置映射回原始表达式
+func SanitizeWithOffsets(expression string) (string, []int) {
+	// 移除多余的空白字符，记录开头移除的长度
+	trimmed := strings.TrimSpace(expression)
+	start := len(expression) - len(strings.TrimLeftFunc(expression, unicode.IsSpace))
+
+	// 移除无效的 UTF-8 字节，规则与 sanitizeExpression 相同
+	valid := trimmed
+	positions := make([]int, 0, len(trimmed)+1)
+	if utf8.ValidString(trimmed) {
+		for i := 0; i < len(trimmed); i++ {
+			positions = append(positions, start+i)
+		}
+	} else {
+		var sb strings.Builder
+		for i := 0; i < len(trimmed); {
+			r, size := utf8.DecodeRuneInString(trimmed[i:])
+			if r == utf8.RuneError {
+				i++
+				continue
+			}
+			sb.WriteString(trimmed[i : i+size])
+			for k := 0; k < size; k++ {
+				positions = append(positions, start+i+k)
+			}
+			i += size
+		}
+		valid = sb.String()
+	}
+	positions = append(positions, start+len(trimmed))
+
+	// 处理连续的加减运算符：连续的加减号被合并为一个或被删除，其他字符原样保留
+	// 长度不变的加减号逐个对应，合并后的运算符对应原来的第一个加减号
+	sanitized := SimplifyConsecutiveOperators(valid)
+	offsets := make([]int, 0, len(sanitized)+1)
+	i := 0
+	for j := 0; j < len(sanitized); {
+		if !isSign(sanitized[j]) {
+			// 跳过被删除的加减号
+			for i < len(valid) && isSign(valid[i]) {
+				i++
+			}
+			offsets = append(offsets, positions[i])
+			i++
+			j++
+			continue
+		}
+		n, m := signRun(sanitized, j), signRun(valid, i)
+		for k := 0; k < n; k++ {
+			if n == m {
+				offsets = append(offsets, positions[i+k])
+			} else {
+				offsets = append(offsets, positions[i])
+			}
+		}
+		i += m
+		j += n
+	}
+	offsets = append(offsets, positions[len(valid)])
+
+	return sanitized, offsets
+}
+
+// isSign 判断字符是否是加号或减号
+func isSign(c byte) bool {
+	return c == '+' || c == '-'
+}
+
+// signRun 返回 s[start:] 开头连续的加减号的数量
+func signRun(s string, start int) int {
+	end := start
+	for end < len(s) && isSign(s[end]) {
+		end++
+	}
+	return end - start
+}
@@ -1,11 +1,24 @@
 package validator
 
 import (
+	"reflect"
 	"testing"
 
+	"github.com/ZHOUXING1997/math_calculation/internal"
+	"github.com/ZHOUXING1997/math_calculation/internal/croe"
 	"github.com/ZHOUXING1997/math_calculation/internal/math_utils"
 )
 
+// firstError 返回检查函数对表达式的标记报告的第一个错误
+func firstError(check func([]croe.Token, ValidationOptions, reporter) bool, expression string, options ValidationOptions) error {
+	var first error
+	check(croe.NewLexer(nil).Lex(expression), options, func(err *ValidationError) bool {
+		first = err
+		return false
+	})
+	return first
+}
+
 func TestValidateExpression(t *testing.T) {
 	tests := []struct {
 		name       string
@@ -307,7 +320,7 @@ func TestValidateFunctions(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			err := validateFunctions(tt.expression, tt.options)
+			err := firstError(validateFunctions, tt.expression, tt.options)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("validateFunctions() error = %v, wantErr %v", err, tt.wantErr)
 			}
@@ -374,7 +387,7 @@ func TestValidateNumberLength(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			err := validateNumberLength(tt.expression, tt.options)
+			err := firstError(validateNumberLength, tt.expression, tt.options)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("validateNumberLength() error = %v, wantErr %v", err, tt.wantErr)
 			}
@@ -458,6 +471,11 @@ func TestSimplifyConsecutiveOperators(t *testing.T) {
 			expression: "max(5,+-3)",
 			want:       "max(5,-3)",
 		},
+		{
+			name:       "引用名称中的连续运算符",
+			expression: "`a--b` +- [c+-d] -- e[`f--g`]",
+			want:       "`a--b` - [c+-d] + e[`f--g`]",
+		},
 		{
 			name:       "三个运算符",
 			expression: "x +++ y",
@@ -582,3 +600,311 @@ func TestSanitizeExpression(t *testing.T) {
 		})
 	}
 }
+
+func TestValidationError_Diagnostic(t *testing.T) {
+	options := DefaultValidationOptions
+	options.DisallowedFunctions = []string{"pow"}
+
+	err := ValidateExpression("x +\n  pow(x, 2)", options)
+	validationErr, ok := err.(*ValidationError)
+	if !ok {
+		t.Fatalf("ValidateExpression() error = %v, want *ValidationError", err)
+	}
+	if validationErr.Line != 2 || validationErr.Column != 3 || validationErr.Length != 3 {
+		t.Errorf("ValidateExpression() error span = %d:%d length %d, want 2:3 length 3",
+			validationErr.Line, validationErr.Column, validationErr.Length)
+	}
+
+	want := "第 2 行，第 3 列: 函数 pow 在禁止列表中\n 2 |   pow(x, 2)\n   |   ^~~"
+	if got := validationErr.Diagnostic(); got != want {
+		t.Errorf("Diagnostic() = %q, want %q", got, want)
+	}
+}
+
+func TestValidateAll(t *testing.T) {
+	options := DefaultValidationOptions
+	options.DisallowedFunctions = []string{"pow"}
+	options.MaxVariableNameLength = 3
+
+	tests := []struct {
+		name       string
+		expression string
+		wantKeys   []string
+	}{
+		{
+			name:       "没有错误",
+			expression: "abs(x) + 1",
+			wantKeys:   nil,
+		},
+		{
+			name:       "多个验证错误",
+			expression: "pow(x, 2) + price * pow(y, 3)",
+			wantKeys: []string{
+				internal.MsgFunctionDisallowed,
+				internal.MsgFunctionDisallowed,
+				internal.MsgVariableNameLength,
+			},
+		},
+		{
+			name:       "多余的右括号",
+			expression: "1) + pow(2, 3)",
+			wantKeys:   []string{internal.MsgUnbalancedParentheses, internal.MsgFunctionDisallowed},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var keys []string
+			for _, err := range ValidateAll(tt.expression, options) {
+				validationErr, ok := err.(*ValidationError)
+				if !ok {
+					t.Fatalf("ValidateAll() error = %v, want *ValidationError", err)
+				}
+				if validationErr.Line == 0 {
+					t.Errorf("ValidateAll() error %v is not located", err)
+				}
+				keys = append(keys, validationErr.Key)
+			}
+			if !reflect.DeepEqual(keys, tt.wantKeys) {
+				t.Errorf("ValidateAll() keys = %q, want %q", keys, tt.wantKeys)
+			}
+		})
+	}
+}
+
+// 验证器与词法分析器、解析器对表达式的理解一致
+func TestValidateExpression_AgreesWithLexer(t *testing.T) {
+	tests := []struct {
+		name       string
+		expression string
+		options    ValidationOptions
+		wantKey    string
+	}{
+		{
+			name:       "科学计数法中的 e 不是变量",
+			expression: "1e5 * 2.5E-3",
+			options:    ValidationOptions{MaxExpressionLength: 1000, MaxNestedParentheses: 5, MaxFunctionArguments: 5, MaxVariableNameLength: 10, MaxNumberLength: 10},
+			wantKey:    "",
+		},
+		{
+			name:       "科学计数法的指数计入数字长度",
+			expression: "1.5e-10 + 1",
+			options:    ValidationOptions{MaxExpressionLength: 1000, MaxNestedParentheses: 5, MaxFunctionArguments: 5, MaxNumberLength: 6},
+			wantKey:    internal.MsgNumberLength,
+		},
+		{
+			name:       "嵌套函数名也受允许列表限制",
+			expression: "sin(tan(x))",
+			options: ValidationOptions{
+				MaxExpressionLength: 1000, MaxNestedParentheses: 5, AllowedFunctions: []string{"sin", "cos"},
+				MaxFunctionArguments: 10, AllowVariables: true, MaxVariableNameLength: 10,
+			},
+			wantKey: internal.MsgFunctionNotAllowed,
+		},
+		{
+			name:       "嵌套函数的参数单独计数",
+			expression: "max(min(1, 2, 3), 4)",
+			options:    ValidationOptions{MaxExpressionLength: 1000, MaxNestedParentheses: 5, MaxFunctionArguments: 2, MaxNumberLength: 10},
+			wantKey:    internal.MsgFunctionArguments,
+		},
+		{
+			name:       "括号中的逗号不计入外层函数的参数",
+			expression: "max(min(1, 2), (3), 4)",
+			options:    ValidationOptions{MaxExpressionLength: 1000, MaxNestedParentheses: 5, MaxFunctionArguments: 3, MaxNumberLength: 10},
+			wantKey:    "",
+		},
+		{
+			name:       "没有参数的函数",
+			expression: "max()",
+			options:    ValidationOptions{MaxExpressionLength: 1000, MaxNestedParentheses: 5, MaxFunctionArguments: 0},
+			wantKey:    "",
+		},
+		{
+			name:       "制表符和换行分隔的函数调用",
+			expression: "max\t(x,\n y)",
+			options: ValidationOptions{
+				MaxExpressionLength: 1000, MaxNestedParentheses: 5, MaxFunctionArguments: 5,
+				DisallowedFunctions: []string{"max"}, AllowVariables: true, MaxVariableNameLength: 10,
+			},
+			wantKey: internal.MsgFunctionDisallowed,
+		},
+		{
+			name:       "缺少右括号的函数调用",
+			expression: "max(1, 2, 3",
+			options:    ValidationOptions{MaxExpressionLength: 1000, MaxNestedParentheses: 5, MaxFunctionArguments: 2, MaxNumberLength: 10},
+			wantKey:    internal.MsgFunctionArguments,
+		},
+		{
+			name:       "Unicode 变量名按字符计算长度",
+			expression: "单价 * [数量] + order.items[0]",
+			options:    ValidationOptions{MaxExpressionLength: 1000, MaxNestedParentheses: 5, AllowVariables: true, MaxVariableNameLength: 14},
+			wantKey:    "",
+		},
+		{
+			name:       "引用符号不计入变量名长度",
+			expression: "`unit price` * 2",
+			options:    ValidationOptions{MaxExpressionLength: 1000, MaxNestedParentheses: 5, AllowVariables: true, MaxVariableNameLength: 9, MaxNumberLength: 10},
+			wantKey:    internal.MsgVariableNameLength,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var keys []string
+			for _, err := range ValidateAll(tt.expression, tt.options) {
+				if key := err.(*ValidationError).Key; key != internal.MsgUnbalancedParentheses {
+					keys = append(keys, key)
+				}
+			}
+			if tt.wantKey == "" && keys != nil {
+				t.Errorf("ValidateAll() keys = %q, want none", keys)
+			}
+			if tt.wantKey != "" && (len(keys) != 1 || keys[0] != tt.wantKey) {
+				t.Errorf("ValidateAll() keys = %q, want [%q]", keys, tt.wantKey)
+			}
+		})
+	}
+}
+
+func TestValidateOperatorsAndNodes(t *testing.T) {
+	options := DefaultValidationOptions
+	options.MaxOperators = 3
+	options.MaxNodes = 7
+
+	tests := []struct {
+		name       string
+		expression string
+		wantKeys   []string
+		wantToken  string
+	}{
+		{
+			name:       "未超过限制",
+			expression: "-1 + max(a, b) * c",
+			wantKeys:   nil,
+		},
+		{
+			name:       "运算符过多",
+			expression: "a + b - c * d / e",
+			wantKeys:   []string{internal.MsgOperatorCount, internal.MsgNodeCount},
+			wantToken:  "/",
+		},
+		{
+			name:       "节点过多",
+			expression: "max(1, 2, 3, 4, 5, 6, 7)",
+			wantKeys:   []string{internal.MsgNodeCount},
+		},
+		{
+			name:       "无法解析时不检查节点数",
+			expression: "max(1, 2, 3, 4, 5, 6, 7,)",
+			wantKeys:   nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var keys []string
+			for _, err := range ValidateAll(tt.expression, options) {
+				validationErr := err.(*ValidationError)
+				keys = append(keys, validationErr.Key)
+				if validationErr.Key == internal.MsgOperatorCount && validationErr.Token != tt.wantToken {
+					t.Errorf("ValidateAll() operator token = %q, want %q", validationErr.Token, tt.wantToken)
+				}
+			}
+			if !reflect.DeepEqual(keys, tt.wantKeys) {
+				t.Errorf("ValidateAll() keys = %q, want %q", keys, tt.wantKeys)
+			}
+		})
+	}
+
+	// 0 表示不限制
+	if err := ValidateExpression("a + b - c * d / e", DefaultValidationOptions); err != nil {
+		t.Errorf("ValidateExpression() error = %v, want nil", err)
+	}
+}
+
+// TestValidateSanitizedExpression 测试验证的是净化后实际计算的表达式，错误位置对应原始表达式
+func TestValidateSanitizedExpression(t *testing.T) {
+	options := DefaultValidationOptions
+	options.MaxNumberLength = 3
+	options.MaxOperators = 1
+
+	tests := []struct {
+		name       string
+		expression string
+		wantKey    string
+		wantToken  string
+		wantPos    int
+		wantLength int
+	}{
+		{
+			name:       "删除无效 UTF-8 字节后数字过长",
+			expression: "12\xff345",
+			wantKey:    internal.MsgNumberLength,
+			wantToken:  "12345",
+			wantPos:    0,
+			wantLength: 6,
+		},
+		{
+			name:       "删除无效 UTF-8 字节后运算符过多",
+			expression: "1+\xff2*3",
+			wantKey:    internal.MsgOperatorCount,
+			wantToken:  "*",
+			wantPos:    4,
+			wantLength: 1,
+		},
+		{
+			name:       "合并后的加减号只计一次",
+			expression: " 1 +-+ 2",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateExpression(tt.expression, options)
+			if tt.wantKey == "" {
+				if err != nil {
+					t.Errorf("ValidateExpression() error = %v, want nil", err)
+				}
+				return
+			}
+			validationErr, ok := err.(*ValidationError)
+			if !ok {
+				t.Fatalf("ValidateExpression() error = %v, want *ValidationError", err)
+			}
+			if validationErr.Key != tt.wantKey || validationErr.Token != tt.wantToken ||
+				validationErr.Pos != tt.wantPos || validationErr.Length != tt.wantLength {
+				t.Errorf("ValidateExpression() error = %s %q at %d+%d, want %s %q at %d+%d",
+					validationErr.Key, validationErr.Token, validationErr.Pos, validationErr.Length,
+					tt.wantKey, tt.wantToken, tt.wantPos, tt.wantLength)
+			}
+		})
+	}
+}
+
+func TestSanitizeWithOffsets(t *testing.T) {
+	tests := []struct {
+		name       string
+		expression string
+		want       string
+		offsets    []int
+	}{
+		{name: "不需要净化", expression: "x+1", want: "x+1", offsets: []int{0, 1, 2, 3}},
+		{name: "首尾空白", expression: "  x+1 ", want: "x+1", offsets: []int{2, 3, 4, 5}},
+		{name: "合并连续运算符", expression: "1 +-+ 2", want: "1 - 2", offsets: []int{0, 1, 2, 5, 6, 7}},
+		{name: "删除开头的运算符", expression: "--x", want: "x", offsets: []int{2, 3}},
+		{name: "引用名称中的加减号", expression: "`a--b` -- 1", want: "`a--b` + 1", offsets: []int{0, 1, 2, 3, 4, 5, 6, 7, 9, 10, 11}},
+		{name: "无效的 UTF-8 字节", expression: "\xff1 -- 2", want: "1 + 2", offsets: []int{1, 2, 3, 5, 6, 7}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, offsets := SanitizeWithOffsets(tt.expression)
+			if got != tt.want || got != sanitizeExpression(tt.expression) {
+				t.Errorf("SanitizeWithOffsets() = %q, want %q", got, tt.want)
+			}
+			if !reflect.DeepEqual(offsets, tt.offsets) {
+				t.Errorf("SanitizeWithOffsets() offsets = %v, want %v", offsets, tt.offsets)
+			}
+		})
+	}
+}
@@ -0,0 +1,104 @@
+package validator
+
+import (
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/ZHOUXING1997/math_calculation/internal"
+	"github.com/ZHOUXING1997/math_calculation/internal/math_node"
+)
+
+// variablePatterns 编译后的变量名正则表达式缓存，键为原始模式
+var variablePatterns sync.Map
+
+// hasVariableRules 判断是否设置了变量声明规则
+func (o ValidationOptions) hasVariableRules() bool {
+	return o.hasVariableAllowList() || len(o.RequiredVariables) > 0
+}
+
+// hasVariableAllowList 判断是否限制了允许的变量
+func (o ValidationOptions) hasVariableAllowList() bool {
+	return len(o.AllowedVariables) > 0 || len(o.AllowedVariablePrefixes) > 0 || len(o.AllowedVariablePatterns) > 0
+}
+
+// validateVariables 按变量声明规则验证解析树中的变量
+// 不在允许范围内的变量在每次出现的位置报告，缺少的必需变量按声明顺序报告
+func validateVariables(ast math_node.Node, options ValidationOptions, report reporter) bool {
+	if !options.hasVariableRules() {
+		return true
+	}
+
+	// 编译变量名正则表达式，无效的模式不匹配任何变量
+	patterns := make([]*regexp.Regexp, 0, len(options.AllowedVariablePatterns))
+	for _, pattern := range options.AllowedVariablePatterns {
+		re, err := compileVariablePattern(pattern)
+		if err != nil {
+			if !report(newValidationError(0, "", internal.MsgInvalidVariablePattern, pattern, err.Error())) {
+				return false
+			}
+			continue
+		}
+		patterns = append(patterns, re)
+	}
+
+	referenced := make(map[string]bool)
+	ok := true
+	math_node.Walk(ast, func(node math_node.Node) bool {
+		if !ok {
+			return false
+		}
+		if n, isVar := node.(*math_node.VariableNode); isVar {
+			referenced[n.VarName] = true
+			if options.hasVariableAllowList() && !variableAllowed(n.VarName, options, patterns) {
+				ok = report(newValidationError(n.Pos, n.VarName, internal.MsgVariableNotAllowed, n.VarName))
+			}
+		}
+		return ok
+	})
+	if !ok {
+		return false
+	}
+
+	// 检查必需的变量
+	for _, name := range options.RequiredVariables {
+		if !referenced[name] {
+			if !report(newValidationError(0, "", internal.MsgRequiredVariable, name)) {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+// variableAllowed 判断变量是否在允许列表中、带有允许的前缀或匹配允许的模式
+func variableAllowed(name string, options ValidationOptions, patterns []*regexp.Regexp) bool {
+	if contains(options.AllowedVariables, name) {
+		return true
+	}
+	for _, prefix := range options.AllowedVariablePrefixes {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+	for _, re := range patterns {
+		if re.MatchString(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// compileVariablePattern 编译变量名正则表达式，结果按模式缓存
+func compileVariablePattern(pattern string) (*regexp.Regexp, error) {
+	if re, ok := variablePatterns.Load(pattern); ok {
+		return re.(*regexp.Regexp), nil
+	}
+	re, err := regexp.Compile(`^(?:` + pattern + `)$`)
+	if err != nil {
+		return nil, err
+	}
+	variablePatterns.Store(pattern, re)
+	return re, nil
+}
@@ -0,0 +1,164 @@
+package validator
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/ZHOUXING1997/math_calculation/internal"
+)
+
+func TestValidateVariables(t *testing.T) {
+	type result struct {
+		Key   string
+		Token string
+		Pos   int
+	}
+
+	tests := []struct {
+		name       string
+		expression string
+		configure  func(options *ValidationOptions)
+		want       []result
+	}{
+		{
+			name:       "没有变量规则",
+			expression: "anything * 2",
+			configure:  func(options *ValidationOptions) {},
+			want:       nil,
+		},
+		{
+			name:       "允许列表中的变量",
+			expression: "qty * unit_price * (1 - discount)",
+			configure: func(options *ValidationOptions) {
+				options.AllowedVariables = []string{"qty", "unit_price", "discount"}
+			},
+			want: nil,
+		},
+		{
+			name:       "不在允许列表中的变量报告每次出现的位置",
+			expression: "qty * price + price",
+			configure: func(options *ValidationOptions) {
+				options.AllowedVariables = []string{"qty", "unit_price"}
+			},
+			want: []result{
+				{internal.MsgVariableNotAllowed, "price", 6},
+				{internal.MsgVariableNotAllowed, "price", 14},
+			},
+		},
+		{
+			name:       "前缀规则",
+			expression: "input_a + input_b + other",
+			configure: func(options *ValidationOptions) {
+				options.AllowedVariablePrefixes = []string{"input_"}
+			},
+			want: []result{{internal.MsgVariableNotAllowed, "other", 20}},
+		},
+		{
+			name:       "正则规则匹配整个变量名",
+			expression: "x1 + x22 + ax3",
+			configure: func(options *ValidationOptions) {
+				options.AllowedVariablePatterns = []string{`x\d+`}
+			},
+			want: []result{{internal.MsgVariableNotAllowed, "ax3", 11}},
+		},
+		{
+			name:       "满足任意一条规则即可",
+			expression: "qty + input_a + x1",
+			configure: func(options *ValidationOptions) {
+				options.AllowedVariables = []string{"qty"}
+				options.AllowedVariablePrefixes = []string{"input_"}
+				options.AllowedVariablePatterns = []string{`x\d`}
+			},
+			want: nil,
+		},
+		{
+			name:       "缺少必需的变量",
+			expression: "qty * 2",
+			configure: func(options *ValidationOptions) {
+				options.RequiredVariables = []string{"qty", "unit_price", "discount"}
+			},
+			want: []result{
+				{internal.MsgRequiredVariable, "", 0},
+				{internal.MsgRequiredVariable, "", 0},
+			},
+		},
+		{
+			name:       "函数参数中的变量",
+			expression: "max(qty, other)",
+			configure: func(options *ValidationOptions) {
+				options.AllowedVariables = []string{"qty"}
+				options.RequiredVariables = []string{"qty"}
+			},
+			want: []result{{internal.MsgVariableNotAllowed, "other", 9}},
+		},
+		{
+			name:       "无效的正则表达式",
+			expression: "x + y",
+			configure: func(options *ValidationOptions) {
+				options.AllowedVariables = []string{"x"}
+				options.AllowedVariablePatterns = []string{`(`}
+			},
+			want: []result{
+				{internal.MsgInvalidVariablePattern, "", 0},
+				{internal.MsgVariableNotAllowed, "y", 4},
+			},
+		},
+		{
+			name:       "检查删除无效 UTF-8 字节后的变量名",
+			expression: "sec\xffret+1",
+			configure: func(options *ValidationOptions) {
+				options.AllowedVariables = []string{"qty"}
+			},
+			want: []result{{internal.MsgVariableNotAllowed, "secret", 0}},
+		},
+		{
+			name:       "删除无效 UTF-8 字节后的位置对应原始表达式",
+			expression: "qty+sec\xffret",
+			configure: func(options *ValidationOptions) {
+				options.AllowedVariables = []string{"qty"}
+			},
+			want: []result{{internal.MsgVariableNotAllowed, "secret", 4}},
+		},
+		{
+			name:       "无法解析时不检查",
+			expression: "x + * y",
+			configure: func(options *ValidationOptions) {
+				options.AllowedVariables = []string{"z"}
+			},
+			want: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			options := DefaultValidationOptions
+			tt.configure(&options)
+
+			var got []result
+			for _, err := range ValidateAll(tt.expression, options) {
+				validationErr := err.(*ValidationError)
+				got = append(got, result{validationErr.Key, validationErr.Token, validationErr.Pos})
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ValidateAll() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateVariables_Messages(t *testing.T) {
+	options := DefaultValidationOptions
+	options.AllowedVariables = []string{"qty"}
+	options.RequiredVariables = []string{"qty", "discount"}
+
+	errs := ValidateAll("qty * prcie", options)
+	if len(errs) != 2 {
+		t.Fatalf("ValidateAll() = %v, want 2 errors", errs)
+	}
+	if want := "位置 6: 变量 prcie 不在允许列表中"; errs[0].Error() != want {
+		t.Errorf("Error() = %q, want %q", errs[0].Error(), want)
+	}
+	if want := "position 0: required variable discount is not referenced"; errs[1].(*ValidationError).LocalizedError(internal.LocaleEn) != want {
+		t.Errorf("LocalizedError() = %q, want %q", errs[1].(*ValidationError).LocalizedError(internal.LocaleEn), want)
+	}
+}
@@ -0,0 +1,371 @@
+// Package workbook 管理相互引用的命名公式，按依赖顺序计算，输入变化时只重新计算受影响的公式
+package workbook
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/ZHOUXING1997/math_calculation/internal"
+	"github.com/ZHOUXING1997/math_calculation/internal/croe"
+	"github.com/ZHOUXING1997/math_calculation/internal/math_utils"
+	"github.com/ZHOUXING1997/math_calculation/math_config"
+)
+
+// formula 一个命名公式
+type formula struct {
+	expression string
+	compiled   *croe.CompiledExpression
+	references []string // 表达式引用的变量，包括其他公式和输入
+}
+
+// Workbook 一组命名公式，公式可以引用输入和其他公式，如 subtotal = qty * price、total = subtotal + tax
+// 公式按依赖关系的拓扑顺序计算，定义公式时检查循环引用；输入或公式变化后，
+// 只有直接或间接依赖它的公式会在下次取值时重新计算。Workbook 可以被多个 goroutine 同时使用
+type Workbook struct {
+	mutex      sync.Mutex
+	config     *math_config.CalcConfig
+	formulas   map[string]*formula
+	inputs     map[string]decimal.Decimal
+	values     map[string]decimal.Decimal // 输入和公式的计算结果，计算公式时作为变量
+	errs       map[string]error           // 计算出错的公式
+	dirty      map[string]bool            // 需要重新计算的公式
+	order      []string                   // 公式的计算顺序，定义或删除公式后为 nil，需要时重新生成
+	dependents map[string][]string        // 直接引用每个名称的公式，与 order 一起生成
+}
+
+// New 创建公式集，复制传入的配置，之后修改 config 不会影响公式集；config 为 nil 时使用默认配置
+func New(config *math_config.CalcConfig) *Workbook {
+	return &Workbook{
+		config:   config.Clone(),
+		formulas: make(map[string]*formula),
+		inputs:   make(map[string]decimal.Decimal),
+		values:   make(map[string]decimal.Decimal),
+		errs:     make(map[string]error),
+		dirty:    make(map[string]bool),
+	}
+}
+
+// Define 定义或替换公式，表达式引用的变量中与公式同名的作为对其他公式的依赖，其余作为输入
+// 表达式无法解析、名称已被输入使用或会形成循环引用时返回错误，原有的公式保持不变，
+// 循环引用返回 *CycleError，其中包含循环经过的公式
+func (w *Workbook) Define(name, expression string) error {
+	if name == "" {
+		return internal.Localize(internal.NewParseError(0, internal.ErrInvalidArgument, "", internal.MsgEmptyFormulaName), w.config.Locale)
+	}
+	compiled, err := croe.Compile(expression, w.config)
+	if err != nil {
+		return err
+	}
+
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if _, ok := w.inputs[name]; ok {
+		return internal.Localize(internal.NewParseError(0, internal.ErrInvalidArgument, name, internal.MsgNameIsInput, name), w.config.Locale)
+	}
+
+	previous, existed := w.formulas[name]
+	w.formulas[name] = &formula{expression: expression, compiled: compiled, references: compiled.Variables()}
+	if path := w.findCycle(name); path != nil {
+		if existed {
+			w.formulas[name] = previous
+		} else {
+			delete(w.formulas, name)
+		}
+		return internal.Localize(&internal.CycleError{Path: path}, w.config.Locale)
+	}
+
+	w.order = nil
+	w.markDirty(name)
+	return nil
+}
+
+// Remove 删除公式，引用它的公式在下次取值时重新计算，公式不存在时返回 false
+func (w *Workbook) Remove(name string) bool {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if _, ok := w.formulas[name]; !ok {
+		return false
+	}
+	delete(w.formulas, name)
+	delete(w.values, name)
+	delete(w.errs, name)
+	delete(w.dirty, name)
+	w.order = nil
+	w.markDirty(name)
+	return true
+}
+
+// SetInput 设置输入变量的值，值发生变化时依赖它的公式在下次取值时重新计算
+// 名称已被公式使用时返回错误
+func (w *Workbook) SetInput(name string, value decimal.Decimal) error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	return w.setInput(name, value)
+}
+
+// SetInputs 设置多个输入变量的值，遇到已被公式使用的名称时返回错误，之前的输入仍然生效
+func (w *Workbook) SetInputs(inputs map[string]decimal.Decimal) error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	// 按名称顺序设置，出错时生效的输入是确定的
+	names := make([]string, 0, len(inputs))
+	for name := range inputs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if err := w.setInput(name, inputs[name]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// setInput 设置输入变量的值，调用方需要持有锁
+func (w *Workbook) setInput(name string, value decimal.Decimal) error {
+	if _, ok := w.formulas[name]; ok {
+		return internal.Localize(internal.NewParseError(0, internal.ErrInvalidArgument, name, internal.MsgNameIsFormula, name), w.config.Locale)
+	}
+	if old, ok := w.inputs[name]; ok && old.Equal(value) {
+		return nil
+	}
+	w.inputs[name] = value
+	w.values[name] = value
+	w.markDirty(name)
+	return nil
+}
+
+// Recalculate 按依赖顺序重新计算所有需要重新计算的公式，返回重新计算的公式名
+// 公式的计算错误在取值时返回；ctx 结束时停止计算并返回取消错误，未计算的公式留到下次
+func (w *Workbook) Recalculate(ctx context.Context) ([]string, error) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	return w.recalculate(ctx)
+}
+
+// recalculate 重新计算需要重新计算的公式，调用方需要持有锁
+func (w *Workbook) recalculate(ctx context.Context) ([]string, error) {
+	var recalculated []string
+	for _, name := range w.graph() {
+		if !w.dirty[name] {
+			continue
+		}
+		if ctx.Err() != nil {
+			return recalculated, math_utils.Canceled(ctx)
+		}
+		if err := w.evaluate(ctx, name); err != nil {
+			return recalculated, err
+		}
+		delete(w.dirty, name)
+		recalculated = append(recalculated, name)
+	}
+	return recalculated, nil
+}
+
+// evaluate 计算一个公式，依赖的公式出错时返回相同的错误
+// 只有调用方的 ctx 结束时返回错误，此时公式仍需要重新计算
+func (w *Workbook) evaluate(ctx context.Context, name string) error {
+	f := w.formulas[name]
+	// 只传入公式引用的变量，计算时复制的变量个数与公式集的大小无关
+	vars := make(map[string]decimal.Decimal, len(f.references))
+	for _, ref := range f.references {
+		if err, failed := w.errs[ref]; failed {
+			w.errs[name] = err
+			delete(w.values, name)
+			return nil
+		}
+		if val, ok := w.values[ref]; ok {
+			vars[ref] = val
+		}
+	}
+
+	result, err := f.compiled.EvaluateContext(ctx, vars)
+	if err != nil {
+		if ctx.Err() != nil {
+			return err
+		}
+		w.errs[name] = err
+		delete(w.values, name)
+		return nil
+	}
+	w.values[name] = result
+	delete(w.errs, name)
+	return nil
+}
+
+// Value 返回公式或输入的值，需要时先重新计算，公式计算出错时返回错误
+func (w *Workbook) Value(name string) (decimal.Decimal, error) {
+	return w.ValueContext(context.Background(), name)
+}
+
+// ValueContext 使用调用方的上下文返回公式或输入的值
+func (w *Workbook) ValueContext(ctx context.Context, name string) (decimal.Decimal, error) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if _, err := w.recalculate(ctx); err != nil {
+		return decimal.Zero, err
+	}
+	if err, failed := w.errs[name]; failed {
+		return decimal.Zero, err
+	}
+	if val, ok := w.values[name]; ok {
+		return val, nil
+	}
+	return decimal.Zero, internal.Localize(internal.NewParseError(0, internal.ErrUndefinedVariable, name, internal.MsgUndefinedVariable, name), w.config.Locale)
+}
+
+// Values 重新计算后返回所有计算成功的公式的值，计算出错的公式不包含在内
+func (w *Workbook) Values() map[string]decimal.Decimal {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	_, _ = w.recalculate(context.Background())
+	values := make(map[string]decimal.Decimal, len(w.formulas))
+	for name := range w.formulas {
+		if val, ok := w.values[name]; ok {
+			values[name] = val
+		}
+	}
+	return values
+}
+
+// Expression 返回公式的表达式，公式不存在时返回 false
+func (w *Workbook) Expression(name string) (string, bool) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	f, ok := w.formulas[name]
+	if !ok {
+		return "", false
+	}
+	return f.expression, true
+}
+
+// Formulas 返回所有公式名，按计算顺序排列，被依赖的公式在前
+func (w *Workbook) Formulas() []string {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	return append([]string(nil), w.graph()...)
+}
+
+// Dependencies 返回公式直接引用的变量，包括其他公式和输入，按首次出现的顺序排列
+func (w *Workbook) Dependencies(name string) []string {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	f, ok := w.formulas[name]
+	if !ok {
+		return nil
+	}
+	return append([]string(nil), f.references...)
+}
+
+// Dependents 返回直接引用公式或输入的公式，按名称排序
+func (w *Workbook) Dependents(name string) []string {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	w.graph()
+	return append([]string(nil), w.dependents[name]...)
+}
+
+// markDirty 将名称为 name 的公式以及直接或间接引用 name 的公式标记为需要重新计算，调用方需要持有锁
+func (w *Workbook) markDirty(name string) {
+	w.graph()
+	queue := []string{name}
+	if _, ok := w.formulas[name]; ok {
+		w.dirty[name] = true
+	}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		for _, dependent := range w.dependents[current] {
+			if !w.dirty[dependent] {
+				w.dirty[dependent] = true
+				queue = append(queue, dependent)
+			}
+		}
+	}
+}
+
+// graph 返回公式的计算顺序，同时生成反向依赖，调用方需要持有锁
+// 使用深度优先的后序遍历，依赖的公式排在前面，没有依赖关系的公式按名称排序
+func (w *Workbook) graph() []string {
+	if w.order != nil {
+		return w.order
+	}
+
+	names := make([]string, 0, len(w.formulas))
+	for name := range w.formulas {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	w.dependents = make(map[string][]string)
+	for _, name := range names {
+		for _, ref := range w.formulas[name].references {
+			w.dependents[ref] = append(w.dependents[ref], name)
+		}
+	}
+
+	order := make([]string, 0, len(names))
+	visited := make(map[string]bool, len(names))
+	var visit func(name string)
+	visit = func(name string) {
+		if visited[name] {
+			return
+		}
+		visited[name] = true
+		for _, ref := range w.formulas[name].references {
+			if _, ok := w.formulas[ref]; ok {
+				visit(ref)
+			}
+		}
+		order = append(order, name)
+	}
+	for _, name := range names {
+		visit(name)
+	}
+
+	w.order = order
+	return order
+}
+
+// findCycle 查找从公式 start 出发回到 start 的引用路径，没有循环时返回 nil，调用方需要持有锁
+// 其他公式之间没有循环，因此只需要检查经过 start 的循环
+func (w *Workbook) findCycle(start string) []string {
+	visited := make(map[string]bool)
+	var path []string
+	var visit func(name string) bool
+	visit = func(name string) bool {
+		path = append(path, name)
+		for _, ref := range w.formulas[name].references {
+			if _, ok := w.formulas[ref]; !ok {
+				continue
+			}
+			if ref == start {
+				path = append(path, ref)
+				return true
+			}
+			if !visited[ref] {
+				visited[ref] = true
+				if visit(ref) {
+					return true
+				}
+			}
+		}
+		path = path[:len(path)-1]
+		return false
+	}
+	if visit(start) {
+		return path
+	}
+	return nil
+}
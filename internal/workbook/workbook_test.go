@@ -0,0 +1,293 @@
+package workbook
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/ZHOUXING1997/math_calculation/internal"
+	"github.com/ZHOUXING1997/math_calculation/math_config"
+)
+
+// newOrderWorkbook 创建订单金额的公式集
+func newOrderWorkbook(t *testing.T) *Workbook {
+	t.Helper()
+	w := New(nil)
+	// 故意先定义依赖其他公式的公式
+	for _, f := range [][2]string{
+		{"total", "subtotal + tax"},
+		{"tax", "subtotal * rate"},
+		{"subtotal", "qty * price"},
+		{"shipping", "weight * 2"},
+	} {
+		if err := w.Define(f[0], f[1]); err != nil {
+			t.Fatalf("Define(%s) error = %v", f[0], err)
+		}
+	}
+	err := w.SetInputs(map[string]decimal.Decimal{
+		"qty":    decimal.NewFromInt(3),
+		"price":  decimal.NewFromInt(10),
+		"rate":   decimal.RequireFromString("0.1"),
+		"weight": decimal.NewFromInt(5),
+	})
+	if err != nil {
+		t.Fatalf("SetInputs() error = %v", err)
+	}
+	return w
+}
+
+func TestWorkbook_Value(t *testing.T) {
+	w := newOrderWorkbook(t)
+
+	tests := []struct {
+		name string
+		want decimal.Decimal
+	}{
+		{name: "subtotal", want: decimal.NewFromInt(30)},
+		{name: "tax", want: decimal.NewFromInt(3)},
+		{name: "total", want: decimal.NewFromInt(33)},
+		{name: "shipping", want: decimal.NewFromInt(10)},
+		{name: "qty", want: decimal.NewFromInt(3)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := w.Value(tt.name)
+			if err != nil {
+				t.Fatalf("Value() error = %v", err)
+			}
+			if !got.Equal(tt.want) {
+				t.Errorf("Value() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := w.Value("missing"); !errors.Is(err, internal.ErrUndefinedVariable) {
+		t.Errorf("Value(missing) error = %v, want %v", err, internal.ErrUndefinedVariable)
+	}
+	if got := w.Values(); len(got) != 4 || !got["total"].Equal(decimal.NewFromInt(33)) {
+		t.Errorf("Values() = %v", got)
+	}
+}
+
+func TestWorkbook_Order(t *testing.T) {
+	w := newOrderWorkbook(t)
+
+	if got, want := w.Formulas(), []string{"shipping", "subtotal", "tax", "total"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Formulas() = %v, want %v", got, want)
+	}
+	if got, want := w.Dependencies("total"), []string{"subtotal", "tax"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Dependencies(total) = %v, want %v", got, want)
+	}
+	if got, want := w.Dependents("subtotal"), []string{"tax", "total"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Dependents(subtotal) = %v, want %v", got, want)
+	}
+	if got, want := w.Dependents("rate"), []string{"tax"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Dependents(rate) = %v, want %v", got, want)
+	}
+	if expr, ok := w.Expression("tax"); !ok || expr != "subtotal * rate" {
+		t.Errorf("Expression(tax) = %q, %v", expr, ok)
+	}
+}
+
+func TestWorkbook_Recalculate(t *testing.T) {
+	w := newOrderWorkbook(t)
+
+	recalculated, err := w.Recalculate(context.Background())
+	if err != nil {
+		t.Fatalf("Recalculate() error = %v", err)
+	}
+	if want := []string{"shipping", "subtotal", "tax", "total"}; !reflect.DeepEqual(recalculated, want) {
+		t.Errorf("Recalculate() = %v, want %v", recalculated, want)
+	}
+
+	tests := []struct {
+		name   string
+		change func() error
+		want   []string
+	}{
+		{name: "没有变化", change: func() error { return nil }},
+		{name: "输入的值不变", change: func() error { return w.SetInput("qty", decimal.NewFromInt(3)) }},
+		{name: "只影响依赖的公式", change: func() error { return w.SetInput("rate", decimal.RequireFromString("0.2")) }, want: []string{"tax", "total"}},
+		{name: "间接依赖", change: func() error { return w.SetInput("price", decimal.NewFromInt(20)) }, want: []string{"subtotal", "tax", "total"}},
+		{name: "替换公式", change: func() error { return w.Define("subtotal", "qty * price - 1") }, want: []string{"subtotal", "tax", "total"}},
+		{name: "不相关的输入", change: func() error { return w.SetInput("weight", decimal.NewFromInt(1)) }, want: []string{"shipping"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.change(); err != nil {
+				t.Fatalf("change error = %v", err)
+			}
+			got, err := w.Recalculate(context.Background())
+			if err != nil {
+				t.Fatalf("Recalculate() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Recalculate() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	// (3 * 20 - 1) * 1.2
+	if got, _ := w.Value("total"); !got.Equal(decimal.RequireFromString("70.8")) {
+		t.Errorf("Value(total) = %v, want 70.8", got)
+	}
+}
+
+func TestWorkbook_Cycle(t *testing.T) {
+	w := newOrderWorkbook(t)
+
+	tests := []struct {
+		name       string
+		formula    string
+		expression string
+		wantPath   []string
+	}{
+		{name: "引用自身", formula: "x", expression: "x + 1", wantPath: []string{"x", "x"}},
+		{name: "间接循环", formula: "subtotal", expression: "total - tax", wantPath: []string{"subtotal", "total", "subtotal"}},
+		{name: "名称已被输入使用", formula: "price", expression: "total / qty", wantPath: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := w.Define(tt.formula, tt.expression)
+			if tt.wantPath == nil {
+				if !errors.Is(err, internal.ErrInvalidArgument) {
+					t.Errorf("Define() error = %v, want %v", err, internal.ErrInvalidArgument)
+				}
+				return
+			}
+			var cycleErr *internal.CycleError
+			if !errors.As(err, &cycleErr) || !errors.Is(err, internal.ErrCircularReference) {
+				t.Fatalf("Define() error = %v, want *CycleError", err)
+			}
+			if !reflect.DeepEqual(cycleErr.Path, tt.wantPath) {
+				t.Errorf("CycleError.Path = %v, want %v", cycleErr.Path, tt.wantPath)
+			}
+			if !strings.Contains(err.Error(), strings.Join(tt.wantPath, " -> ")) {
+				t.Errorf("CycleError.Error() = %q", err.Error())
+			}
+		})
+	}
+
+	// 出错时原有的公式保持不变
+	if expr, _ := w.Expression("subtotal"); expr != "qty * price" {
+		t.Errorf("Expression(subtotal) = %q, want unchanged", expr)
+	}
+	if _, ok := w.Expression("x"); ok {
+		t.Errorf("Expression(x) found, want removed")
+	}
+	if got, err := w.Value("total"); err != nil || !got.Equal(decimal.NewFromInt(33)) {
+		t.Errorf("Value(total) = %v, %v, want 33", got, err)
+	}
+
+	// 经过三个公式的循环
+	config := math_config.NewDefaultCalcConfig()
+	config.Locale = internal.LocaleEn
+	w = New(config)
+	_ = w.Define("a", "b + 1")
+	_ = w.Define("b", "c + 1")
+	err := w.Define("c", "a + 1")
+	if err == nil || err.Error() != "circular reference between formulas: c -> a -> b -> c" {
+		t.Errorf("Define() error = %v", err)
+	}
+
+	// 名称冲突的错误按配置的语言输出
+	_ = w.SetInput("rate", decimal.NewFromInt(1))
+	if err := w.Define("rate", "1"); err == nil || err.Error() != "position 0: rate is already an input: invalid argument" {
+		t.Errorf("Define(rate) error = %v", err)
+	}
+	if err := w.SetInput("a", decimal.Zero); err == nil || err.Error() != "position 0: a is already a formula: invalid argument" {
+		t.Errorf("SetInput(a) error = %v", err)
+	}
+	if err := w.Define("", "1"); err == nil || err.Error() != "position 0: formula name cannot be empty: invalid argument" {
+		t.Errorf("Define(\"\") error = %v", err)
+	}
+
+	// 公式集复制了配置，之后修改配置不影响公式集
+	config.Locale = internal.LocaleZh
+	config.Precision = 0
+	if err := w.Define("", "1"); err == nil || err.Error() != "position 0: formula name cannot be empty: invalid argument" {
+		t.Errorf("Define(\"\") after changing config error = %v", err)
+	}
+	_ = w.Define("third", "1 / 3")
+	if got, err := w.Value("third"); err != nil || !got.Equal(decimal.RequireFromString("0.3333333333")) {
+		t.Errorf("Value(third) = %v, %v, want 0.3333333333", got, err)
+	}
+}
+
+func TestWorkbook_Errors(t *testing.T) {
+	w := newOrderWorkbook(t)
+
+	// 解析错误和名称冲突
+	if err := w.Define("bad", "1 +"); !errors.Is(err, internal.ErrInvalidExpression) {
+		t.Errorf("Define(bad) error = %v, want %v", err, internal.ErrInvalidExpression)
+	}
+	if err := w.Define("", "1"); !errors.Is(err, internal.ErrInvalidArgument) {
+		t.Errorf("Define(\"\") error = %v, want %v", err, internal.ErrInvalidArgument)
+	}
+	if err := w.SetInput("tax", decimal.Zero); !errors.Is(err, internal.ErrInvalidArgument) {
+		t.Errorf("SetInput(tax) error = %v, want %v", err, internal.ErrInvalidArgument)
+	}
+
+	// 计算错误传递给依赖的公式，输入修正后恢复
+	if err := w.SetInput("qty", decimal.Zero); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Define("unit", "total / qty"); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Define("report", "unit + shipping"); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"unit", "report"} {
+		if _, err := w.Value(name); !errors.Is(err, internal.ErrDivisionByZero) {
+			t.Errorf("Value(%s) error = %v, want %v", name, err, internal.ErrDivisionByZero)
+		}
+	}
+	if _, ok := w.Values()["report"]; ok {
+		t.Errorf("Values() contains report, want omitted")
+	}
+	if err := w.SetInput("qty", decimal.NewFromInt(3)); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := w.Value("report"); err != nil || !got.Equal(decimal.NewFromInt(21)) {
+		t.Errorf("Value(report) = %v, %v, want 21", got, err)
+	}
+
+	// 删除公式后依赖它的公式缺少变量
+	if !w.Remove("shipping") || w.Remove("shipping") {
+		t.Errorf("Remove(shipping) should succeed exactly once")
+	}
+	if _, err := w.Value("report"); !errors.Is(err, internal.ErrUndefinedVariable) {
+		t.Errorf("Value(report) error = %v, want %v", err, internal.ErrUndefinedVariable)
+	}
+	if err := w.SetInput("shipping", decimal.NewFromInt(4)); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := w.Value("report"); err != nil || !got.Equal(decimal.NewFromInt(15)) {
+		t.Errorf("Value(report) = %v, %v, want 15", got, err)
+	}
+}
+
+func TestWorkbook_RecalculateCanceled(t *testing.T) {
+	w := newOrderWorkbook(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	recalculated, err := w.Recalculate(ctx)
+	if !errors.Is(err, internal.ErrCanceled) || len(recalculated) != 0 {
+		t.Errorf("Recalculate() = %v, %v, want %v", recalculated, err, internal.ErrCanceled)
+	}
+
+	// 未计算的公式留到下次
+	recalculated, err = w.Recalculate(context.Background())
+	if err != nil || len(recalculated) != 4 {
+		t.Errorf("Recalculate() = %v, %v, want all formulas", recalculated, err)
+	}
+}
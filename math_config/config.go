@@ -2,6 +2,8 @@ package math_config
 
 import (
 	"time"
+
+	"github.com/shopspring/decimal"
 )
 
 // PrecisionMode 精度设置方式
@@ -30,8 +32,22 @@ type CalcConfig struct {
 	UseExprCache           bool      // 是否使用表达式解析树缓存
 	UseLexerCache          bool      // 是否使用词法分析器缓存
 	DebugMode              DebugMode // debug 模式
+	Locale                 string    // 错误消息语言，如 "zh"、"en"，空表示中文
+
+	// 资源限制，防止恶意表达式耗尽内存或 CPU，在运算循环内部检查
+	MaxExponent   int64 // 幂运算指数绝对值的上限，0 表示不限制
+	MaxDigits     int   // 数字和中间结果的最大位数（整数位数与小数位数之和），0 表示不限制
+	MaxIterations int   // 平方根等迭代算法的最大迭代次数，0 表示使用默认值 100
+	MaxOperations int64 // 一次计算最多执行的运算次数（每个运算符、函数调用和乘方中的每次乘法），0 表示不限制
+
+	// 缺失变量的处理，变量不在变量 map 中且变量提供者也没有时，先使用默认值，没有默认值时按策略处理
+	VariableDefaults map[string]decimal.Decimal // 变量的默认值
+	MissingVariables MissingVariablePolicy      // 没有默认值时的处理策略
 }
 
+// DefaultMaxIterations 未设置 MaxIterations 时迭代算法的最大迭代次数
+const DefaultMaxIterations = 100
+
 // DefaultConfig 默认配置
 var DefaultConfig = NewDefaultCalcConfig()
 
@@ -46,5 +62,24 @@ func NewDefaultCalcConfig() *CalcConfig {
 		UseExprCache:           true,
 		UseLexerCache:          true,
 		DebugMode:              DebugNone,
+		MaxExponent:            10000,
+		MaxDigits:              10000,
+		MaxIterations:          DefaultMaxIterations,
+		MaxOperations:          1000000,
+	}
+}
+
+// Clone 返回配置的副本，副本中的 map 也是复制的，修改副本不会影响原配置；c 为 nil 时返回默认配置
+func (c *CalcConfig) Clone() *CalcConfig {
+	if c == nil {
+		return NewDefaultCalcConfig()
+	}
+	clone := *c
+	if c.VariableDefaults != nil {
+		clone.VariableDefaults = make(map[string]decimal.Decimal, len(c.VariableDefaults))
+		for k, v := range c.VariableDefaults {
+			clone.VariableDefaults[k] = v
+		}
 	}
+	return &clone
 }
@@ -3,6 +3,8 @@ package math_config
 import (
 	"testing"
 	"time"
+
+	"github.com/shopspring/decimal"
 )
 
 func TestGetDefaultCalcConfig(t *testing.T) {
@@ -16,6 +18,10 @@ func TestGetDefaultCalcConfig(t *testing.T) {
 		ApplyPrecisionEachStep: true,
 		UseExprCache:           true,
 		UseLexerCache:          true,
+		MaxExponent:            10000,
+		MaxDigits:              10000,
+		MaxIterations:          DefaultMaxIterations,
+		MaxOperations:          1000000,
 	}
 
 	if config.MaxRecursionDepth != expectedConfig.MaxRecursionDepth {
@@ -41,4 +47,41 @@ func TestGetDefaultCalcConfig(t *testing.T) {
 	if config.UseLexerCache != expectedConfig.UseLexerCache {
 		t.Errorf("NewDefaultCalcConfig().UseLexerCache = %v, want %v", config.UseLexerCache, expectedConfig.UseLexerCache)
 	}
+
+	if config.MaxExponent != expectedConfig.MaxExponent {
+		t.Errorf("NewDefaultCalcConfig().MaxExponent = %v, want %v", config.MaxExponent, expectedConfig.MaxExponent)
+	}
+
+	if config.MaxDigits != expectedConfig.MaxDigits {
+		t.Errorf("NewDefaultCalcConfig().MaxDigits = %v, want %v", config.MaxDigits, expectedConfig.MaxDigits)
+	}
+
+	if config.MaxIterations != expectedConfig.MaxIterations {
+		t.Errorf("NewDefaultCalcConfig().MaxIterations = %v, want %v", config.MaxIterations, expectedConfig.MaxIterations)
+	}
+
+	if config.MaxOperations != expectedConfig.MaxOperations {
+		t.Errorf("NewDefaultCalcConfig().MaxOperations = %v, want %v", config.MaxOperations, expectedConfig.MaxOperations)
+	}
+}
+
+func TestCalcConfig_Clone(t *testing.T) {
+	config := NewDefaultCalcConfig()
+	config.VariableDefaults = map[string]decimal.Decimal{"rate": decimal.NewFromInt(1)}
+
+	clone := config.Clone()
+	clone.Precision = 2
+	clone.VariableDefaults["rate"] = decimal.NewFromInt(2)
+
+	if config.Precision != 10 {
+		t.Errorf("Clone() shares Precision, original = %v", config.Precision)
+	}
+	if !config.VariableDefaults["rate"].Equal(decimal.NewFromInt(1)) {
+		t.Errorf("Clone() shares VariableDefaults, original = %v", config.VariableDefaults)
+	}
+
+	var nilConfig *CalcConfig
+	if got := nilConfig.Clone(); got == nil || got.Precision != 10 {
+		t.Errorf("nil Clone() = %+v, want default config", got)
+	}
 }
@@ -0,0 +1,10 @@
+package math_config
+
+// MissingVariablePolicy 变量未定义且没有默认值时的处理策略
+type MissingVariablePolicy int
+
+const (
+	MissingAsError MissingVariablePolicy = iota // 返回 ErrUndefinedVariable（默认）
+	MissingAsZero                               // 按 0 计算
+	MissingAsNull                               // 按空值计算，含有空值的表达式结果为空，返回 ErrNullResult
+)
@@ -0,0 +1,89 @@
+package math_calculation
+
+import (
+	"context"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/ZHOUXING1997/math_calculation/internal/pool"
+	"github.com/ZHOUXING1997/math_calculation/math_config"
+)
+
+// Job 并行计算的一个任务，每个任务有自己的表达式和变量
+type Job struct {
+	Expression string
+	Vars       map[string]decimal.Decimal
+}
+
+// JobResult 一个任务的结果，Index 为任务在 jobs 中的位置
+type JobResult = pool.Result
+
+// ParallelOptions 并行计算选项
+type ParallelOptions struct {
+	Pool        *WorkerPool // 使用的工作池，为 nil 时为这次计算创建临时工作池
+	Concurrency int         // 临时工作池的 worker 数，0 表示 runtime.GOMAXPROCS(0)
+	FailFast    bool        // 有任务出错时取消其余任务；为 false 时出错的任务不影响其他任务
+}
+
+// WorkerPool 可以复用的计算工作池，worker 数量固定，可以被多次并行计算同时使用
+// 不再使用时调用 Close 释放 worker
+type WorkerPool struct {
+	pool *pool.Pool
+}
+
+// NewWorkerPool 创建有 workers 个 worker 的工作池，workers 小于等于 0 时使用 runtime.GOMAXPROCS(0)
+func NewWorkerPool(workers int) *WorkerPool {
+	return &WorkerPool{pool: pool.New(workers)}
+}
+
+// Workers 返回 worker 的数量
+func (p *WorkerPool) Workers() int {
+	return p.pool.Workers()
+}
+
+// Close 关闭工作池，等待已经开始的任务完成，之后提交的任务返回 ErrCanceled
+func (p *WorkerPool) Close() {
+	p.pool.Close()
+}
+
+// CalculateJobs 并行计算多个任务，等待全部完成后按任务的顺序返回结果和错误
+func CalculateJobs(ctx context.Context, jobs []Job, cfg *math_config.CalcConfig, options ParallelOptions) ([]decimal.Decimal, []error) {
+	return pool.Collect(StreamJobs(ctx, jobs, cfg, options), len(jobs))
+}
+
+// StreamJobs 并行计算多个任务，按完成顺序从返回的通道输出每个任务的结果，所有任务都有结果后关闭通道
+// ctx 结束后尚未开始的任务不再计算，直接返回与 CalculateContext 相同的取消错误：
+//
+//	for result := range StreamJobs(ctx, jobs, cfg, ParallelOptions{Concurrency: 8}) {
+//		if result.Err != nil {
+//			log.Printf("job %d: %v", result.Index, result.Err)
+//		}
+//	}
+func StreamJobs(ctx context.Context, jobs []Job, cfg *math_config.CalcConfig, options ParallelOptions) <-chan JobResult {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if cfg == nil {
+		cfg = math_config.NewDefaultCalcConfig()
+	}
+
+	// 验证配置，在副本上补充默认值，避免修改调用方可能共用的配置
+	if cfg.Timeout <= 0 || cfg.MaxRecursionDepth <= 0 {
+		cfg = cfg.Clone()
+		if cfg.Timeout <= 0 {
+			cfg.Timeout = math_config.DefaultConfig.Timeout
+		}
+		if cfg.MaxRecursionDepth <= 0 {
+			cfg.MaxRecursionDepth = math_config.DefaultConfig.MaxRecursionDepth
+		}
+	}
+
+	task := func(ctx context.Context, index int) (decimal.Decimal, error) {
+		return CalculateContext(ctx, jobs[index].Expression, jobs[index].Vars, cfg)
+	}
+	poolOptions := pool.Options{FailFast: options.FailFast, Locale: cfg.Locale}
+	if options.Pool != nil {
+		return options.Pool.pool.Stream(ctx, len(jobs), task, poolOptions)
+	}
+	return pool.Stream(ctx, options.Concurrency, len(jobs), task, poolOptions)
+}
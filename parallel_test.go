@@ -0,0 +1,95 @@
+package math_calculation
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestCalculateJobs(t *testing.T) {
+	jobs := []Job{
+		{Expression: "price * qty", Vars: map[string]decimal.Decimal{"price": decimal.NewFromInt(3), "qty": decimal.NewFromInt(4)}},
+		{Expression: "price * qty", Vars: map[string]decimal.Decimal{"price": decimal.NewFromInt(5), "qty": decimal.NewFromInt(6)}},
+		{Expression: "1 / x", Vars: map[string]decimal.Decimal{"x": decimal.Zero}},
+		{Expression: "x + 1", Vars: map[string]decimal.Decimal{"x": decimal.NewFromInt(9)}},
+	}
+
+	pool := NewWorkerPool(2)
+	defer pool.Close()
+
+	tests := []struct {
+		name    string
+		options ParallelOptions
+	}{
+		{name: "临时工作池", options: ParallelOptions{Concurrency: 3}},
+		{name: "复用工作池", options: ParallelOptions{Pool: pool}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			results, errs := CalculateJobs(context.Background(), jobs, nil, tt.options)
+			want := []int64{12, 30, 0, 10}
+			for i := range jobs {
+				if i == 2 {
+					if !errors.Is(errs[i], ErrDivisionByZero) {
+						t.Errorf("job %d error = %v, want %v", i, errs[i], ErrDivisionByZero)
+					}
+					continue
+				}
+				if errs[i] != nil || !results[i].Equal(decimal.NewFromInt(want[i])) {
+					t.Errorf("job %d = %v, %v, want %d", i, results[i], errs[i], want[i])
+				}
+			}
+		})
+	}
+
+	// 流式获取结果
+	seen := make(map[int]bool)
+	for result := range StreamJobs(context.Background(), jobs, nil, ParallelOptions{Pool: pool}) {
+		seen[result.Index] = true
+	}
+	if len(seen) != len(jobs) {
+		t.Errorf("StreamJobs() returned %d results, want %d", len(seen), len(jobs))
+	}
+
+	// 有任务出错时取消其余任务
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, errs := CalculateJobs(ctx, jobs, nil, ParallelOptions{FailFast: true})
+	for i, err := range errs {
+		if !errors.Is(err, ErrCanceled) {
+			t.Errorf("job %d error = %v, want %v", i, err, ErrCanceled)
+		}
+	}
+}
+
+func TestCalculatorJobs(t *testing.T) {
+	calc := NewCalculator(nil).
+		WithVariable("rate", decimal.RequireFromString("0.1")).
+		WithParallelOptions(ParallelOptions{Concurrency: 2})
+
+	results, errs := calc.CalculateJobs(context.Background(), []Job{
+		{Expression: "price * (1 + rate)", Vars: map[string]decimal.Decimal{"price": decimal.NewFromInt(10)}},
+		{Expression: "price * (1 + rate)", Vars: map[string]decimal.Decimal{"price": decimal.NewFromInt(10), "rate": decimal.RequireFromString("0.2")}},
+		{Expression: "rate * 10"},
+	})
+	want := []string{"11", "12", "1"}
+	for i := range want {
+		if errs[i] != nil || !results[i].Equal(decimal.RequireFromString(want[i])) {
+			t.Errorf("Calculator.CalculateJobs()[%d] = %v, %v, want %s", i, results[i], errs[i], want[i])
+		}
+	}
+
+	count := 0
+	for result := range calc.StreamJobs(context.Background(), []Job{{Expression: "rate + 1"}}) {
+		if result.Err != nil || !result.Value.Equal(decimal.RequireFromString("1.1")) {
+			t.Errorf("Calculator.StreamJobs() = %v, %v, want 1.1", result.Value, result.Err)
+		}
+		count++
+	}
+	if count != 1 {
+		t.Errorf("Calculator.StreamJobs() returned %d results, want 1", count)
+	}
+}
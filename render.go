@@ -0,0 +1,25 @@
+package math_calculation
+
+import (
+	"github.com/ZHOUXING1997/math_calculation/internal/croe"
+)
+
+// ToLaTeX 将表达式渲染为 LaTeX 公式，options.Variables 非空时用变量值替换变量名
+func ToLaTeX(expression string, options RenderOptions) (string, error) {
+	compiled, err := croe.Compile(expression, nil)
+	if err != nil {
+		return "", err
+	}
+
+	return compiled.LaTeX(options), nil
+}
+
+// ToMathML 将表达式渲染为 MathML 标记，options.Variables 非空时用变量值替换变量名
+func ToMathML(expression string, options RenderOptions) (string, error) {
+	compiled, err := croe.Compile(expression, nil)
+	if err != nil {
+		return "", err
+	}
+
+	return compiled.MathML(options), nil
+}
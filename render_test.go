@@ -0,0 +1,35 @@
+package math_calculation
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/ZHOUXING1997/math_calculation/internal/render"
+)
+
+// TestToLaTeX 测试渲染为 LaTeX 和 MathML
+func TestToLaTeX(t *testing.T) {
+	options := render.Options{Variables: map[string]decimal.Decimal{"amount": decimal.NewFromInt(200)}}
+
+	latex, err := ToLaTeX("ceil(amount * rate / 100, 2)", options)
+	if err != nil {
+		t.Fatalf("ToLaTeX() error = %v", err)
+	}
+	if want := `\operatorname{ceil}\left(\frac{200 \cdot \mathrm{rate}}{100}, 2\right)`; latex != want {
+		t.Errorf("ToLaTeX() = %s, want %s", latex, want)
+	}
+
+	mathml, err := ToMathML("sqrt(amount)", options)
+	if err != nil {
+		t.Fatalf("ToMathML() error = %v", err)
+	}
+	if !strings.Contains(mathml, "<msqrt><mn>200</mn></msqrt>") {
+		t.Errorf("ToMathML() = %s, want <msqrt> with substituted value", mathml)
+	}
+
+	if _, err := ToLaTeX("1 +", render.Options{}); err == nil {
+		t.Errorf("ToLaTeX() expected error for invalid expression")
+	}
+}
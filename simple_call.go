@@ -2,42 +2,49 @@ package math_calculation
 
 import (
 	"context"
-	"fmt"
-	"sync"
 
 	"github.com/shopspring/decimal"
 
 	"github.com/ZHOUXING1997/math_calculation/math_config"
 
 	"github.com/ZHOUXING1997/math_calculation/internal"
+	"github.com/ZHOUXING1997/math_calculation/internal/binder"
 	"github.com/ZHOUXING1997/math_calculation/internal/croe"
 	"github.com/ZHOUXING1997/math_calculation/internal/math_utils"
 )
 
 // Calculate 计算表达式的便捷函数
 func Calculate(expression string, vars map[string]decimal.Decimal, cfg *math_config.CalcConfig) (decimal.Decimal, error) {
-	// 验证表达式
-	if len(expression) == 0 {
-		return decimal.Zero, &internal.ParseError{
-			Pos:     0,
-			Message: "空表达式",
-			Cause:   internal.ErrInvalidExpression,
-		}
-	}
+	return CalculateContext(context.Background(), expression, vars, cfg)
+}
+
+// CalculateContext 使用调用方的上下文计算表达式
+// 计算在 ctx 取消、ctx 的截止时间到达或配置的超时时间到达时停止，
+// 前两种情况返回 ErrCanceled（同时可以用 errors.Is 判断 context.Canceled、context.DeadlineExceeded），
+// 配置的超时返回 ErrExecutionTimeout
+func CalculateContext(ctx context.Context, expression string, vars map[string]decimal.Decimal, cfg *math_config.CalcConfig) (decimal.Decimal, error) {
 	if cfg == nil {
 		cfg = math_config.NewDefaultCalcConfig()
 	}
 
-	// 验证配置
-	if cfg.Timeout <= 0 {
-		cfg.Timeout = math_config.DefaultConfig.Timeout
+	// 验证表达式
+	if len(expression) == 0 {
+		return decimal.Zero, internal.Localize(internal.NewParseError(0, internal.ErrInvalidExpression, "", internal.MsgEmptyExpression), cfg.Locale)
 	}
-	if cfg.MaxRecursionDepth <= 0 {
-		cfg.MaxRecursionDepth = math_config.DefaultConfig.MaxRecursionDepth
+
+	// 验证配置，在副本上补充默认值，避免修改调用方可能共用的配置
+	if cfg.Timeout <= 0 || cfg.MaxRecursionDepth <= 0 {
+		cfg = cfg.Clone()
+		if cfg.Timeout <= 0 {
+			cfg.Timeout = math_config.DefaultConfig.Timeout
+		}
+		if cfg.MaxRecursionDepth <= 0 {
+			cfg.MaxRecursionDepth = math_config.DefaultConfig.MaxRecursionDepth
+		}
 	}
 
-	// 创建上下文并设置超时
-	ctx, cancel := context.WithTimeout(context.Background(), cfg.Timeout)
+	// 从调用方的上下文派生计算使用的上下文，设置超时和运算次数预算
+	ctx, cancel := math_utils.EvalContext(ctx, cfg)
 	defer cancel()
 
 	// 创建解析器，使用完整的配置
@@ -46,7 +53,7 @@ func Calculate(expression string, vars map[string]decimal.Decimal, cfg *math_con
 	// 解析表达式
 	ast, err := parser.Parse(expression)
 	if err != nil {
-		return decimal.Zero, err
+		return decimal.Zero, internal.Localize(err, cfg.Locale)
 	}
 
 	// 创建变量副本，避免并发问题
@@ -58,7 +65,7 @@ func Calculate(expression string, vars map[string]decimal.Decimal, cfg *math_con
 	// 计算表达式
 	result, err := ast.Eval(ctx, varsCopy, cfg)
 	if err != nil {
-		return decimal.Zero, err
+		return decimal.Zero, internal.Localize(internal.Locate(err, expression), cfg.Locale)
 	}
 
 	// 如果策略是只在最终结果控制精度，则在这里应用精度控制
@@ -69,67 +76,44 @@ func Calculate(expression string, vars map[string]decimal.Decimal, cfg *math_con
 	return result, nil
 }
 
-// CalculateParallel 并行计算多个表达式
-func CalculateParallel(expressions []string, vars map[string]decimal.Decimal, cfg *math_config.CalcConfig) ([]decimal.Decimal, []error) {
-	// 验证表达式列表
-	if len(expressions) == 0 {
-		return []decimal.Decimal{}, []error{}
-	}
-	if cfg == nil {
-		cfg = math_config.NewDefaultCalcConfig()
-	}
+// CalculateNullable 计算表达式，结果为空时返回无效的 NullDecimal 而不是 ErrNullResult
+// 配置 MissingVariables 为 MissingAsNull 时，含有未定义变量的表达式结果为空
+func CalculateNullable(expression string, vars map[string]decimal.Decimal, cfg *math_config.CalcConfig) (NullDecimal, error) {
+	return math_utils.Nullable(Calculate(expression, vars, cfg))
+}
 
-	// 验证配置
-	if cfg.Timeout <= 0 {
-		cfg.Timeout = math_config.DefaultConfig.Timeout
-	}
-	if cfg.MaxRecursionDepth <= 0 {
-		cfg.MaxRecursionDepth = math_config.DefaultConfig.MaxRecursionDepth
-	}
+// CalculateWithProvider 使用变量提供者计算表达式，变量的值在计算时按需获取，
+// 只查询表达式用到的变量，同一次计算内每个变量只查询一次
+func CalculateWithProvider(ctx context.Context, expression string, provider VariableProvider, cfg *math_config.CalcConfig) (decimal.Decimal, error) {
+	return CalculateContext(math_utils.WithProvider(ctx, provider), expression, nil, cfg)
+}
 
-	// 预分配结果切片
-	results := make([]decimal.Decimal, len(expressions))
-	errs := make([]error, len(expressions))
+// BindStruct 将结构体或结构体指针的字段绑定为变量，返回的变量提供者可以传给 CalculateWithProvider 等函数
+// 字段名默认转换为蛇形命名（UnitPrice -> unit_price），可以用 `calc:"name"` 标签指定变量名、`calc:"-"` 忽略字段，
+// 嵌套结构体的字段用点号访问，如 customer.level。支持整数、浮点数、数字字符串、decimal.Decimal 以及它们的指针，
+// 每种结构体类型的字段访问计划只生成一次
+func BindStruct(v interface{}) (*StructProvider, error) {
+	return binder.Bind(v)
+}
 
-	// 限制并发数量，避免资源耗尽
-	maxConcurrency := 10
-	if len(expressions) < maxConcurrency {
-		maxConcurrency = len(expressions)
-	}
+// CompileJSON 从 JSON 重建预编译表达式，不会重新解析表达式
+// JSON 可以通过对预编译表达式调用 json.Marshal 得到
+func CompileJSON(data []byte, cfg *math_config.CalcConfig) (*CompiledExpression, error) {
+	return croe.CompileJSON(data, cfg)
+}
 
-	// 使用信号量控制并发
-	sem := make(chan struct{}, maxConcurrency)
-	wg := sync.WaitGroup{}
-
-	// 并行计算每个表达式
-	for i, expr := range expressions {
-		wg.Add(1)
-		sem <- struct{}{} // 获取信号量，如果满了就会阻塞
-		go func(index int, expression string) {
-			defer func() {
-				<-sem // 释放信号量
-				wg.Done()
-
-				// 捕获panic
-				if r := recover(); r != nil {
-					errs[index] = fmt.Errorf("计算表达式时发生异常: %v", r)
-					results[index] = decimal.Zero
-				}
-			}()
-
-			// 创建变量副本，避免并发问题
-			varsCopy := make(map[string]decimal.Decimal, len(vars))
-			for k, v := range vars {
-				varsCopy[k] = v
-			}
-
-			result, err := Calculate(expression, varsCopy, cfg)
-			results[index] = result
-			errs[index] = err
-		}(i, expr)
-	}
+// CalculateParallel 并行计算多个表达式，所有表达式使用相同的变量
+func CalculateParallel(expressions []string, vars map[string]decimal.Decimal, cfg *math_config.CalcConfig) ([]decimal.Decimal, []error) {
+	return CalculateParallelContext(context.Background(), expressions, vars, cfg)
+}
 
-	// 等待所有计算完成
-	wg.Wait()
-	return results, errs
+// CalculateParallelContext 使用调用方的上下文并行计算多个表达式，最多 runtime.GOMAXPROCS(0) 个表达式同时计算
+// ctx 结束后尚未开始的表达式不再计算，直接返回与 CalculateContext 相同的取消错误
+// 需要每个表达式使用不同的变量、指定并发数或流式获取结果时使用 CalculateJobs 或 StreamJobs
+func CalculateParallelContext(ctx context.Context, expressions []string, vars map[string]decimal.Decimal, cfg *math_config.CalcConfig) ([]decimal.Decimal, []error) {
+	jobs := make([]Job, len(expressions))
+	for i, expression := range expressions {
+		jobs[i] = Job{Expression: expression, Vars: vars}
+	}
+	return CalculateJobs(ctx, jobs, cfg, ParallelOptions{})
 }
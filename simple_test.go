@@ -1,7 +1,11 @@
 package math_calculation
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"testing"
+	"time"
 
 	"github.com/ZHOUXING1997/math_calculation/math_config"
 	"github.com/shopspring/decimal"
@@ -36,6 +40,14 @@ func TestCalculate(t *testing.T) {
 			want:    decimal.NewFromInt(30),
 			wantErr: false,
 		},
+		{
+			name:       "科学计数法",
+			expression: "1.5e3 + 2E-1",
+			vars:       nil,
+			config:     nil,
+			want:       decimal.RequireFromString("1500.2"),
+			wantErr:    false,
+		},
 		{
 			name:       "连续运算符",
 			expression: "(867255+-440375)-426878",
@@ -121,3 +133,312 @@ func TestCalculateParallel(t *testing.T) {
 		}
 	}
 }
+
+// TestCalculateContext 测试使用调用方上下文的计算
+func TestCalculateContext(t *testing.T) {
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+	expired, cancelExpired := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+	defer cancelExpired()
+
+	shortTimeout := math_config.NewDefaultCalcConfig()
+	shortTimeout.Timeout = time.Nanosecond
+
+	tests := []struct {
+		name      string
+		ctx       context.Context
+		config    *math_config.CalcConfig
+		want      decimal.Decimal
+		wantErr   error
+		wantCause error
+	}{
+		{name: "正常计算", ctx: context.Background(), want: decimal.NewFromInt(3)},
+		{name: "调用方取消", ctx: canceled, wantErr: ErrCanceled, wantCause: context.Canceled},
+		{name: "调用方截止时间到达", ctx: expired, wantErr: ErrCanceled, wantCause: context.DeadlineExceeded},
+		{name: "配置的超时", ctx: context.Background(), config: shortTimeout, wantErr: ErrExecutionTimeout},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := CalculateContext(tt.ctx, "1 + 2", nil, tt.config)
+			if tt.wantErr == nil {
+				if err != nil {
+					t.Fatalf("CalculateContext() error = %v", err)
+				}
+				if !got.Equal(tt.want) {
+					t.Errorf("CalculateContext() = %v, want %v", got, tt.want)
+				}
+				return
+			}
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("CalculateContext() error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantCause != nil && !errors.Is(err, tt.wantCause) {
+				t.Errorf("CalculateContext() error = %v, want %v", err, tt.wantCause)
+			}
+			if tt.wantErr == ErrCanceled && errors.Is(err, ErrExecutionTimeout) {
+				t.Errorf("CalculateContext() error = %v, should not be %v", err, ErrExecutionTimeout)
+			}
+		})
+	}
+
+	// 预编译表达式和计算器的上下文版本
+	compiled, err := NewCalculator(nil).Compile("x * 2")
+	if err != nil {
+		t.Fatalf("Compile() error = %v", err)
+	}
+	if _, err := compiled.EvaluateContext(canceled, map[string]decimal.Decimal{"x": decimal.NewFromInt(1)}); !errors.Is(err, ErrCanceled) {
+		t.Errorf("EvaluateContext() error = %v, want %v", err, ErrCanceled)
+	}
+	_, err = NewCalculator(nil).CalculateContext(canceled, "1 + 2")
+	if !errors.Is(err, ErrCanceled) || ErrorCodeOf(err) != CodeCanceled {
+		t.Errorf("Calculator.CalculateContext() error = %v, code = %q", err, ErrorCodeOf(err))
+	}
+	_, _, err = NewCalculator(nil).WithDebugMode(math_config.DebugBasic).CalculateWithDebugContext(canceled, "1 + 2")
+	if !errors.Is(err, ErrCanceled) {
+		t.Errorf("Calculator.CalculateWithDebugContext() error = %v, want %v", err, ErrCanceled)
+	}
+}
+
+// TestCalculateParallelContext 测试使用调用方上下文的并行计算
+func TestCalculateParallelContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	expressions := []string{"1 + 2", "3 * 4", "10 / 2"}
+	_, errs := CalculateParallelContext(ctx, expressions, nil, nil)
+	for i, err := range errs {
+		if !errors.Is(err, ErrCanceled) || !errors.Is(err, context.Canceled) {
+			t.Errorf("CalculateParallelContext() error at index %d = %v, want %v", i, err, ErrCanceled)
+		}
+	}
+
+	results, errs := NewCalculator(nil).CalculateParallelContext(context.Background(), expressions)
+	for i, err := range errs {
+		if err != nil {
+			t.Errorf("Calculator.CalculateParallelContext() error at index %d: %v", i, err)
+		}
+	}
+	if !results[1].Equal(decimal.NewFromInt(12)) {
+		t.Errorf("Calculator.CalculateParallelContext()[1] = %v, want 12", results[1])
+	}
+}
+
+// countingProvider 记录每个变量被查询的次数
+type countingProvider struct {
+	values map[string]decimal.Decimal
+	calls  map[string]int
+}
+
+func (p *countingProvider) Lookup(_ context.Context, name string) (decimal.Decimal, bool, error) {
+	p.calls[name]++
+	val, ok := p.values[name]
+	return val, ok, nil
+}
+
+// TestCalculateWithProvider 测试使用变量提供者计算
+func TestCalculateWithProvider(t *testing.T) {
+	newProvider := func() *countingProvider {
+		values := make(map[string]decimal.Decimal, 500)
+		for i := 0; i < 500; i++ {
+			values[fmt.Sprintf("f%d", i)] = decimal.NewFromInt(int64(i))
+		}
+		return &countingProvider{values: values, calls: map[string]int{}}
+	}
+
+	// 只查询用到的变量，同一次计算内每个变量只查询一次
+	provider := newProvider()
+	got, err := CalculateWithProvider(context.Background(), "f2 * f2 + f3", provider, nil)
+	if err != nil {
+		t.Fatalf("CalculateWithProvider() error = %v", err)
+	}
+	if !got.Equal(decimal.NewFromInt(7)) {
+		t.Errorf("CalculateWithProvider() = %v, want 7", got)
+	}
+	if len(provider.calls) != 2 || provider.calls["f2"] != 1 || provider.calls["f3"] != 1 {
+		t.Errorf("provider calls = %v, want f2:1 f3:1", provider.calls)
+	}
+
+	// 未定义的变量
+	if _, err := CalculateWithProvider(context.Background(), "f1 + g", provider, nil); !errors.Is(err, ErrUndefinedVariable) {
+		t.Errorf("CalculateWithProvider() error = %v, want %v", err, ErrUndefinedVariable)
+	}
+
+	// 预编译表达式，每次计算重新查询
+	compiled, err := NewCalculator(nil).Compile("f10 + f10")
+	if err != nil {
+		t.Fatalf("Compile() error = %v", err)
+	}
+	provider = newProvider()
+	for i := 0; i < 2; i++ {
+		got, err = compiled.EvaluateWithProvider(context.Background(), provider)
+		if err != nil || !got.Equal(decimal.NewFromInt(20)) {
+			t.Errorf("EvaluateWithProvider() = %v, %v, want 20", got, err)
+		}
+	}
+	if provider.calls["f10"] != 2 {
+		t.Errorf("provider calls for f10 = %d, want 2", provider.calls["f10"])
+	}
+
+	// 计算器，WithVariable 设置的变量优先
+	calc := NewCalculator(nil).
+		WithVariable("f1", decimal.NewFromInt(100)).
+		WithProvider(MapProvider{"f1": decimal.NewFromInt(1), "f2": decimal.NewFromInt(2)})
+	got, err = calc.Calculate("f1 + f2")
+	if err != nil || !got.Equal(decimal.NewFromInt(102)) {
+		t.Errorf("Calculator.Calculate() = %v, %v, want 102", got, err)
+	}
+	results, errs := calc.CalculateParallel([]string{"f2 * 3", "f1"})
+	if errs[0] != nil || !results[0].Equal(decimal.NewFromInt(6)) || errs[1] != nil || !results[1].Equal(decimal.NewFromInt(100)) {
+		t.Errorf("Calculator.CalculateParallel() = %v, %v", results, errs)
+	}
+	got, _, err = calc.CalculateWithDebug("f2 + 1")
+	if err != nil || !got.Equal(decimal.NewFromInt(3)) {
+		t.Errorf("Calculator.CalculateWithDebug() = %v, %v, want 3", got, err)
+	}
+}
+
+func TestHierarchicalIdentifiers(t *testing.T) {
+	provider := NestedMapProvider{
+		"order": map[string]interface{}{
+			"items": []interface{}{
+				map[string]interface{}{"unit price": 12.5, "qty": 2},
+				map[string]interface{}{"unit price": "3.5", "qty": 4},
+			},
+		},
+		"数量": 3,
+	}
+
+	tests := []struct {
+		name       string
+		expression string
+		want       decimal.Decimal
+		wantErr    error
+	}{
+		{name: "路径和下标", expression: "order.items[0].`unit price` * order.items[0].qty", want: decimal.NewFromInt(25)},
+		{name: "方括号引用的 Unicode 变量", expression: "order.items[1].[unit price] * [数量]", want: decimal.RequireFromString("10.5")},
+		{name: "未加引用的 Unicode 变量", expression: "数量 + 1", want: decimal.NewFromInt(4)},
+		{name: "路径不存在", expression: "order.items[2].qty", wantErr: ErrUndefinedVariable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := CalculateWithProvider(context.Background(), tt.expression, provider, nil)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("CalculateWithProvider() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil || !got.Equal(tt.want) {
+				t.Errorf("CalculateWithProvider() = %v, %v, want %v", got, err, tt.want)
+			}
+		})
+	}
+
+	// 变量 map 中使用规范的变量名
+	got, err := Calculate("`unit price` * [税率]", map[string]decimal.Decimal{
+		"unit price": decimal.NewFromInt(10),
+		"税率":         decimal.RequireFromString("0.13"),
+	}, nil)
+	if err != nil || !got.Equal(decimal.RequireFromString("1.3")) {
+		t.Errorf("Calculate() = %v, %v, want 1.3", got, err)
+	}
+
+	// 引用的名称中的加减号不会被当作连续运算符简化
+	calc := NewCalculator(nil).WithVariables(map[string]decimal.Decimal{
+		"a--b": decimal.NewFromInt(5),
+		"c+-d": decimal.NewFromInt(2),
+	})
+	for expression, want := range map[string]int64{
+		"`a--b` + 1":       6,
+		"[a--b]+1":         6,
+		"[a--b] -- [c+-d]": 7,
+		"`a--b` +- `c+-d`": 3,
+	} {
+		got, err := calc.Calculate(expression)
+		if err != nil || !got.Equal(decimal.NewFromInt(want)) {
+			t.Errorf("Calculator.Calculate(%q) = %v, %v, want %d", expression, got, err, want)
+		}
+	}
+}
+
+func TestMissingVariables(t *testing.T) {
+	vars := map[string]decimal.Decimal{"price": decimal.NewFromInt(10)}
+
+	// 按空值计算
+	cfg := math_config.NewDefaultCalcConfig()
+	cfg.MissingVariables = math_config.MissingAsNull
+	got, err := CalculateNullable("price * (1 - discount)", vars, cfg)
+	if err != nil || got.Valid {
+		t.Errorf("CalculateNullable() = %v, %v, want null", got, err)
+	}
+	if _, err := Calculate("price * (1 - discount)", vars, cfg); !errors.Is(err, ErrNullResult) || ErrorCodeOf(err) != CodeNullResult {
+		t.Errorf("Calculate() error = %v, want %v", err, ErrNullResult)
+	}
+	got, err = CalculateNullable("price * (1 - coalesce(discount, 0.2))", vars, cfg)
+	if err != nil || !got.Valid || !got.Decimal.Equal(decimal.NewFromInt(8)) {
+		t.Errorf("CalculateNullable() = %v, %v, want 8", got, err)
+	}
+
+	// 默认值和按 0 计算
+	calc := NewCalculator(nil).
+		WithVariables(vars).
+		WithDefault("discount", decimal.RequireFromString("0.1")).
+		WithMissingVariables(math_config.MissingAsZero)
+	tests := []struct {
+		expression string
+		want       decimal.Decimal
+	}{
+		{expression: "price * (1 - discount)", want: decimal.NewFromInt(9)},
+		{expression: "price + shipping", want: decimal.NewFromInt(10)},
+		{expression: "isdefined(shipping) * 100 + isdefined(discount)", want: decimal.NewFromInt(1)},
+	}
+	for _, tt := range tests {
+		got, err := calc.CalculateNullable(tt.expression)
+		if err != nil || !got.Valid || !got.Decimal.Equal(tt.want) {
+			t.Errorf("Calculator.CalculateNullable(%q) = %v, %v, want %v", tt.expression, got, err, tt.want)
+		}
+	}
+
+	// 预编译表达式
+	compiled, err := NewCalculator(cfg).Compile("a + b")
+	if err != nil {
+		t.Fatalf("Compile() error = %v", err)
+	}
+	if got, err := compiled.EvaluateNullable(map[string]decimal.Decimal{"a": decimal.NewFromInt(1)}); err != nil || got.Valid {
+		t.Errorf("EvaluateNullable() = %v, %v, want null", got, err)
+	}
+	if got, err := compiled.EvaluateNullable(map[string]decimal.Decimal{"a": decimal.NewFromInt(1), "b": decimal.NewFromInt(2)}); err != nil || !got.Decimal.Equal(decimal.NewFromInt(3)) {
+		t.Errorf("EvaluateNullable() = %v, %v, want 3", got, err)
+	}
+}
+
+func TestEvaluateBatch(t *testing.T) {
+	compiled, err := NewCalculator(nil).Compile("price * qty")
+	if err != nil {
+		t.Fatalf("Compile() error = %v", err)
+	}
+
+	rows := []map[string]decimal.Decimal{
+		{"price": decimal.RequireFromString("9.5"), "qty": decimal.NewFromInt(2)},
+		{"price": decimal.NewFromInt(3), "qty": decimal.NewFromInt(4)},
+		{"price": decimal.NewFromInt(3)},
+	}
+	results, errs := compiled.EvaluateBatchContext(context.Background(), rows, BatchOptions{ChunkSize: 2})
+	if errs[0] != nil || !results[0].Equal(decimal.NewFromInt(19)) || errs[1] != nil || !results[1].Equal(decimal.NewFromInt(12)) {
+		t.Errorf("EvaluateBatchContext() = %v, %v, want [19 12]", results[:2], errs[:2])
+	}
+	if !errors.Is(errs[2], ErrUndefinedVariable) {
+		t.Errorf("EvaluateBatchContext() error = %v, want %v", errs[2], ErrUndefinedVariable)
+	}
+
+	// 按列输入
+	results, errs, err = compiled.EvaluateColumns(map[string][]decimal.Decimal{
+		"price": {decimal.NewFromInt(1), decimal.NewFromInt(2)},
+		"qty":   {decimal.NewFromInt(5), decimal.NewFromInt(6)},
+	})
+	if err != nil || errs[0] != nil || errs[1] != nil || !results[0].Equal(decimal.NewFromInt(5)) || !results[1].Equal(decimal.NewFromInt(12)) {
+		t.Errorf("EvaluateColumns() = %v, %v, %v, want [5 12]", results, errs, err)
+	}
+}
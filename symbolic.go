@@ -0,0 +1,56 @@
+package math_calculation
+
+import (
+	"github.com/ZHOUXING1997/math_calculation/math_config"
+
+	"github.com/ZHOUXING1997/math_calculation/internal/croe"
+)
+
+// Derivative 对表达式关于变量 variable 求导，返回导数的预编译表达式
+// 可以通过 String() 获取导数的表达式字符串，或通过 Evaluate 计算导数值
+func Derivative(expression, variable string, cfg *math_config.CalcConfig) (*CompiledExpression, error) {
+	compiled, err := croe.Compile(expression, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	return compiled.Derive(variable)
+}
+
+// Simplify 化简表达式，返回规范形式的预编译表达式
+// 化简会合并同类项并统一操作数顺序，在每一步应用精度控制时结果可能与原表达式存在舍入差异
+func Simplify(expression string, cfg *math_config.CalcConfig) (*CompiledExpression, error) {
+	compiled, err := croe.Compile(expression, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	return compiled.Simplify(), nil
+}
+
+// Canonicalize 返回表达式的规范字符串，可用于对用户保存的公式去重
+// 求值时可能出错的表达式（如 x / x、x - x）与不会出错的表达式得到不同的规范字符串
+func Canonicalize(expression string) (string, error) {
+	return CanonicalizeWithOptions(expression, NormalizeOptions{})
+}
+
+// CanonicalizeWithOptions 使用指定的规范化选项返回表达式的规范字符串
+// 设置 AssumeDefined 后 x - x、0 * x 等消去变量，适用于变量总是有值的场景
+func CanonicalizeWithOptions(expression string, options NormalizeOptions) (string, error) {
+	compiled, err := croe.Compile(expression, nil)
+	if err != nil {
+		return "", err
+	}
+
+	return compiled.CanonicalWithOptions(options), nil
+}
+
+// Format 解析表达式并按指定选项重新格式化，只保留必要的括号
+func Format(expression string, options FormatOptions) (string, error) {
+	compiled, err := croe.Compile(expression, nil)
+	if err != nil {
+		return "", err
+	}
+
+	return compiled.Format(options), nil
+}
@@ -0,0 +1,119 @@
+package math_calculation
+
+import (
+	"testing"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/ZHOUXING1997/math_calculation/internal/math_node"
+)
+
+// TestDerivative 测试表达式求导
+func TestDerivative(t *testing.T) {
+	derivative, err := Derivative("price * qty * (1 - discount)", "discount", nil)
+	if err != nil {
+		t.Fatalf("Derivative() error = %v", err)
+	}
+
+	if got, want := derivative.String(), "-(price * qty)"; got != want {
+		t.Errorf("Derivative().String() = %q, want %q", got, want)
+	}
+
+	result, err := derivative.Evaluate(map[string]decimal.Decimal{
+		"price":    decimal.NewFromInt(20),
+		"qty":      decimal.NewFromInt(3),
+		"discount": decimal.NewFromFloat(0.1),
+	})
+	if err != nil {
+		t.Fatalf("Evaluate() error = %v", err)
+	}
+	if !result.Equal(decimal.NewFromInt(-60)) {
+		t.Errorf("Evaluate() = %v, want -60", result)
+	}
+
+	// 通过计算器求导时会先验证表达式
+	_, err = NewCalculator(nil).Derivative("x + ", "x")
+	if err == nil {
+		t.Errorf("Calculator.Derivative() expected error for invalid expression")
+	}
+}
+
+// TestCanonicalize 测试表达式规范化
+func TestCanonicalize(t *testing.T) {
+	a, err := Canonicalize("price * qty + fee")
+	if err != nil {
+		t.Fatalf("Canonicalize() error = %v", err)
+	}
+	b, err := Canonicalize("fee + qty*price")
+	if err != nil {
+		t.Fatalf("Canonicalize() error = %v", err)
+	}
+	if a != b {
+		t.Errorf("Canonicalize() = %q and %q, want equal", a, b)
+	}
+
+	// 求值会出错的公式与能正常求值的公式得到不同的键
+	for _, pair := range [][2]string{
+		{"x / x", "1"}, {"a * b / a", "b"}, {"0 * (1 / 0)", "0"},
+		{"x - x", "0"}, {"x ^ 0", "1"}, {"sqrt(-1) * 0", "0"}, {"sqr(x) * 0", "0"},
+	} {
+		failing, err := Canonicalize(pair[0])
+		if err != nil {
+			t.Fatalf("Canonicalize(%q) error = %v", pair[0], err)
+		}
+		working, _ := Canonicalize(pair[1])
+		if failing == working {
+			t.Errorf("Canonicalize(%q) = Canonicalize(%q) = %q, want distinct", pair[0], pair[1], failing)
+		}
+	}
+
+	// 假定变量都有值时消去变量
+	if got, err := CanonicalizeWithOptions("x * y - y * x + 1", NormalizeOptions{AssumeDefined: true}); err != nil || got != "1" {
+		t.Errorf("CanonicalizeWithOptions() = %q, %v, want 1", got, err)
+	}
+
+	simplified, err := Simplify("x * 2 + x", nil)
+	if err != nil {
+		t.Fatalf("Simplify() error = %v", err)
+	}
+	if got := simplified.String(); got != "3 * x" {
+		t.Errorf("Simplify().String() = %q, want %q", got, "3 * x")
+	}
+}
+
+// TestFormat 测试格式化输出可以重新解析为相同的表达式
+func TestFormat(t *testing.T) {
+	expressions := []string{
+		"1+2*3",
+		"(1+2)*3",
+		"x-(y-z)",
+		"-(x^2)",
+		"-2^2",
+		"a--2",
+		"sqrt(25)*(3.14*x+2.5)-abs(-5)+pow(2,3)",
+		"max(sqrt(16),pow(2,3))/min(abs(-5),3,7)",
+	}
+
+	for _, expression := range expressions {
+		for _, options := range []math_node.FormatOptions{
+			{},
+			{Compact: true},
+			{MultiLine: true, MaxWidth: 10},
+		} {
+			formatted, err := Format(expression, options)
+			if err != nil {
+				t.Fatalf("Format(%q) error = %v", expression, err)
+			}
+
+			// 格式化结果重新解析后应该得到相同的表达式
+			again, err := Format(formatted, math_node.FormatOptions{})
+			if err != nil {
+				t.Fatalf("Format(%q) error = %v", formatted, err)
+			}
+			want, _ := Format(expression, math_node.FormatOptions{})
+			if again != want {
+				t.Errorf("Format(%q) round trip = %q, want %q", expression, again, want)
+			}
+		}
+	}
+}
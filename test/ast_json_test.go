@@ -0,0 +1,55 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/ZHOUXING1997/math_calculation"
+	"github.com/ZHOUXING1997/math_calculation/math_config"
+)
+
+// TestASTJSONRoundTrip 对综合测试用例中的表达式做 JSON 往返测试
+func TestASTJSONRoundTrip(t *testing.T) {
+	for _, tc := range comprehensiveCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			cfg := math_config.NewDefaultCalcConfig()
+			cfg.ApplyPrecisionEachStep = false
+
+			compiled, err := math_calculation.NewCalculator(cfg).Compile(tc.Expression)
+			if err != nil {
+				// 无法编译的表达式没有语法树
+				return
+			}
+
+			data, err := json.Marshal(compiled)
+			if err != nil {
+				t.Fatalf("json.Marshal() error = %v", err)
+			}
+
+			restored, err := math_calculation.CompileJSON(data, cfg)
+			if err != nil {
+				t.Fatalf("CompileJSON() error = %v", err)
+			}
+
+			// 重建的语法树再次序列化应得到相同的 JSON
+			again, err := json.Marshal(restored)
+			if err != nil {
+				t.Fatalf("json.Marshal() error = %v", err)
+			}
+			if !bytes.Equal(data, again) {
+				t.Errorf("JSON round trip mismatch:\n%s\n%s", data, again)
+			}
+
+			// 计算结果一致
+			want, wantErr := compiled.Evaluate(tc.Variables)
+			got, gotErr := restored.Evaluate(tc.Variables)
+			if (wantErr != nil) != (gotErr != nil) {
+				t.Fatalf("Evaluate() error = %v, want %v", gotErr, wantErr)
+			}
+			if !got.Equal(want) {
+				t.Errorf("Evaluate() = %v, want %v", got, want)
+			}
+		})
+	}
+}
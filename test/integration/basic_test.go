@@ -173,7 +173,7 @@ func TestBasicIntegration(t *testing.T) {
 			if !tt.wantErr {
 				calc := math_calculation.NewCalculator(nil)
 				for k, v := range tt.vars {
-					calc.WithVariable(k, v)
+					calc = calc.WithVariable(k, v)
 				}
 
 				got, err = calc.Calculate(tt.expression)
@@ -111,7 +111,7 @@ func TestIntegration(t *testing.T) {
 			// 测试链式API
 			calc := math_calculation.NewCalculator(nil)
 			for k, v := range tt.vars {
-				calc.WithVariable(k, v)
+				calc = calc.WithVariable(k, v)
 			}
 
 			got, err = calc.Calculate(tt.expression)
@@ -0,0 +1,18 @@
+package math_calculation
+
+import (
+	"github.com/ZHOUXING1997/math_calculation/internal/workbook"
+	"github.com/ZHOUXING1997/math_calculation/math_config"
+)
+
+// NewWorkbook 创建一组相互引用的命名公式，复制传入的配置，cfg 为 nil 时使用默认配置
+// 公式按依赖关系的拓扑顺序计算，定义公式时检查循环引用，输入变化后只重新计算受影响的公式：
+//
+//	wb := NewWorkbook(nil)
+//	_ = wb.Define("subtotal", "qty * price")
+//	_ = wb.Define("total", "subtotal * (1 + rate)")
+//	_ = wb.SetInputs(inputs)
+//	total, err := wb.Value("total")
+func NewWorkbook(cfg *math_config.CalcConfig) *Workbook {
+	return workbook.New(cfg)
+}
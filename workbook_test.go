@@ -0,0 +1,50 @@
+package math_calculation
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestWorkbook(t *testing.T) {
+	wb := NewWorkbook(nil)
+	for name, expression := range map[string]string{
+		"subtotal": "qty * price",
+		"tax":      "subtotal * rate",
+		"total":    "subtotal + tax",
+	} {
+		if err := wb.Define(name, expression); err != nil {
+			t.Fatalf("Define(%s) error = %v", name, err)
+		}
+	}
+	if err := wb.SetInputs(map[string]decimal.Decimal{
+		"qty":   decimal.NewFromInt(2),
+		"price": decimal.RequireFromString("9.5"),
+		"rate":  decimal.RequireFromString("0.1"),
+	}); err != nil {
+		t.Fatalf("SetInputs() error = %v", err)
+	}
+
+	if got, err := wb.Value("total"); err != nil || !got.Equal(decimal.RequireFromString("20.9")) {
+		t.Errorf("Value(total) = %v, %v, want 20.9", got, err)
+	}
+
+	// 只重新计算受影响的公式
+	_ = wb.SetInput("rate", decimal.RequireFromString("0.2"))
+	recalculated, err := wb.Recalculate(context.Background())
+	if err != nil || len(recalculated) != 2 || recalculated[0] != "tax" || recalculated[1] != "total" {
+		t.Errorf("Recalculate() = %v, %v, want [tax total]", recalculated, err)
+	}
+
+	// 循环引用
+	err = wb.Define("subtotal", "total - tax")
+	var cycleErr *CycleError
+	if !errors.As(err, &cycleErr) || !errors.Is(err, ErrCircularReference) || ErrorCodeOf(err) != CodeCircularReference {
+		t.Fatalf("Define() error = %v, want *CycleError", err)
+	}
+	if len(cycleErr.Path) != 3 || cycleErr.Path[0] != "subtotal" || cycleErr.Path[2] != "subtotal" {
+		t.Errorf("CycleError.Path = %v", cycleErr.Path)
+	}
+}